@@ -0,0 +1,475 @@
+// Tuning advisors for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// Advisors look at the stats and make a concrete recommendation along with
+// the numbers they are based on. They are printed with -advise. These are
+// rules of thumb, not a replacement for understanding the workload. See
+// arc_summary.go for the license
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	meminfoPath = "/proc/meminfo"
+	uptimePath  = "/proc/uptime"
+	gib         = 1 << 30
+
+	// Every block in the L2ARC needs a header in RAM. This is the size of
+	// the L2 header on current OpenZFS and the block size we assume when
+	// we have nothing better to go by
+	l2HdrBytes   = 88
+	l2BlockBytes = 16 * 1024
+)
+
+var advisors = []func(context.Context){
+	adviseARC,
+	adviseL2ARC,
+	adviseSLOG,
+}
+
+// readMeminfo returns the values of /proc/meminfo in bytes
+func readMeminfo(ctx context.Context) (map[string]uint64, error) {
+
+	data, err := readFile(ctx, meminfoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]uint64)
+	input := bufio.NewScanner(bytes.NewReader(data))
+
+	// Lines look like "MemAvailable:   12345678 kB"
+	for input.Scan() {
+		fields := strings.Fields(input.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		if len(fields) == 3 && fields[2] == "kB" {
+			value *= 1024
+		}
+
+		m[strings.TrimSuffix(fields[0], ":")] = value
+	}
+
+	return m, input.Err()
+}
+
+// readUptime returns the number of seconds since boot
+func readUptime(ctx context.Context) (float64, error) {
+
+	data, err := readFile(ctx, uptimePath)
+	if err != nil {
+		return 0, err
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("%s is empty", uptimePath)
+	}
+
+	return strconv.ParseFloat(fields[0], 64)
+}
+
+// printAdvice runs all advisors
+func printAdvice() {
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	for _, a := range advisors {
+		a(ctx)
+	}
+
+	printWarnings()
+}
+
+// adviceStats returns the stats of a kstat file an advisor is based on. It
+// fails if the file couldn't be read or a required stat is missing. Optional
+// stats that this version of ZFS doesn't have are zero
+func adviceStats(file string, required, optional []string) (map[string]uint64, error) {
+
+	if err := kstatError(file); err != nil {
+		return nil, err
+	}
+
+	if _, ok := kstats[file]; !ok {
+		return nil, fmt.Errorf("no data on %s", file)
+	}
+
+	stats := make(map[string]string)
+	procSection(file, stats)
+
+	m := make(map[string]uint64)
+	var missing []string
+
+	for _, name := range required {
+		v, ok := statValue(stats, name)
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		m[name] = v
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%s lacks %s", file, strings.Join(missing, ", "))
+	}
+
+	for _, name := range optional {
+		m[name], _ = statValue(stats, name)
+	}
+
+	return m, nil
+}
+
+// arcAdviceStats are the arcstats both the ARC and the L2ARC advice need
+var arcAdviceStats = []string{"size", "c_min", "c_max", "misses",
+	"mru_ghost_hits", "mfu_ghost_hits", "mru_ghost_size", "mfu_ghost_size"}
+
+// arcAdviceInput are the numbers the ARC sizing advice is based on
+type arcAdviceInput struct {
+	size, cMin, cMax    uint64
+	ghostHits, misses   uint64
+	ghostSize           uint64
+	memTotal, memAvail  uint64
+	throttles, reclaims uint64
+}
+
+// adviseArcMax decides if zfs_arc_max should be raised or lowered. It returns
+// "bigger", "smaller" or "fine" and the suggested new value in bytes
+func adviseArcMax(in arcAdviceInput) (string, uint64) {
+
+	ghostRatio := 0.0
+	if in.misses > 0 {
+		ghostRatio = float64(in.ghostHits) / float64(in.misses)
+	}
+
+	headroom := 0.0
+	if in.memTotal > 0 {
+		headroom = float64(in.memAvail) / float64(in.memTotal)
+	}
+
+	arcFull := in.cMax > 0 && float64(in.size) >= 0.95*float64(in.cMax)
+	pressure := in.throttles > 0 || in.reclaims > 0 || (in.memTotal > 0 && headroom < 0.1)
+
+	switch {
+
+	// Many misses would have been hits with a larger ARC, and the machine
+	// has memory to spare: Grow by the size of the ghost lists, but never
+	// take more than half of what is available
+	case arcFull && ghostRatio > 0.1 && headroom > 0.2:
+		grow := in.ghostSize
+		if grow > in.memAvail/2 {
+			grow = in.memAvail / 2
+		}
+		return "bigger", roundUpGiB(in.cMax + grow)
+
+	// The ghost lists show a larger ARC wouldn't help much, but the kernel
+	// is fighting the ARC for memory: Shrink to what is actually used
+	case ghostRatio < 0.01 && pressure:
+		target := in.size
+		if target < in.cMin {
+			target = in.cMin
+		}
+		target = roundUpGiB(target)
+		if target < in.cMax {
+			return "smaller", target
+		}
+	}
+
+	return "fine", in.cMax
+}
+
+// roundUpGiB rounds a number of bytes up to the next full GiB
+func roundUpGiB(b uint64) uint64 {
+	return (b + gib - 1) / gib * gib
+}
+
+// adviseARC prints the recommendation for zfs_arc_max
+func adviseARC(ctx context.Context) {
+
+	printTitle("arc sizing advice")
+
+	m, err := adviceStats("arcstats", arcAdviceStats,
+		[]string{"memory_throttle_count", "memory_direct_count"})
+	if err != nil {
+		skipSection("advice", err)
+		return
+	}
+
+	in := arcAdviceInput{
+		size:      m["size"],
+		cMin:      m["c_min"],
+		cMax:      m["c_max"],
+		ghostHits: m["mru_ghost_hits"] + m["mfu_ghost_hits"],
+		misses:    m["misses"],
+		ghostSize: m["mru_ghost_size"] + m["mfu_ghost_size"],
+		throttles: m["memory_throttle_count"],
+		reclaims:  m["memory_direct_count"],
+	}
+
+	meminfo, err := readMeminfo(ctx)
+	if err == nil {
+		in.memTotal = meminfo["MemTotal"]
+		in.memAvail = meminfo["MemAvailable"]
+	}
+
+	verdict, value := adviseArcMax(in)
+
+	u := func(v uint64) string { return strconv.FormatUint(v, 10) }
+
+	prtL1p("ARC size:", fPerc(u(in.size), u(in.cMax)), fBytes(u(in.size)))
+	prtL2("Max size (c_max):", fBytes(u(in.cMax)))
+	prtL2p("Ghost list hits (of misses):", fPerc(u(in.ghostHits), u(in.misses)), fHits(u(in.ghostHits)))
+	prtL2("Ghost list size:", fBytes(u(in.ghostSize)))
+	prtL2("Memory throttles:", fHits(u(in.throttles)))
+	prtL2("Direct reclaims:", fHits(u(in.reclaims)))
+
+	if err == nil {
+		prtL2p("Memory available:", fPerc(u(in.memAvail), u(in.memTotal)), fBytes(u(in.memAvail)))
+	} else {
+		prtL2("Memory available:", "n/a")
+	}
+
+	fmt.Fprintln(reportOut)
+
+	switch verdict {
+	case "bigger":
+		fmt.Fprintf(reportOut, "A larger ARC would turn ghost list hits into real hits and there is\n"+
+			"memory to spare. Suggest raising zfs_arc_max to %d (%s).\n", value, fBytes(u(value)))
+	case "smaller":
+		fmt.Fprintf(reportOut, "A larger ARC would barely help, but the kernel is short of memory.\n"+
+			"Suggest lowering zfs_arc_max to %d (%s).\n", value, fBytes(u(value)))
+	default:
+		fmt.Fprintln(reportOut, "The ARC size looks fine as it is.")
+	}
+}
+
+// l2AdviceInput are the numbers the L2ARC advice is based on
+type l2AdviceInput struct {
+	l2Size, l2HdrSize  uint64
+	l2Hits, l2Misses   uint64
+	arcSize, cMax      uint64
+	ghostHits, misses  uint64
+	ghostSize          uint64
+	memTotal, memAvail uint64
+}
+
+// adviseL2 decides if an existing L2ARC is worth its header RAM, or if a
+// missing one would help. It returns "useful", "useless", "add" or "none" and,
+// for "add", the suggested device size in bytes
+func adviseL2(in l2AdviceInput) (string, uint64) {
+
+	if in.l2Size > 0 {
+		hitRatio := 0.0
+		if in.l2Hits+in.l2Misses > 0 {
+			hitRatio = float64(in.l2Hits) / float64(in.l2Hits+in.l2Misses)
+		}
+
+		// The RAM for the headers would otherwise be ARC, so the L2ARC has
+		// to hit noticeably more often than the ARC would have
+		hdrShare := 0.0
+		if in.arcSize > 0 {
+			hdrShare = float64(in.l2HdrSize) / float64(in.arcSize)
+		}
+
+		if hitRatio < 0.1 || hdrShare > 0.1 {
+			return "useless", 0
+		}
+		return "useful", 0
+	}
+
+	ghostRatio := 0.0
+	if in.misses > 0 {
+		ghostRatio = float64(in.ghostHits) / float64(in.misses)
+	}
+
+	headroom := 1.0
+	if in.memTotal > 0 {
+		headroom = float64(in.memAvail) / float64(in.memTotal)
+	}
+
+	arcFull := in.cMax > 0 && float64(in.arcSize) >= 0.95*float64(in.cMax)
+
+	// The ARC is full, recently evicted data is being asked for again, and
+	// there is no RAM left to simply make the ARC larger. The ghost lists
+	// tell us roughly how much more cache would be used
+	if arcFull && ghostRatio > 0.1 && headroom < 0.2 {
+		return "add", roundUpGiB(2 * in.ghostSize)
+	}
+
+	return "none", 0
+}
+
+// adviseL2ARC prints the recommendation on the L2ARC
+func adviseL2ARC(ctx context.Context) {
+
+	printTitle("l2arc advice")
+
+	// Without the L2ARC stats, we treat the system as one without L2ARC
+	m, err := adviceStats("arcstats", arcAdviceStats,
+		[]string{"l2_size", "l2_hdr_size", "l2_hits", "l2_misses"})
+	if err != nil {
+		skipSection("advice", err)
+		return
+	}
+
+	in := l2AdviceInput{
+		l2Size:    m["l2_size"],
+		l2HdrSize: m["l2_hdr_size"],
+		l2Hits:    m["l2_hits"],
+		l2Misses:  m["l2_misses"],
+		arcSize:   m["size"],
+		cMax:      m["c_max"],
+		ghostHits: m["mru_ghost_hits"] + m["mfu_ghost_hits"],
+		misses:    m["misses"],
+		ghostSize: m["mru_ghost_size"] + m["mfu_ghost_size"],
+	}
+
+	if meminfo, err := readMeminfo(ctx); err == nil {
+		in.memTotal = meminfo["MemTotal"]
+		in.memAvail = meminfo["MemAvailable"]
+	}
+
+	verdict, size := adviseL2(in)
+
+	u := func(v uint64) string { return strconv.FormatUint(v, 10) }
+
+	if in.l2Size > 0 {
+		prtL1("L2ARC size:", fBytes(u(in.l2Size)))
+		prtL2p("L2ARC hits:", fPerc(u(in.l2Hits), u(in.l2Hits+in.l2Misses)), fHits(u(in.l2Hits)))
+		prtL2p("Header RAM (of ARC):", fPerc(u(in.l2HdrSize), u(in.arcSize)), fBytes(u(in.l2HdrSize)))
+	} else {
+		prtL1("L2ARC size:", "none")
+		prtL2p("ARC size:", fPerc(u(in.arcSize), u(in.cMax)), fBytes(u(in.arcSize)))
+		prtL2p("Ghost list hits (of misses):", fPerc(u(in.ghostHits), u(in.misses)), fHits(u(in.ghostHits)))
+		prtL2("Ghost list size:", fBytes(u(in.ghostSize)))
+	}
+
+	fmt.Fprintln(reportOut)
+
+	switch verdict {
+	case "useful":
+		fmt.Fprintln(reportOut, "The L2ARC is serving a useful share of ARC misses for the RAM its\n"+
+			"headers cost.")
+	case "useless":
+		fmt.Fprintln(reportOut, "The L2ARC hits rarely or its headers take a large share of the ARC.\n"+
+			"The RAM would probably do more good as ARC; consider removing it.")
+	case "add":
+		hdr := size / l2BlockBytes * l2HdrBytes
+		fmt.Fprintf(reportOut, "The ARC is full, often misses recently evicted data and can't grow.\n"+
+			"An L2ARC of about %s would likely help (header RAM about %s\n"+
+			"at %d KiB blocks).\n", fBytes(u(size)), fBytes(u(hdr)), l2BlockBytes/1024)
+	default:
+		fmt.Fprintln(reportOut, "No L2ARC needed: The ARC either has room to grow or rarely misses\n"+
+			"recently evicted data.")
+	}
+}
+
+// slogAdviceInput are the numbers the SLOG advice is based on
+type slogAdviceInput struct {
+	commits                uint64
+	indirectBytes          uint64
+	copiedBytes, needBytes uint64
+	normalBytes, slogBytes uint64
+	slogCount              uint64
+	uptime                 float64
+}
+
+// adviseSlog decides if a separate log device would help. It returns "have"
+// if there already is one, "helpful" or "unhelpful"
+func adviseSlog(in slogAdviceInput) string {
+
+	if in.slogCount > 0 {
+		return "have"
+	}
+
+	rate := 0.0
+	if in.uptime > 0 {
+		rate = float64(in.commits) / in.uptime
+	}
+
+	// Only data that is written into the log itself gets faster with a
+	// SLOG. Indirect writes go to the pool and only a pointer is logged
+	logged := in.copiedBytes + in.needBytes
+	total := logged + in.indirectBytes
+
+	loggedShare := 0.0
+	if total > 0 {
+		loggedShare = float64(logged) / float64(total)
+	}
+
+	if rate >= 10 && loggedShare >= 0.5 {
+		return "helpful"
+	}
+
+	return "unhelpful"
+}
+
+// adviseSLOG prints the recommendation on a separate ZIL device
+func adviseSLOG(ctx context.Context) {
+
+	printTitle("slog advice")
+
+	// Older versions of ZFS don't say which device the log blocks went to
+	m, err := adviceStats("zil",
+		[]string{"zil_commit_count", "zil_itx_indirect_bytes", "zil_itx_copied_bytes", "zil_itx_needcopy_bytes"},
+		[]string{"zil_itx_metaslab_normal_bytes", "zil_itx_metaslab_slog_bytes", "zil_itx_metaslab_slog_count"})
+	if err != nil {
+		skipSection("advice", err)
+		return
+	}
+
+	in := slogAdviceInput{
+		commits:       m["zil_commit_count"],
+		indirectBytes: m["zil_itx_indirect_bytes"],
+		copiedBytes:   m["zil_itx_copied_bytes"],
+		needBytes:     m["zil_itx_needcopy_bytes"],
+		normalBytes:   m["zil_itx_metaslab_normal_bytes"],
+		slogBytes:     m["zil_itx_metaslab_slog_bytes"],
+		slogCount:     m["zil_itx_metaslab_slog_count"],
+	}
+
+	uptime, err := readUptime(ctx)
+	if err == nil {
+		in.uptime = uptime
+	}
+
+	u := func(v uint64) string { return strconv.FormatUint(v, 10) }
+	logged := in.copiedBytes + in.needBytes
+
+	prtL1("ZIL commits:", fHits(u(in.commits)))
+	if in.uptime > 0 {
+		prtL2("Commits per second (since boot):", fmt.Sprintf("%0.1f", float64(in.commits)/in.uptime))
+	}
+	prtL2p("Sync data written to log:", fPerc(u(logged), u(logged+in.indirectBytes)), fBytes(u(logged)))
+	prtL2p("Sync data written indirectly:", fPerc(u(in.indirectBytes), u(logged+in.indirectBytes)), fBytes(u(in.indirectBytes)))
+	prtL2("Log blocks on pool devices:", fBytes(u(in.normalBytes)))
+	prtL2("Log blocks on SLOG devices:", fBytes(u(in.slogBytes)))
+
+	fmt.Fprintln(reportOut)
+
+	switch adviseSlog(in) {
+	case "have":
+		fmt.Fprintln(reportOut, "A separate log device is already in use.")
+	case "helpful":
+		fmt.Fprintln(reportOut, "There is a steady stream of synchronous writes that go through the\n"+
+			"log. A fast, power-safe SLOG device would likely reduce their latency.")
+	default:
+		fmt.Fprintln(reportOut, "Synchronous writes are rare or mostly written indirectly. A SLOG\n"+
+			"device would not make a noticeable difference.")
+	}
+}
@@ -0,0 +1,107 @@
+// Test file for advisor.go
+package main
+
+import "testing"
+
+func TestAdviseArcMax(t *testing.T) {
+	var tests = []struct {
+		name    string
+		have    arcAdviceInput
+		verdict string
+		value   uint64
+	}{
+		{"full ARC, many ghost hits, free memory",
+			arcAdviceInput{size: 8 * gib, cMin: gib, cMax: 8 * gib, ghostHits: 200, misses: 1000,
+				ghostSize: 3 * gib, memTotal: 32 * gib, memAvail: 16 * gib},
+			"bigger", 11 * gib},
+		{"growth limited to half of available memory",
+			arcAdviceInput{size: 8 * gib, cMin: gib, cMax: 8 * gib, ghostHits: 200, misses: 1000,
+				ghostSize: 20 * gib, memTotal: 32 * gib, memAvail: 8 * gib},
+			"bigger", 12 * gib},
+		{"no ghost hits, memory pressure",
+			arcAdviceInput{size: 3*gib + 1, cMin: gib, cMax: 16 * gib, ghostHits: 0, misses: 1000,
+				memTotal: 32 * gib, memAvail: gib, reclaims: 5},
+			"smaller", 4 * gib},
+		{"idle system",
+			arcAdviceInput{size: 2 * gib, cMin: gib, cMax: 16 * gib, memTotal: 32 * gib, memAvail: 20 * gib},
+			"fine", 16 * gib},
+	}
+
+	for _, test := range tests {
+		verdict, value := adviseArcMax(test.have)
+		if verdict != test.verdict || value != test.value {
+			t.Errorf("adviseArcMax(%s) = %s, %d (wanted %s, %d)", test.name, verdict, value, test.verdict, test.value)
+		}
+	}
+}
+
+func TestAdviseL2(t *testing.T) {
+	var tests = []struct {
+		name    string
+		have    l2AdviceInput
+		verdict string
+		size    uint64
+	}{
+		{"busy L2ARC",
+			l2AdviceInput{l2Size: 100 * gib, l2HdrSize: 100 << 20, l2Hits: 500, l2Misses: 500, arcSize: 8 * gib},
+			"useful", 0},
+		{"rarely hit L2ARC",
+			l2AdviceInput{l2Size: 100 * gib, l2HdrSize: 100 << 20, l2Hits: 5, l2Misses: 995, arcSize: 8 * gib},
+			"useless", 0},
+		{"full ARC without spare memory",
+			l2AdviceInput{arcSize: 8 * gib, cMax: 8 * gib, ghostHits: 300, misses: 1000,
+				ghostSize: 5 * gib, memTotal: 16 * gib, memAvail: gib},
+			"add", 10 * gib},
+		{"ARC can still grow",
+			l2AdviceInput{arcSize: 8 * gib, cMax: 8 * gib, ghostHits: 300, misses: 1000,
+				ghostSize: 5 * gib, memTotal: 64 * gib, memAvail: 40 * gib},
+			"none", 0},
+	}
+
+	for _, test := range tests {
+		verdict, size := adviseL2(test.have)
+		if verdict != test.verdict || size != test.size {
+			t.Errorf("adviseL2(%s) = %s, %d (wanted %s, %d)", test.name, verdict, size, test.verdict, test.size)
+		}
+	}
+}
+
+func TestAdviseSlog(t *testing.T) {
+	var tests = []struct {
+		name string
+		have slogAdviceInput
+		want string
+	}{
+		{"existing SLOG", slogAdviceInput{commits: 1000, slogCount: 10, uptime: 10}, "have"},
+		{"busy sync writes", slogAdviceInput{commits: 10000, copiedBytes: 1 << 30, uptime: 100}, "helpful"},
+		{"rare sync writes", slogAdviceInput{commits: 100, copiedBytes: 1 << 30, uptime: 100}, "unhelpful"},
+		{"mostly indirect", slogAdviceInput{commits: 10000, copiedBytes: 1 << 20, indirectBytes: 1 << 30, uptime: 100}, "unhelpful"},
+	}
+
+	for _, test := range tests {
+		got := adviseSlog(test.have)
+		if got != test.want {
+			t.Errorf("adviseSlog(%s) = %s (wanted %s)", test.name, got, test.want)
+		}
+	}
+}
+
+func TestAdviceStats(t *testing.T) {
+
+	saved := kstats
+	kstats = map[string][]string{"arcstats": {"size 4 600", "c_max 4 1000"}}
+	defer func() { kstats = saved }()
+
+	m, err := adviceStats("arcstats", []string{"size"}, []string{"memory_direct_count"})
+	if err != nil || m["size"] != 600 || m["memory_direct_count"] != 0 {
+		t.Errorf("adviceStats(size) = %v, %v (wanted size 600)", m, err)
+	}
+
+	if _, err := adviceStats("arcstats", []string{"size", "misses"}, nil); err == nil {
+		t.Errorf("adviceStats(misses) succeeded (wanted error)")
+	}
+
+	if _, err := adviceStats("zil", []string{"zil_commit_count"}, nil); err == nil {
+		t.Errorf("adviceStats(zil) succeeded (wanted error)")
+	}
+}
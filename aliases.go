@@ -0,0 +1,92 @@
+// Compatibility names of kstats for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// Stats come and go between versions of ZFS. Instead of every section
+// checking which names the kernel has, kstatAliases says how to get a stat
+// under its name in one version from the stats of another. Missing stats are
+// filled in when sections and expressions look them up, so they only ever
+// use one name. The kstats themselves stay as the kernel wrote them, the
+// JSON and dump output don't show made up stats. See arc_summary.go for the
+// license
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// kstatAliases are keyed by file and name. The alternatives are expressions
+// over the other stats of the same file, which are tried in order until one
+// can be evaluated
+var kstatAliases = map[string][]string{
+	// ZFS 0.7 split other_size up
+	"arcstats.other_size": {"dbuf_size + dnode_size + bonus_size"},
+
+	// OpenZFS 2.2 replaced the MRU target p and the metadata limit by the
+	// fractions pd, pm and meta of c, in units of 2^32. pd and pm are the
+	// MRU share of data and metadata
+	"arcstats.p":              {"c * ((4294967296 - meta) * pd + meta * pm) / 18446744073709551616"},
+	"arcstats.arc_meta_limit": {"c * meta / 4294967296"},
+	"arcstats.arc_meta_used":  {"hdr_size + l2_hdr_size + metadata_size + dbuf_size + dnode_size + bonus_size"},
+}
+
+// aliasValue computes a stat of a file that is missing from one of its
+// aliases. lookup returns the value of the other stats of the file
+func aliasValue(file, name string, lookup func(string) (float64, error)) (float64, bool) {
+
+	for _, expr := range kstatAliases[file+"."+name] {
+		if v, err := evalExpr(expr, lookup); err == nil && v >= 0 {
+			return v, true
+		}
+	}
+
+	return 0, false
+}
+
+// addAliases adds the stats of a file that are missing from stats but can be
+// computed from one of their aliases
+func addAliases(file string, stats map[string]string) {
+
+	lookup := func(name string) (float64, error) {
+		v, ok := stats[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown stat '%s' in section '%s'", name, file)
+		}
+		return strconv.ParseFloat(v, 64)
+	}
+
+	// Aliases are computed from the real stats only, so they are added
+	// when all are done
+	added := make(map[string]string)
+
+	for _, name := range aliasNames(file) {
+		if _, ok := stats[name]; ok {
+			continue
+		}
+		if v, ok := aliasValue(file, name, lookup); ok {
+			added[name] = strconv.FormatUint(uint64(v), 10)
+		}
+	}
+
+	for name, v := range added {
+		stats[name] = v
+	}
+}
+
+// aliasNames returns the names of the stats of a file that have aliases in
+// alphabetical order
+func aliasNames(file string) []string {
+
+	var names []string
+
+	for k := range kstatAliases {
+		if strings.HasPrefix(k, file+".") {
+			names = append(names, strings.TrimPrefix(k, file+"."))
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,60 @@
+// Test file for aliases.go
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAddAliases(t *testing.T) {
+
+	var tests = []struct {
+		stats map[string]string
+		want  map[string]string
+	}{
+		{map[string]string{"other_size": "30"}, map[string]string{"other_size": "30"}},
+		{map[string]string{"dbuf_size": "10", "dnode_size": "15", "bonus_size": "5"},
+			map[string]string{"dbuf_size": "10", "dnode_size": "15", "bonus_size": "5", "other_size": "30"}},
+		{map[string]string{"dbuf_size": "10"}, map[string]string{"dbuf_size": "10"}},
+		{map[string]string{"c": "1000", "meta": "1073741824", "pd": "2147483648", "pm": "4294967296"},
+			map[string]string{"c": "1000", "meta": "1073741824", "pd": "2147483648", "pm": "4294967296",
+				"arc_meta_limit": "250", "p": "625"}},
+		{map[string]string{"c": "1000", "meta": "1073741824", "arc_meta_limit": "300"},
+			map[string]string{"c": "1000", "meta": "1073741824", "arc_meta_limit": "300"}},
+	}
+
+	for _, test := range tests {
+		got := make(map[string]string)
+		for k, v := range test.stats {
+			got[k] = v
+		}
+		addAliases("arcstats", got)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("addAliases(%v) = %v (wanted \"%v\")", test.stats, got, test.want)
+		}
+	}
+
+	got := map[string]string{"dbuf_size": "10", "dnode_size": "15", "bonus_size": "5"}
+	if addAliases("zil", got); len(got) != 3 {
+		t.Errorf("addAliases(zil) = %v (wanted no aliases)", got)
+	}
+}
+
+func TestLookupStatAlias(t *testing.T) {
+
+	saved := kstats
+	kstats = map[string][]string{"arcstats": {"c 4 1000", "meta 4 1073741824"}}
+	defer func() { kstats = saved }()
+
+	if v, err := lookupStat("arc_meta_limit"); err != nil || v != 250 {
+		t.Errorf("lookupStat(arc_meta_limit) = %v, %v (wanted \"250\")", v, err)
+	}
+
+	if _, err := lookupStat("p"); err == nil {
+		t.Errorf("lookupStat(p) without pd and pm succeeded (wanted error)")
+	}
+
+	if len(kstats["arcstats"]) != 2 {
+		t.Errorf("lookupStat() changed the kstats to %v", kstats["arcstats"])
+	}
+}
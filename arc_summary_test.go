@@ -5,9 +5,12 @@
 package main
 
 import (
+	"errors"
 	"math"
+	"os"
 	"strconv"
 	"testing"
+	"time"
 )
 
 // Convert float64 to string
@@ -72,7 +75,55 @@ func TestFBytes(t *testing.T) {
 	for _, test := range tests {
 		got := fBytes(test.have)
 		if got != test.want {
-			t.Errorf("fBytes(%d) = %v (wanted \"%v\")", test.have, got, test.want)
+			t.Errorf("fBytes(%s) = %v (wanted \"%v\")", test.have, got, test.want)
+		}
+	}
+}
+
+func TestShowRaw(t *testing.T) {
+
+	*OptShowRaw = true
+	defer func() { *OptShowRaw = false }()
+
+	var tests = []struct {
+		have string
+		f    func(string) string
+		want string
+	}{
+		{"1023", fBytes, "1023 Bytes"},
+		{"65715183616", fBytes, "61.2 GiB (65,715,183,616)"},
+		{"999", fHits, "999    "},
+		{"1000", fHits, "1.0k (1,000)"},
+		{"123456", fHits, "123.5k (123,456)"},
+	}
+
+	for _, test := range tests {
+		got := test.f(test.have)
+		if got != test.want {
+			t.Errorf("f(%s) = %v (wanted \"%v\")", test.have, got, test.want)
+		}
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+
+	ts := time.Date(2017, 11, 12, 14, 5, 9, 0, time.FixedZone("CET", 3600))
+
+	var tests = []struct {
+		format string
+		want   string
+	}{
+		{"default", "Sun Nov 12 14:05:09 2017"},
+		{"iso8601", "2017-11-12T14:05:09+01:00"},
+		{"rfc3339", "2017-11-12T14:05:09+01:00"},
+		{"unix", "1510491909"},
+		{"02.01.2006 15:04", "12.11.2017 14:05"},
+	}
+
+	for _, test := range tests {
+		got := formatTime(ts, test.format)
+		if got != test.want {
+			t.Errorf("formatTime(%s) = %v (wanted \"%v\")", test.format, got, test.want)
 		}
 	}
 }
@@ -90,6 +141,8 @@ func TestIsLegalSection(t *testing.T) {
 		{"xuio", true},
 		{"zfetch", true},
 		{"zil", true},
+		{"disks", true},
+		{"queues", true},
 
 		{"ZFS", false},
 		{"So say we all", false},
@@ -102,3 +155,79 @@ func TestIsLegalSection(t *testing.T) {
 		}
 	}
 }
+
+func TestArcLimitStatus(t *testing.T) {
+	var tests = []struct {
+		configured string
+		effective  string
+		value      string
+		warning    bool
+	}{
+		{"0", "8589934592", "0 (auto)", false},
+		{"8589934592", "8589934592", "8.0 GiB", false},
+		{"1024", "8589934592", "1.0 KiB", true},
+		{"", "8589934592", "n/a", false},
+	}
+
+	for _, test := range tests {
+		value, warning := arcLimitStatus("zfs_arc_max", test.configured, test.effective)
+		if value != test.value || (warning != "") != test.warning {
+			t.Errorf("arcLimitStatus(%s, %s) = %v, \"%v\" (wanted %v, warning %v)",
+				test.configured, test.effective, value, warning, test.value, test.warning)
+		}
+	}
+}
+
+func TestParseModinfo(t *testing.T) {
+	out := "filename:       /lib/modules/zfs.ko\000" +
+		"parm:           zfs_arc_max:Max arc size (ulong)\000" +
+		"parm:           zfs_txg_timeout:Max seconds worth of delta per txg (int)\000" +
+		"parm:           zfs_vdev_raidz_impl:Select raidz implementation: fastest (default) (charp)\000" +
+		"parm:           zfs_nodesc\000"
+
+	descs := make(map[string]string)
+	types := make(map[string]string)
+	parseModinfo(out, descs, types)
+
+	var tests = []struct {
+		name string
+		desc string
+		typ  string
+	}{
+		{"zfs_arc_max", "Max arc size", "ulong"},
+		{"zfs_txg_timeout", "Max seconds worth of delta per txg", "int"},
+		{"zfs_vdev_raidz_impl", "Select raidz implementation: fastest (default)", "charp"},
+		{"zfs_nodesc", "(No description available)", ""},
+	}
+
+	for _, test := range tests {
+		if descs[test.name] != test.desc || types[test.name] != test.typ {
+			t.Errorf("parseModinfo() %s = \"%v\", \"%v\" (wanted \"%v\", \"%v\")",
+				test.name, descs[test.name], types[test.name], test.desc, test.typ)
+		}
+	}
+}
+
+func TestKstatReadErrors(t *testing.T) {
+
+	defer func() { kstatErrors = make(map[string]error) }()
+
+	var tests = []struct {
+		key    string
+		err    error
+		wanted int
+	}{
+		{"vdev_cache_stats", os.ErrNotExist, 0},
+		{"xuio_stats", os.ErrPermission, 1},
+		{"zstd", os.ErrNotExist, 0},
+		{"zil", os.ErrNotExist, 1},
+		{"arcstats", errors.New("short read"), 1},
+	}
+
+	for _, test := range tests {
+		kstatErrors = map[string]error{test.key: test.err}
+		if got := kstatReadErrors(); got != test.wanted {
+			t.Errorf("kstatReadErrors(%s: %v) = %d (wanted \"%d\")", test.key, test.err, got, test.wanted)
+		}
+	}
+}
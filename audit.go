@@ -0,0 +1,185 @@
+// Audit log of tunable changes for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// Every tunable the tool writes is recorded with its old and new value, so
+// "arc_summary tunables rollback" can undo changes one at a time. The log has
+// one JSON record per line. See arc_summary.go for the license
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const defaultAuditPath = "/var/lib/arc_summary/tunables-audit.log"
+
+// auditRecord is a single change of a tunable. Records written by a rollback
+// point to the record they undo
+type auditRecord struct {
+	ID     int    `json:"id"`
+	Time   int64  `json:"time"`
+	Name   string `json:"name"`
+	Old    string `json:"old"`
+	New    string `json:"new"`
+	Undoes int    `json:"undoes,omitempty"`
+}
+
+// readAudit returns all records of the audit log. A missing log is empty
+func readAudit(path string) ([]auditRecord, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []auditRecord
+	input := bufio.NewScanner(f)
+
+	for input.Scan() {
+		var rec auditRecord
+		if err := json.Unmarshal(input.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("corrupt record in %s: %v", path, err)
+		}
+		records = append(records, rec)
+	}
+
+	return records, input.Err()
+}
+
+// appendAudit adds a record to the audit log, numbering it after the last
+// record in the log
+func appendAudit(path string, rec auditRecord) error {
+
+	records, err := readAudit(path)
+	if err != nil {
+		return err
+	}
+
+	rec.ID = 1
+	if len(records) > 0 {
+		rec.ID = records[len(records)-1].ID + 1
+	}
+	rec.Time = time.Now().Unix()
+
+	f, err := openAuditLog(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return json.NewEncoder(f).Encode(rec)
+}
+
+// openAuditLog opens the audit log for appending. The directory it goes in
+// doesn't exist before the first change on a fresh system
+func openAuditLog(path string) (*os.File, error) {
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	return os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+}
+
+// setTunable writes a tunable and records the change in the audit log. The
+// change is not made if it can't be recorded, since it could then not be
+// rolled back. With -dry-run, we only say what we would do
+func setTunable(name, old, value string, undoes int) error {
+
+	if bundleFiles != nil {
+		return fmt.Errorf("can't change tunables when reading from a bundle")
+	}
+
+	if *OptDryRun {
+		fmt.Fprintf(reportOut, "DRY RUN: would write %s to %s (currently %s) and log it to %s\n",
+			value, filepath.Join(tunablesPath, name), old, *OptAuditLog)
+		return nil
+	}
+
+	// Make sure we can write the log before touching anything
+	f, err := openAuditLog(*OptAuditLog)
+	if err != nil {
+		return fmt.Errorf("can't write audit log: %v", err)
+	}
+	f.Close()
+
+	if err := writeTunable(name, value); err != nil {
+		return err
+	}
+
+	rec := auditRecord{Name: name, Old: old, New: value, Undoes: undoes}
+
+	if err := appendAudit(*OptAuditLog, rec); err != nil {
+		log.Print("WARNING: ", name, " was changed but the audit log couldn't be written: ", err)
+	}
+
+	return nil
+}
+
+// lastUndoable returns the most recent change in the log that has not been
+// rolled back yet, or nil if there is none. Rollbacks themselves are not
+// undone, so repeated rollbacks walk back through the history
+func lastUndoable(records []auditRecord) *auditRecord {
+
+	undone := make(map[int]bool)
+
+	for _, r := range records {
+		if r.Undoes != 0 {
+			undone[r.Undoes] = true
+		}
+	}
+
+	for i := len(records) - 1; i >= 0; i-- {
+		r := records[i]
+		if r.Undoes == 0 && !undone[r.ID] {
+			return &records[i]
+		}
+	}
+
+	return nil
+}
+
+// rollbackTunable undoes the most recent change in the audit log
+func rollbackTunable() {
+
+	records, err := readAudit(*OptAuditLog)
+	if err != nil {
+		log.Fatal("Couldn't read audit log: ", err)
+	}
+
+	rec := lastUndoable(records)
+	if rec == nil {
+		fmt.Fprintln(reportOut, "Nothing to roll back")
+		return
+	}
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	getTunables(ctx, tunables)
+	current := tunables[rec.Name]
+
+	if current != rec.New {
+		log.Fatal(rec.Name, " was changed to ", current, " outside of arc_summary, not rolling back")
+	}
+
+	if err := setTunable(rec.Name, current, rec.Old, rec.ID); err != nil {
+		log.Fatal("Couldn't roll back ", rec.Name, ": ", err)
+	}
+
+	if *OptDryRun {
+		return
+	}
+
+	fmt.Fprintf(reportOut, "%s set back from %s to %s (change of %s)\n", rec.Name, rec.New, rec.Old,
+		time.Unix(rec.Time, 0).Format(time.RFC1123))
+}
@@ -0,0 +1,58 @@
+// Test file for audit.go
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLastUndoable(t *testing.T) {
+	var tests = []struct {
+		name    string
+		records []auditRecord
+		want    int // 0 means nothing to undo
+	}{
+		{"empty log", nil, 0},
+		{"single change", []auditRecord{{ID: 1}}, 1},
+		{"last change undone",
+			[]auditRecord{{ID: 1}, {ID: 2}, {ID: 3, Undoes: 2}}, 1},
+		{"everything undone",
+			[]auditRecord{{ID: 1}, {ID: 2, Undoes: 1}}, 0},
+		{"new change after rollback",
+			[]auditRecord{{ID: 1}, {ID: 2, Undoes: 1}, {ID: 3}}, 3},
+	}
+
+	for _, test := range tests {
+		got := 0
+		if r := lastUndoable(test.records); r != nil {
+			got = r.ID
+		}
+		if got != test.want {
+			t.Errorf("lastUndoable(%s) = %d (wanted %d)", test.name, got, test.want)
+		}
+	}
+}
+
+func TestAppendAuditCreatesDir(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "arc_summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "var", "lib", "arc_summary", "audit.log")
+
+	for i := 1; i <= 2; i++ {
+		if err := appendAudit(path, auditRecord{Name: "zfs_arc_max", Old: "0", New: "1"}); err != nil {
+			t.Fatalf("appendAudit(%s) = %v (wanted no error)", path, err)
+		}
+	}
+
+	records, err := readAudit(path)
+	if err != nil || len(records) != 2 || records[1].ID != 2 {
+		t.Errorf("readAudit(%s) = %v, %v (wanted 2 records)", path, records, err)
+	}
+}
@@ -0,0 +1,175 @@
+// Moving averages of rates for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// A single rate is too noisy to alert on, so watch and serve mode keep 1, 5
+// and 15 minute moving averages of the hit, miss and eviction rates, much
+// like the load average of Unix. The averages are exponentially weighted, so
+// they need no history and cope with irregular intervals. They show up in
+// the ARC section of the report, in JSON as "averages" and as
+// arc_summary_rate_average in the exporter. See arc_summary.go for the
+// license
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// averagedRates are the rates we average, with the arcstats counter they
+// are computed from and their label in the report. "deleted" counts the
+// buffers evicted from the ARC
+var averagedRates = []struct{ name, stat, label string }{
+	{"hits", "hits", "Hits:"},
+	{"misses", "misses", "Misses:"},
+	{"evictions", "deleted", "Evictions:"},
+}
+
+// averageWindows are the periods of the moving averages
+var averageWindows = []struct {
+	name   string
+	period time.Duration
+}{
+	{"1m", time.Minute},
+	{"5m", 5 * time.Minute},
+	{"15m", 15 * time.Minute},
+}
+
+// movingAverages holds the averages and the counters of the last sample.
+// The averages are keyed by rate and window, eg "hits_5m"
+type movingAverages struct {
+	mu       sync.Mutex
+	last     map[string]float64
+	lastTime time.Time
+	avg      map[string]float64
+}
+
+// averages are the moving averages of watch and serve mode
+var averages movingAverages
+
+// averageKey returns the key of the average of a rate over a window
+func averageKey(rate, window string) string {
+	return rate + "_" + window
+}
+
+// update adds a sample of the counters taken at time t. The first sample only
+// sets the baseline, the second starts the averages at its rates. A counter
+// that went backwards (eg because the module was reloaded) starts over
+// without disturbing the averages
+func (m *movingAverages) update(t time.Time, counters map[string]float64) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dt := t.Sub(m.lastTime).Seconds()
+
+	if m.last != nil && dt > 0 {
+		if m.avg == nil {
+			m.avg = make(map[string]float64)
+		}
+
+		for _, r := range averagedRates {
+			v, ok := counters[r.stat]
+			last, hadLast := m.last[r.stat]
+			if !ok || !hadLast || v < last {
+				continue
+			}
+
+			rate := (v - last) / dt
+
+			for _, w := range averageWindows {
+				key := averageKey(r.name, w.name)
+				old, started := m.avg[key]
+				if !started {
+					m.avg[key] = rate
+					continue
+				}
+				decay := math.Exp(-dt / w.period.Seconds())
+				m.avg[key] = old*decay + rate*(1-decay)
+			}
+		}
+	}
+
+	m.last, m.lastTime = counters, t
+}
+
+// values returns a copy of the averages, nil if there are none yet
+func (m *movingAverages) values() map[string]float64 {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.avg) == 0 {
+		return nil
+	}
+
+	result := make(map[string]float64)
+	for k, v := range m.avg {
+		result[k] = v
+	}
+
+	return result
+}
+
+// updateAverages adds the counters that were last read into the globals
+func updateAverages(t time.Time) {
+
+	counters := make(map[string]float64)
+
+	for _, r := range averagedRates {
+		if v, err := lookupStat(r.stat); err == nil {
+			counters[r.stat] = v
+		}
+	}
+
+	averages.update(t, counters)
+}
+
+// printAverages prints the moving averages, if there are any
+func printAverages() {
+
+	values := averages.values()
+	if values == nil {
+		return
+	}
+
+	var windows []string
+	for _, w := range averageWindows {
+		windows = append(windows, w.name)
+	}
+
+	fmt.Fprintf(reportOut, "\nRates per second (%s averages):\n", strings.Join(windows, "/"))
+
+	for _, r := range averagedRates {
+		var parts []string
+		for _, w := range averageWindows {
+			v, ok := values[averageKey(r.name, w.name)]
+			if !ok {
+				parts = append(parts, "n/a")
+				continue
+			}
+			parts = append(parts, fmt.Sprintf("%.1f", v))
+		}
+		prtL2(r.label, strings.Join(parts, " / "))
+	}
+}
+
+// averageLines returns the averages in the Prometheus text format
+func averageLines(values map[string]float64) []string {
+
+	var lines []string
+
+	for _, r := range averagedRates {
+		for _, w := range averageWindows {
+			if v, ok := values[averageKey(r.name, w.name)]; ok {
+				lines = append(lines, fmt.Sprintf("arc_summary_rate_average{rate=%q,window=%q} %g", r.name, w.name, v))
+			}
+		}
+	}
+
+	sort.Strings(lines)
+	return lines
+}
@@ -0,0 +1,67 @@
+// Test file for averages.go
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMovingAverages(t *testing.T) {
+
+	var m movingAverages
+	start := time.Unix(1000, 0)
+
+	m.update(start, map[string]float64{"hits": 0, "misses": 0})
+	if v := m.values(); v != nil {
+		t.Errorf("values() after one sample = %v (wanted nil)", v)
+	}
+
+	// The first rate starts every average
+	m.update(start.Add(time.Minute), map[string]float64{"hits": 600, "misses": 60})
+
+	var tests = []struct {
+		key    string
+		wanted float64
+	}{
+		{"hits_1m", 10},
+		{"hits_15m", 10},
+		{"misses_5m", 1},
+	}
+
+	v := m.values()
+	for _, test := range tests {
+		if math.Abs(v[test.key]-test.wanted) > 1e-9 {
+			t.Errorf("values()[%s] = %v (wanted \"%v\")", test.key, v[test.key], test.wanted)
+		}
+	}
+
+	if _, ok := v["evictions_1m"]; ok {
+		t.Errorf("values() has evictions without a deleted counter")
+	}
+
+	// A minute without hits takes the 1 minute average down to 1/e
+	m.update(start.Add(2*time.Minute), map[string]float64{"hits": 600, "misses": 60})
+	if got, wanted := m.values()["hits_1m"], 10/math.E; math.Abs(got-wanted) > 1e-9 {
+		t.Errorf("values()[hits_1m] = %v (wanted \"%v\")", got, wanted)
+	}
+
+	// A counter that went backwards doesn't change the averages
+	before := m.values()["hits_15m"]
+	m.update(start.Add(3*time.Minute), map[string]float64{"hits": 5, "misses": 60})
+	if got := m.values()["hits_15m"]; got != before {
+		t.Errorf("values()[hits_15m] after reset = %v (wanted \"%v\")", got, before)
+	}
+}
+
+func TestAverageLines(t *testing.T) {
+
+	lines := averageLines(map[string]float64{"hits_1m": 2.5, "misses_15m": 0})
+	wanted := `arc_summary_rate_average{rate="hits",window="1m"} 2.5` + "\n" +
+		`arc_summary_rate_average{rate="misses",window="15m"} 0`
+
+	if got := strings.Join(lines, "\n"); got != wanted {
+		t.Errorf("averageLines() = %q (wanted \"%q\")", got, wanted)
+	}
+}
@@ -0,0 +1,168 @@
+// Checksum and RAID-Z benchmark section for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// When the module loads, ZFS benchmarks its implementations of the
+// fletcher-4 checksum and of RAID-Z parity and picks the fastest, unless a
+// tunable says otherwise. The results are in the fletcher_4_bench and
+// vdev_raidz_bench kstats, which are tables rather than the usual name and
+// value lines. The speeds are in bytes per second; we show GiB/s and mark
+// the implementation in use for each operation with '*'. Linux only. See
+// arc_summary.go for the license
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// zcommonParamsPath holds zfs_fletcher_4_impl before zcommon was merged into
+// the zfs module
+const zcommonParamsPath = "/sys/module/zcommon/parameters"
+
+// benchTables are the benchmark kstats with their title and the tunable
+// that selects the implementation
+var benchTables = []struct{ file, title, tunable string }{
+	{"fletcher_4_bench", "Fletcher-4 checksum", "zfs_fletcher_4_impl"},
+	{"vdev_raidz_bench", "RAID-Z parity", "zfs_vdev_raidz_impl"},
+}
+
+// benchTable is a parsed benchmark kstat. Speeds are by implementation and
+// then column, fastest holds the name of the fastest implementation of
+// each column
+type benchTable struct {
+	columns []string
+	impls   []string
+	speeds  map[string][]uint64
+	fastest []string
+}
+
+// parseBench parses a benchmark kstat: the kstat header, a line with
+// "implementation" and the columns, one line of speeds per implementation
+// and a last line with the fastest implementation of each column
+func parseBench(data []byte) (benchTable, error) {
+
+	t := benchTable{speeds: make(map[string][]uint64)}
+
+	input := bufio.NewScanner(bytes.NewReader(data))
+
+	for input.Scan() {
+		fields := strings.Fields(input.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		switch {
+		case fields[0] == "implementation":
+			t.columns = fields[1:]
+		case t.columns == nil:
+			// kstat header
+		case fields[0] == "fastest":
+			t.fastest = fields[1:]
+		default:
+			var speeds []uint64
+			for _, f := range fields[1:] {
+				v, err := strconv.ParseUint(f, 10, 64)
+				if err != nil {
+					return benchTable{}, fmt.Errorf("bad speed '%s' for %s", f, fields[0])
+				}
+				speeds = append(speeds, v)
+			}
+			t.impls = append(t.impls, fields[0])
+			t.speeds[fields[0]] = speeds
+		}
+	}
+
+	if len(t.columns) == 0 || len(t.impls) == 0 {
+		return benchTable{}, fmt.Errorf("no benchmark results")
+	}
+
+	return t, input.Err()
+}
+
+// selected returns the implementation used for a column, given the active
+// choice of the tunable. "cycle" uses all of them in turn, so none is
+// selected
+func (t benchTable) selected(active string, col int) string {
+
+	switch active {
+	case "fastest", "":
+		if col < len(t.fastest) {
+			return t.fastest[col]
+		}
+		return ""
+	case "cycle":
+		return ""
+	}
+
+	return active
+}
+
+// printBenchTable prints a benchmark table
+func printBenchTable(t benchTable, active string) {
+
+	fmt.Fprintf(reportOut, indent+"%-16s", "Implementation")
+	for _, c := range t.columns {
+		fmt.Fprintf(reportOut, "%10s", c)
+	}
+	fmt.Fprintln(reportOut)
+
+	for _, impl := range t.impls {
+		fmt.Fprintf(reportOut, indent+"%-16s", impl)
+		for i, v := range t.speeds[impl] {
+			mark := " "
+			if t.selected(active, i) == impl {
+				mark = "*"
+			}
+			fmt.Fprintf(reportOut, "%9.1f%s", float64(v)/(1<<30), mark)
+		}
+		fmt.Fprintln(reportOut)
+	}
+}
+
+// printBench displays the results of the checksum and parity benchmarks
+func printBench() {
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	shown := 0
+
+	for _, b := range benchTables {
+		data, err := readFile(ctx, procPath+b.file)
+		if err != nil {
+			continue
+		}
+
+		t, err := parseBench(data)
+		if err != nil {
+			addWarning("bench", "%s: %v", b.file, err)
+			continue
+		}
+
+		active := ""
+		for _, dir := range []string{tunablesPath, zcommonParamsPath} {
+			if impl, err := readFile(ctx, dir+"/"+b.tunable); err == nil {
+				active, _ = parseImpl(string(impl))
+				break
+			}
+		}
+
+		value := active
+		if value == "" {
+			value = "n/a"
+		}
+		fmt.Fprintf(reportOut, "\n%s (GiB/s, %s = %s):\n", b.title, b.tunable, value)
+		printBenchTable(t, active)
+		shown++
+	}
+
+	if shown == 0 {
+		skipSection("bench", fmt.Errorf("couldn't read %sfletcher_4_bench or vdev_raidz_bench", procPath))
+		return
+	}
+
+	fmt.Fprintln(reportOut, "\n"+indent+"* implementation in use")
+}
@@ -0,0 +1,57 @@
+// Test file for bench.go
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+const benchFletcher = `5 0 0x01 -1 0 2803245757 1050426466963
+implementation   native         byteswap
+scalar           4990167024     3962929617
+avx2             18787495898    17625416530
+avx512f          19987495898    16625416530
+fastest          avx512f        avx2
+`
+
+func TestParseBench(t *testing.T) {
+
+	b, err := parseBench([]byte(benchFletcher))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if wanted := []string{"native", "byteswap"}; !reflect.DeepEqual(b.columns, wanted) {
+		t.Errorf("parseBench() columns = %v (wanted \"%v\")", b.columns, wanted)
+	}
+	if wanted := []string{"scalar", "avx2", "avx512f"}; !reflect.DeepEqual(b.impls, wanted) {
+		t.Errorf("parseBench() impls = %v (wanted \"%v\")", b.impls, wanted)
+	}
+	if v := b.speeds["avx2"][1]; v != 17625416530 {
+		t.Errorf("parseBench() avx2 byteswap = %v (wanted \"17625416530\")", v)
+	}
+
+	var tests = []struct {
+		active string
+		col    int
+		want   string
+	}{
+		{"fastest", 0, "avx512f"},
+		{"fastest", 1, "avx2"},
+		{"", 0, "avx512f"},
+		{"scalar", 1, "scalar"},
+		{"cycle", 0, ""},
+	}
+
+	for _, test := range tests {
+		if got := b.selected(test.active, test.col); got != test.want {
+			t.Errorf("selected(%s, %d) = %v (wanted \"%v\")", test.active, test.col, got, test.want)
+		}
+	}
+
+	for _, bad := range []string{"", "implementation native\n", "implementation native\nscalar lots\n"} {
+		if _, err := parseBench([]byte(bad)); err == nil {
+			t.Errorf("parseBench(%q) succeeded (wanted error)", bad)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+// Breakdowns of the ARC for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// A breakdown splits a total from arcstats into its parts, such as the hits
+// by the list the buffer was found on. Not every kernel has every stat, so
+// parts that are missing are left out instead of being printed as zero. See
+// arc_summary.go for the license
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// breakdownPart is one line of a breakdown
+type breakdownPart struct {
+	label string
+	value uint64
+}
+
+// statValue returns the value of a stat and if it exists
+func statValue(stats map[string]string, name string) (uint64, bool) {
+
+	v, ok := stats[name]
+	if !ok {
+		return 0, false
+	}
+
+	i, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return i, true
+}
+
+// hitsByList returns the ARC hits split by the list the buffer was found on.
+// There is no stat for the anonymous list, so those are the hits left over
+// after the others. The uncached list only exists since OpenZFS 2.2. Returns
+// false if the kernel doesn't have the stats
+func hitsByList(stats map[string]string) ([]breakdownPart, uint64, bool) {
+
+	total, ok := statValue(stats, "hits")
+	if !ok {
+		return nil, 0, false
+	}
+
+	lists := []breakdownPart{
+		{"Most Recently Used (MRU):", 0},
+		{"Most Frequently Used (MFU):", 0},
+		{"MRU ghost:", 0},
+		{"MFU ghost:", 0},
+	}
+
+	var sum uint64
+
+	for i, name := range []string{"mru_hits", "mfu_hits", "mru_ghost_hits", "mfu_ghost_hits"} {
+		v, ok := statValue(stats, name)
+		if !ok {
+			return nil, 0, false
+		}
+		lists[i].value = v
+		sum += v
+	}
+
+	if v, ok := statValue(stats, "uncached_hits"); ok {
+		lists = append(lists, breakdownPart{"Uncached:", v})
+		sum += v
+	}
+
+	var anon uint64
+	if total > sum {
+		anon = total - sum
+	}
+
+	return append([]breakdownPart{{"Anonymous:", anon}}, lists...), total, true
+}
+
+// printBreakdown prints the parts of a breakdown with their share of the
+// total, formatted with f
+func printBreakdown(title string, parts []breakdownPart, total uint64, f func(string) string) {
+
+	fmt.Fprintln(reportOut, "\n"+title)
+
+	t := strconv.FormatUint(total, 10)
+
+	for _, p := range parts {
+		v := strconv.FormatUint(p.value, 10)
+		prtL2p(p.label, fPerc(v, t), f(v))
+	}
+}
+
+// pinnedWarning is the share of the ARC in percent that can't be evicted
+// above which we warn
+const pinnedWarning = 75
+
+// evictableParts returns the evictable data and metadata of the MRU or MFU
+// list and the rest of the list, which can't be evicted right now because it
+// is in use. The total is the size of the list
+func evictableParts(stats map[string]string, list string) ([]breakdownPart, uint64, bool) {
+
+	size, ok1 := statValue(stats, list+"_size")
+	data, ok2 := statValue(stats, list+"_evictable_data")
+	meta, ok3 := statValue(stats, list+"_evictable_metadata")
+	if !ok1 || !ok2 || !ok3 {
+		return nil, 0, false
+	}
+
+	var pinned uint64
+	if size > data+meta {
+		pinned = size - data - meta
+	}
+
+	return []breakdownPart{
+		{"Evictable data:", data},
+		{"Evictable metadata:", meta},
+		{"Not evictable:", pinned},
+	}, size, true
+}
+
+// pinnedSize returns how much of the ARC can't be evicted: everything but
+// the evictable parts of MRU and MFU, which includes the anonymous list and
+// the headers
+func pinnedSize(stats map[string]string) (uint64, uint64, bool) {
+
+	size, ok := statValue(stats, "size")
+	if !ok {
+		return 0, 0, false
+	}
+
+	var evictable uint64
+
+	for _, list := range []string{"mru", "mfu"} {
+		parts, _, ok := evictableParts(stats, list)
+		if !ok {
+			return 0, 0, false
+		}
+		evictable += parts[0].value + parts[1].value
+	}
+
+	// OpenZFS 2.2 added evictable stats for the other lists
+	for _, list := range []string{"anon", "uncached"} {
+		if parts, _, ok := evictableParts(stats, list); ok {
+			evictable += parts[0].value + parts[1].value
+		}
+	}
+
+	if evictable > size {
+		return 0, size, true
+	}
+
+	return size - evictable, size, true
+}
+
+// printEvictable prints how much of the ARC could be evicted under memory
+// pressure
+func printEvictable(stats map[string]string) {
+
+	for _, l := range []struct{ list, title string }{
+		{"mru", "MRU evictable:"},
+		{"mfu", "MFU evictable:"},
+		{"uncached", "Uncached evictable:"},
+	} {
+		if parts, total, ok := evictableParts(stats, l.list); ok {
+			printBreakdown(l.title, parts, total, fBytes)
+		}
+	}
+
+	pinned, size, ok := pinnedSize(stats)
+	if !ok {
+		return
+	}
+
+	p, s := strconv.FormatUint(pinned, 10), strconv.FormatUint(size, 10)
+	prtL1p("ARC not evictable:", fPerc(p, s), fBytes(p))
+	explain(explainPinned(pinned, size))
+
+	if size > 0 && 100*pinned/size > pinnedWarning {
+		fmt.Fprintf(reportOut, "%sWARNING: %d %% of the ARC can't be evicted, so it can't shrink much under memory pressure\n",
+			indent, 100*pinned/size)
+	}
+}
+
+// arcConsumers are the stats that the memory of the ARC is split into. Older
+// kernels lack some of them
+var arcConsumers = []struct{ label, stat string }{
+	{"Data buffers:", "data_size"},
+	{"Metadata buffers:", "metadata_size"},
+	{"Headers:", "hdr_size"},
+	{"L2ARC headers:", "l2_hdr_size"},
+	{"Dnodes:", "dnode_size"},
+	{"Dbufs:", "dbuf_size"},
+	{"Bonus buffers:", "bonus_size"},
+	{"ABD chunk waste:", "abd_chunk_waste_size"},
+}
+
+// consumerParts returns the memory of the ARC split by what uses it, with the
+// size of the ARC as the total
+func consumerParts(stats map[string]string) ([]breakdownPart, uint64, bool) {
+
+	size, ok := statValue(stats, "size")
+	if !ok {
+		return nil, 0, false
+	}
+
+	var parts []breakdownPart
+
+	for _, c := range arcConsumers {
+		if v, ok := statValue(stats, c.stat); ok {
+			parts = append(parts, breakdownPart{c.label, v})
+		}
+	}
+
+	return parts, size, len(parts) > 0
+}
+
+// arcLists are the lists of the ARC that hold memory, with the names used in
+// the stats. Ghost lists only hold headers of evicted buffers
+var arcLists = []struct{ label, name string }{
+	{"Anonymous", "anon"},
+	{"MRU", "mru"},
+	{"MFU", "mfu"},
+	{"Uncached", "uncached"},
+}
+
+// listParts returns the data and metadata held by each list of the ARC with
+// the size of the ARC as the total. The split is only there since OpenZFS
+// 2.2, so older kernels return false
+func listParts(stats map[string]string) ([]breakdownPart, uint64, bool) {
+
+	size, ok := statValue(stats, "size")
+	if !ok {
+		return nil, 0, false
+	}
+
+	var parts []breakdownPart
+
+	for _, l := range arcLists {
+		data, ok1 := statValue(stats, l.name+"_data")
+		meta, ok2 := statValue(stats, l.name+"_metadata")
+		if !ok1 || !ok2 {
+			continue
+		}
+		parts = append(parts,
+			breakdownPart{l.label + " data:", data},
+			breakdownPart{l.label + " metadata:", meta})
+	}
+
+	return parts, size, len(parts) > 0
+}
+
+// printIOHits prints the hits on buffers that were still being read, which
+// OpenZFS 2.2 counts apart from the normal hits
+func printIOHits(stats map[string]string) {
+
+	iohits, ok := statValue(stats, "iohits")
+	if !ok {
+		return
+	}
+
+	hits, _ := statValue(stats, "hits")
+	misses, _ := statValue(stats, "misses")
+
+	i := strconv.FormatUint(iohits, 10)
+	all := strconv.FormatUint(hits+misses+iohits, 10)
+	prtL2p("Hits on reads in progress:", fPerc(i, all), fHits(i))
+}
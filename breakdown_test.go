@@ -0,0 +1,133 @@
+// Test file for breakdown.go
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHitsByList(t *testing.T) {
+
+	stats := map[string]string{
+		"hits":           "100",
+		"mru_hits":       "30",
+		"mfu_hits":       "50",
+		"mru_ghost_hits": "5",
+		"mfu_ghost_hits": "10",
+	}
+
+	parts, total, ok := hitsByList(stats)
+	if !ok || total != 100 {
+		t.Fatalf("hitsByList() = %v, %v (wanted 100, true)", total, ok)
+	}
+
+	var got []uint64
+	for _, p := range parts {
+		got = append(got, p.value)
+	}
+
+	wanted := []uint64{5, 30, 50, 5, 10}
+	if !reflect.DeepEqual(got, wanted) {
+		t.Errorf("hitsByList() = %v (wanted \"%v\")", got, wanted)
+	}
+
+	// The lists can count a few more hits than the total while the kstats
+	// are being updated
+	stats["mfu_hits"] = "60"
+	if parts, _, _ := hitsByList(stats); parts[0].value != 0 {
+		t.Errorf("hitsByList() anonymous = %d (wanted 0)", parts[0].value)
+	}
+
+	delete(stats, "mfu_ghost_hits")
+	if _, _, ok := hitsByList(stats); ok {
+		t.Errorf("hitsByList() without mfu_ghost_hits succeeded (wanted false)")
+	}
+}
+
+func TestPinnedSize(t *testing.T) {
+
+	stats := map[string]string{
+		"size":                   "1000",
+		"mru_size":               "400",
+		"mru_evictable_data":     "200",
+		"mru_evictable_metadata": "100",
+		"mfu_size":               "500",
+		"mfu_evictable_data":     "300",
+		"mfu_evictable_metadata": "0",
+	}
+
+	parts, total, ok := evictableParts(stats, "mru")
+	if !ok || total != 400 || parts[2].value != 100 {
+		t.Errorf("evictableParts(mru) = %v, %d, %v (wanted 100 not evictable of 400)", parts, total, ok)
+	}
+
+	pinned, size, ok := pinnedSize(stats)
+	if !ok || pinned != 400 || size != 1000 {
+		t.Errorf("pinnedSize() = %d, %d, %v (wanted 400, 1000, true)", pinned, size, ok)
+	}
+
+	delete(stats, "mfu_evictable_metadata")
+	if _, _, ok := pinnedSize(stats); ok {
+		t.Errorf("pinnedSize() without mfu_evictable_metadata succeeded (wanted false)")
+	}
+}
+
+func TestConsumerParts(t *testing.T) {
+
+	stats := map[string]string{
+		"size":       "1000",
+		"data_size":  "600",
+		"hdr_size":   "50",
+		"dnode_size": "100",
+	}
+
+	parts, total, ok := consumerParts(stats)
+	wanted := []breakdownPart{{"Data buffers:", 600}, {"Headers:", 50}, {"Dnodes:", 100}}
+	if !ok || total != 1000 || !reflect.DeepEqual(parts, wanted) {
+		t.Errorf("consumerParts() = %v, %d, %v (wanted \"%v\", 1000, true)", parts, total, ok, wanted)
+	}
+
+	if _, _, ok := consumerParts(map[string]string{"size": "1000"}); ok {
+		t.Errorf("consumerParts() without consumers succeeded (wanted false)")
+	}
+}
+
+func TestListParts(t *testing.T) {
+
+	stats := map[string]string{
+		"size":          "1000",
+		"mru_data":      "300",
+		"mru_metadata":  "100",
+		"mfu_data":      "400",
+		"mfu_metadata":  "50",
+		"uncached_data": "10",
+	}
+
+	parts, total, ok := listParts(stats)
+	wanted := []breakdownPart{{"MRU data:", 300}, {"MRU metadata:", 100}, {"MFU data:", 400}, {"MFU metadata:", 50}}
+	if !ok || total != 1000 || !reflect.DeepEqual(parts, wanted) {
+		t.Errorf("listParts() = %v, %d, %v (wanted \"%v\", 1000, true)", parts, total, ok, wanted)
+	}
+
+	// Kernels before OpenZFS 2.2 don't split the lists
+	if _, _, ok := listParts(map[string]string{"size": "1000", "mru_size": "400"}); ok {
+		t.Errorf("listParts() without data and metadata succeeded (wanted false)")
+	}
+}
+
+func TestHitsByListUncached(t *testing.T) {
+
+	stats := map[string]string{
+		"hits":           "100",
+		"mru_hits":       "30",
+		"mfu_hits":       "50",
+		"mru_ghost_hits": "0",
+		"mfu_ghost_hits": "0",
+		"uncached_hits":  "15",
+	}
+
+	parts, _, ok := hitsByList(stats)
+	if !ok || len(parts) != 6 || parts[0].value != 5 || parts[5].value != 15 {
+		t.Errorf("hitsByList() with uncached_hits = %v (wanted 5 anonymous, 15 uncached)", parts)
+	}
+}
@@ -0,0 +1,387 @@
+// Support bundles for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// "arc_summary bundle out.tar.gz" collects everything the tool reads (kstats,
+// tunables, module versions, memory information, modprobe.d files and the
+// output of the commands we run) together with a rendered report into one
+// archive. With -bundle, any report can be created from such an archive
+// instead of the live system. See arc_summary.go for the license
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// bundleFiles holds the contents of a bundle given with -bundle, keyed by the
+// path of the original file without the leading slash. If nil, we read from
+// the live system
+var bundleFiles map[string][]byte
+
+// bundleExtraFiles are read in addition to the kstats and tunables
+var bundleExtraFiles = []string{
+	hostnamePath,
+	osReleasePath,
+	zfsVersionPath,
+	splVersionPath,
+	meminfoPath,
+	uptimePath,
+	cmdlinePath,
+	diskstatsPath,
+	icpParamsPath + "/icp_aes_impl",
+	icpParamsPath + "/icp_gcm_impl",
+	kcfStatsPath,
+	zcommonParamsPath + "/zfs_fletcher_4_impl",
+}
+
+// bundleCommands are the external commands whose output goes into the
+// bundle. These must be the same calls the rest of the code makes. The
+// output of modinfo is added apart, since it may not be run at all
+var bundleCommands = [][]string{
+	{"zpool", "list", "-H", "-o", "name"},
+	{"zpool", "status", "-P"},
+	{"zpool", "status", "-t", "-P"},
+	{"zpool", "iostat", "-q", "-v", "-H", "-p"},
+	{"zpool", "iostat", "-w", "-H", "-p"},
+	{"zpool", "get", "-H", "-o", "name,value", "feature@encryption"},
+}
+
+// bundleKey returns the name of a file in the bundle
+func bundleKey(path string) string {
+	return strings.TrimPrefix(filepath.Clean(path), "/")
+}
+
+// commandKey returns the name of the file in the bundle that holds the output
+// of a command
+func commandKey(name string, args ...string) string {
+	return "commands/" + strings.Join(append([]string{filepath.Base(name)}, args...), " ")
+}
+
+// collectContext returns the context for one round of collecting stats,
+// which is cancelled after the time given with -timeout
+func collectContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), *OptTimeout)
+}
+
+// ioResult is the result of a read that runs in the background
+type ioResult struct {
+	data  []byte
+	names []string
+	err   error
+}
+
+// withContext runs f in the background and waits for it until ctx is done.
+// Reads of /proc and /sys can't be interrupted, so a read that hangs keeps
+// its goroutine, but the caller can get on with things
+func withContext(ctx context.Context, path string, f func() ioResult) ioResult {
+
+	ch := make(chan ioResult, 1)
+	go func() { ch <- f() }()
+
+	select {
+	case r := <-ch:
+		return r
+	case <-ctx.Done():
+		return ioResult{err: fmt.Errorf("reading %s: %v", path, ctx.Err())}
+	}
+}
+
+// readFile returns the contents of a file from the bundle if one was given,
+// otherwise from the live system
+func readFile(ctx context.Context, path string) ([]byte, error) {
+
+	if bundleFiles == nil {
+		r := withContext(ctx, path, func() ioResult {
+			data, err := ioutil.ReadFile(path)
+			return ioResult{data: data, err: err}
+		})
+		return r.data, r.err
+	}
+
+	data, ok := bundleFiles[bundleKey(path)]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: "bundle:" + path, Err: os.ErrNotExist}
+	}
+
+	return data, nil
+}
+
+// readDirNames returns the sorted names of the files in a directory, from the
+// bundle if one was given, otherwise from the live system
+func readDirNames(ctx context.Context, dir string) ([]string, error) {
+
+	var names []string
+
+	if bundleFiles == nil {
+		r := withContext(ctx, dir, func() ioResult {
+			infos, err := ioutil.ReadDir(dir)
+			if err != nil {
+				return ioResult{err: err}
+			}
+			for _, i := range infos {
+				names = append(names, i.Name())
+			}
+			return ioResult{names: names}
+		})
+		return r.names, r.err
+	}
+
+	prefix := bundleKey(dir) + "/"
+
+	for k := range bundleFiles {
+		if strings.HasPrefix(k, prefix) && !strings.Contains(k[len(prefix):], "/") {
+			names = append(names, k[len(prefix):])
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, &os.PathError{Op: "open", Path: "bundle:" + dir, Err: os.ErrNotExist}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// runCommand returns the output of an external command, or the output saved
+// in the bundle if one was given. The command is killed when ctx is done
+func runCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+
+	if bundleFiles == nil {
+		if err := execLimit.wait(ctx); err != nil {
+			return nil, fmt.Errorf("waiting to run %s: %v", name, err)
+		}
+		return exec.CommandContext(ctx, name, args...).Output()
+	}
+
+	data, ok := bundleFiles[commandKey(name, args...)]
+	if !ok {
+		return nil, fmt.Errorf("output of '%s' is not in the bundle", strings.Join(append([]string{name}, args...), " "))
+	}
+
+	return data, nil
+}
+
+// loadBundle reads all files of a bundle into memory
+func loadBundle(path string) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatal("Couldn't open bundle ", path, ": ", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		log.Fatal("Couldn't read bundle ", path, ": ", err)
+	}
+
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatal("Couldn't read bundle ", path, ": ", err)
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			log.Fatal("Couldn't read bundle ", path, ": ", err)
+		}
+
+		files[hdr.Name] = data
+	}
+
+	bundleFiles = files
+}
+
+// errWriter passes output on to w until the first error, which it keeps
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (e *errWriter) Write(p []byte) (int, error) {
+	if e.err == nil {
+		_, e.err = e.w.Write(p)
+	}
+	return len(p), nil
+}
+
+// printTo runs f with the report going to w and returns the first error
+// writing it
+func printTo(w io.Writer, f func()) error {
+
+	ew := &errWriter{w: w}
+
+	out, pending, dest := reportOut, layoutPending, layoutDest
+	reportOut, layoutPending, layoutDest = ew, nil, nil
+
+	f()
+
+	reportOut, layoutPending, layoutDest = out, pending, dest
+
+	return ew.err
+}
+
+// captureOutput returns everything f prints to the report
+func captureOutput(f func()) []byte {
+
+	var b bytes.Buffer
+	printTo(&b, f)
+
+	return b.Bytes()
+}
+
+// cmdBundle handles the "bundle" subcommand
+func cmdBundle(args []string) {
+
+	if len(args) != 1 {
+		log.Fatal("Usage: arc_summary bundle <file.tar.gz>")
+	}
+
+	if bundleFiles != nil {
+		log.Fatal("Can't create a bundle from a bundle")
+	}
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	files := make(map[string][]byte)
+
+	// Pools have their own subdirectories of kstats, so we walk the whole
+	// tree. Files in /proc report a size of zero, so they have to be read
+	// before we know how large they are
+	filepath.Walk(procPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		if data, err := readFile(ctx, path); err == nil {
+			files[bundleKey(path)] = data
+		}
+		return nil
+	})
+
+	// Systems without /proc get their kstats some other way, but they go
+	// into the bundle in the Linux format all the same
+	for _, sp := range sectionPaths {
+		key := bundleKey(procPath + sp)
+		if _, ok := files[key]; ok {
+			continue
+		}
+		if data, err := readKstat(ctx, sp); err == nil {
+			files[key] = data
+		}
+	}
+
+	names, _ := readDirNames(ctx, tunablesPath)
+	for _, n := range names {
+		if data, err := readFile(ctx, filepath.Join(tunablesPath, n)); err == nil {
+			files[bundleKey(filepath.Join(tunablesPath, n))] = data
+		}
+	}
+
+	extra := append([]string{}, bundleExtraFiles...)
+	extra = append(extra, cgroupFiles(ctx)...)
+	extra = append(extra, numaFiles(ctx)...)
+	extra = append(extra, virtFiles()...)
+	extra = append(extra, vmSysctlFiles()...)
+	if confs, err := filepath.Glob(filepath.Join(modprobePath, "*.conf")); err == nil {
+		extra = append(extra, confs...)
+	}
+
+	for _, path := range extra {
+		if data, err := readFile(ctx, path); err == nil {
+			files[bundleKey(path)] = data
+		}
+	}
+
+	for _, c := range bundleCommands {
+		if out, err := runCommand(ctx, c[0], c[1:]...); err == nil {
+			files[commandKey(c[0], c[1:]...)] = out
+		}
+	}
+
+	// Without modinfo, what we read from the module is stored in its place
+	if out, err := runModinfo(ctx); err == nil {
+		files[commandKey("modinfo", "zfs", "-0")] = []byte(out)
+	}
+
+	getKstats(ctx, kstats)
+	files["report.txt"] = captureOutput(printReport)
+
+	if *OptRedact {
+		redacted := make(map[string][]byte)
+		for k, v := range files {
+			redacted[redactPath(k)] = []byte(redactText(string(v)))
+		}
+		files = redacted
+	}
+
+	if err := writeBundle(args[0], files); err != nil {
+		log.Fatal("Couldn't write bundle: ", err)
+	}
+
+	fmt.Fprintf(reportOut, "Wrote %d files to %s\n", len(files), args[0])
+}
+
+// writeBundle writes the files to a gzipped tar archive
+func writeBundle(path string, files map[string][]byte) error {
+
+	var buf bytes.Buffer
+
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	var names []string
+	for n := range files {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	now := time.Now()
+
+	for _, n := range names {
+		hdr := &tar.Header{
+			Name:    n,
+			Mode:    0644,
+			Size:    int64(len(files[n])),
+			ModTime: now,
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(files[n]); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
@@ -0,0 +1,92 @@
+// Test file for bundle.go
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "arc_summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "bundle.tar.gz")
+
+	files := map[string][]byte{
+		bundleKey("/sys/module/zfs/parameters/zfs_arc_max"): []byte("0\n"),
+		bundleKey("/sys/module/zfs/parameters/zfs_arc_min"): []byte("0\n"),
+		bundleKey("/proc/spl/kstat/zfs/tank/io"):            []byte("io\n"),
+		commandKey("/sbin/modinfo", "zfs", "-0"):            []byte("modinfo\n"),
+	}
+
+	if err := writeBundle(path, files); err != nil {
+		t.Fatal(err)
+	}
+
+	loadBundle(path)
+	defer func() { bundleFiles = nil }()
+
+	ctx := context.Background()
+
+	data, err := readFile(ctx, "/sys/module/zfs/parameters/zfs_arc_max")
+	if err != nil || string(data) != "0\n" {
+		t.Errorf("readFile(zfs_arc_max) = %q, %v (wanted \"0\\n\")", data, err)
+	}
+
+	if _, err := readFile(ctx, "/proc/meminfo"); !os.IsNotExist(err) {
+		t.Errorf("readFile(/proc/meminfo) = %v (wanted not exist)", err)
+	}
+
+	names, err := readDirNames(ctx, "/sys/module/zfs/parameters")
+	wanted := []string{"zfs_arc_max", "zfs_arc_min"}
+	if err != nil || !reflect.DeepEqual(names, wanted) {
+		t.Errorf("readDirNames(parameters) = %v, %v (wanted \"%v\")", names, err, wanted)
+	}
+
+	names, err = readDirNames(ctx, "/proc/spl/kstat/zfs")
+	if err == nil {
+		t.Errorf("readDirNames(/proc/spl/kstat/zfs) = %v (wanted error)", names)
+	}
+
+	out, err := runCommand(ctx, "/sbin/modinfo", "zfs", "-0")
+	if err != nil || string(out) != "modinfo\n" {
+		t.Errorf("runCommand(modinfo) = %q, %v (wanted \"modinfo\\n\")", out, err)
+	}
+
+	if _, err := runCommand(ctx, "zpool", "status", "-P"); err == nil {
+		t.Errorf("runCommand(zpool) succeeded (wanted error)")
+	}
+}
+
+func TestCollectTimeout(t *testing.T) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	r := withContext(ctx, "/hung", func() ioResult {
+		time.Sleep(time.Second)
+		return ioResult{}
+	})
+	if r.err == nil {
+		t.Errorf("withContext(hung read) succeeded (wanted timeout)")
+	}
+
+	if _, err := runCommand(ctx, "sleep", "1"); err == nil {
+		t.Errorf("runCommand(sleep 1) succeeded (wanted timeout)")
+	}
+
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("timeouts took %v (wanted about 50ms)", d)
+	}
+}
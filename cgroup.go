@@ -0,0 +1,143 @@
+// cgroup memory limits for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// In a container, or when the services using ZFS run under a memory limit,
+// the memory really available to the workload is less than the RAM of the
+// machine. We find the memory cgroup of this process in /proc/self/cgroup
+// and take the smallest limit on the way up to the root. The limit is only
+// shown: the ARC lives in the kernel and isn't charged to any cgroup, and
+// the cgroup of arc_summary needn't be that of the workload, so comparing
+// the two would say nothing. Both cgroup v2 and the memory controller of v1 are
+// supported. See arc_summary.go for the license
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	cgroupSelfPath = "/proc/self/cgroup"
+	cgroupRoot     = "/sys/fs/cgroup"
+
+	// cgroup v1 reports "no limit" as a huge number rounded to the page
+	// size, so anything above this counts as unlimited
+	cgroupUnlimited = 1 << 62
+)
+
+// parseCgroupPath returns the memory cgroup of a process from the contents
+// of /proc/<pid>/cgroup and if it is cgroup v2. Lines look like
+// "0::/system.slice/foo.service" for v2 and "4:memory:/docker/abc" for v1.
+// On hybrid systems the v1 memory controller wins, since that is where the
+// limits are
+func parseCgroupPath(data []byte) (string, bool, bool) {
+
+	var v2Path string
+	var v2Found bool
+
+	input := bufio.NewScanner(bytes.NewReader(data))
+
+	for input.Scan() {
+		fields := strings.SplitN(input.Text(), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+
+		if fields[0] == "0" && fields[1] == "" {
+			v2Path, v2Found = fields[2], true
+			continue
+		}
+
+		for _, c := range strings.Split(fields[1], ",") {
+			if c == "memory" {
+				return fields[2], false, true
+			}
+		}
+	}
+
+	return v2Path, true, v2Found
+}
+
+// cgroupLimitFiles returns the files with the memory limits of a cgroup and
+// all of its parents, starting with the cgroup itself
+func cgroupLimitFiles(cgroup string, v2 bool) []string {
+
+	dir, file := cgroupRoot, "memory.max"
+	if !v2 {
+		dir, file = cgroupRoot+"/memory", "memory.limit_in_bytes"
+	}
+
+	var files []string
+
+	for p := path.Clean("/" + cgroup); ; p = path.Dir(p) {
+		files = append(files, path.Join(dir, p, file))
+		if p == "/" {
+			break
+		}
+	}
+
+	return files
+}
+
+// parseCgroupLimit returns the limit in a memory.max or memory.limit_in_bytes
+// file, false if there is none
+func parseCgroupLimit(data []byte) (uint64, bool) {
+
+	s := strings.TrimSpace(string(data))
+	if s == "max" {
+		return 0, false
+	}
+
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || v >= cgroupUnlimited {
+		return 0, false
+	}
+
+	return v, true
+}
+
+// cgroupFiles returns the files we read to find the cgroup memory limit, for
+// the bundle
+func cgroupFiles(ctx context.Context) []string {
+
+	data, err := readFile(ctx, cgroupSelfPath)
+	if err != nil {
+		return nil
+	}
+
+	cgroup, v2, ok := parseCgroupPath(data)
+	if !ok {
+		return nil
+	}
+
+	return append([]string{cgroupSelfPath}, cgroupLimitFiles(cgroup, v2)...)
+}
+
+// cgroupMemoryLimit returns the smallest memory limit of the cgroup of this
+// process and its parents, false if there is none or we are not on Linux
+func cgroupMemoryLimit(ctx context.Context) (uint64, bool) {
+
+	files := cgroupFiles(ctx)
+	if files == nil {
+		return 0, false
+	}
+
+	var limit uint64
+	var found bool
+
+	for _, f := range files[1:] {
+		data, err := readFile(ctx, f)
+		if err != nil {
+			continue
+		}
+		if v, ok := parseCgroupLimit(data); ok && (!found || v < limit) {
+			limit, found = v, true
+		}
+	}
+
+	return limit, found
+}
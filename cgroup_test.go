@@ -0,0 +1,70 @@
+// Test file for cgroup.go
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCgroupPath(t *testing.T) {
+
+	var tests = []struct {
+		input string
+		path  string
+		v2    bool
+		ok    bool
+	}{
+		{"0::/system.slice/docker.service\n", "/system.slice/docker.service", true, true},
+		{"12:cpu,cpuacct:/docker/abc\n4:memory:/docker/abc\n0::/\n", "/docker/abc", false, true},
+		{"1:name=systemd:/user.slice\n", "", true, false},
+		{"", "", true, false},
+	}
+
+	for _, test := range tests {
+		path, v2, ok := parseCgroupPath([]byte(test.input))
+		if path != test.path || v2 != test.v2 || ok != test.ok {
+			t.Errorf("parseCgroupPath(%q) = %v, %v, %v (wanted \"%v, %v, %v\")",
+				test.input, path, v2, ok, test.path, test.v2, test.ok)
+		}
+	}
+}
+
+func TestCgroupLimitFiles(t *testing.T) {
+
+	got := cgroupLimitFiles("/system.slice/foo.service", true)
+	wanted := []string{
+		"/sys/fs/cgroup/system.slice/foo.service/memory.max",
+		"/sys/fs/cgroup/system.slice/memory.max",
+		"/sys/fs/cgroup/memory.max",
+	}
+	if !reflect.DeepEqual(got, wanted) {
+		t.Errorf("cgroupLimitFiles(v2) = %v (wanted \"%v\")", got, wanted)
+	}
+
+	got = cgroupLimitFiles("/", false)
+	wanted = []string{"/sys/fs/cgroup/memory/memory.limit_in_bytes"}
+	if !reflect.DeepEqual(got, wanted) {
+		t.Errorf("cgroupLimitFiles(v1) = %v (wanted \"%v\")", got, wanted)
+	}
+}
+
+func TestParseCgroupLimit(t *testing.T) {
+
+	var tests = []struct {
+		input string
+		limit uint64
+		ok    bool
+	}{
+		{"4294967296\n", 4294967296, true},
+		{"max\n", 0, false},
+		{"9223372036854771712\n", 0, false},
+		{"", 0, false},
+	}
+
+	for _, test := range tests {
+		limit, ok := parseCgroupLimit([]byte(test.input))
+		if limit != test.limit || ok != test.ok {
+			t.Errorf("parseCgroupLimit(%q) = %v, %v (wanted \"%v, %v\")", test.input, limit, ok, test.limit, test.ok)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// SVG chart export for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// Renders the ARC size breakdown and the hit ratio history as an SVG image;
+// there is no PNG output. In watch mode, the history is that of the session.
+// A single run takes it from the -history file, if there is one. See
+// arc_summary.go for the license
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	chartWidth     = 600
+	chartBarHeight = 40
+	chartPlotTop   = 140
+	chartPlotHigh  = 160
+	chartMargin    = 20
+)
+
+// chartColors follows the order of the segments in the size bar
+var chartColors = []string{"#3465a4", "#73d216", "#f57900", "#eeeeec"}
+
+// intervalHitRatio returns the hit ratio in percent of an interval with the
+// given hits and misses. An idle interval counts as zero
+func intervalHitRatio(hits, misses float64) float64 {
+
+	if hits+misses <= 0 {
+		return 0
+	}
+
+	return 100 * hits / (hits + misses)
+}
+
+// historyHitRatios returns the hit ratio in percent between each pair of
+// consecutive history records, at most the last chartLength of them. Pairs
+// where the counters are missing or went backwards because the module was
+// reloaded are left out
+func historyHitRatios(records []historyRecord) []float64 {
+
+	var ratios []float64
+
+	for i := 1; i < len(records); i++ {
+		prev, cur := records[i-1].Stats, records[i].Stats
+
+		var v [4]float64
+		ok := true
+		for j, s := range []string{prev["arcstats.hits"], prev["arcstats.misses"],
+			cur["arcstats.hits"], cur["arcstats.misses"]} {
+			n, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				ok = false
+			}
+			v[j] = n
+		}
+
+		if !ok || v[2] < v[0] || v[3] < v[1] {
+			continue
+		}
+
+		ratios = append(ratios, intervalHitRatio(v[2]-v[0], v[3]-v[1]))
+	}
+
+	if len(ratios) > chartLength {
+		ratios = ratios[len(ratios)-chartLength:]
+	}
+
+	return ratios
+}
+
+// writeChart writes an SVG chart to path. hitRatios is the hit ratio in
+// percent for each interval of a watch session and may be empty, in which
+// case only the size breakdown is drawn
+func writeChart(path string, hitRatios []float64) error {
+
+	if strings.ToLower(filepath.Ext(path)) != ".svg" {
+		return fmt.Errorf("only SVG charts are supported, use a .svg file name")
+	}
+
+	var arcStats = make(map[string]string)
+	procSection("arcstats", arcStats)
+
+	arcMax := stringToUint64(arcStats["c_max"])
+	mfu := stringToUint64(arcStats["mfu_size"])
+	mru := stringToUint64(arcStats["mru_size"])
+	size := stringToUint64(arcStats["size"])
+
+	var other uint64
+	if size > mfu+mru {
+		other = size - (mfu + mru)
+	}
+
+	var free uint64
+	if arcMax > size {
+		free = arcMax - size
+	}
+
+	height := chartPlotTop
+	if len(hitRatios) > 1 {
+		height += chartPlotHigh + chartMargin
+	}
+
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" font-family=\"sans-serif\" font-size=\"12\">\n",
+		chartWidth, height)
+	fmt.Fprintf(&b, "<text x=\"%d\" y=\"%d\" font-size=\"14\">ARC: %s of %s (%s)</text>\n",
+		chartMargin, chartMargin, fBytes(arcStats["size"]), fBytes(arcStats["c_max"]),
+		strings.TrimSpace(fPerc(arcStats["size"], arcStats["c_max"])))
+
+	// Stacked bar of MFU, MRU, other and unused space, scaled to c_max
+	segments := []struct {
+		label string
+		bytes uint64
+	}{
+		{"MFU", mfu}, {"MRU", mru}, {"Other", other}, {"Free", free},
+	}
+
+	barWidth := float64(chartWidth - 2*chartMargin)
+	x := float64(chartMargin)
+
+	for i, s := range segments {
+		w := 0.0
+		if arcMax > 0 {
+			w = barWidth * float64(s.bytes) / float64(arcMax)
+		}
+
+		fmt.Fprintf(&b, "<rect x=\"%.1f\" y=\"%d\" width=\"%.1f\" height=\"%d\" fill=\"%s\"/>\n",
+			x, 2*chartMargin, w, chartBarHeight, chartColors[i])
+		x += w
+
+		legendX := chartMargin + i*(chartWidth-2*chartMargin)/len(segments)
+		fmt.Fprintf(&b, "<rect x=\"%d\" y=\"%d\" width=\"10\" height=\"10\" fill=\"%s\" stroke=\"#888\"/>\n",
+			legendX, 2*chartMargin+chartBarHeight+chartMargin, chartColors[i])
+		fmt.Fprintf(&b, "<text x=\"%d\" y=\"%d\">%s %s</text>\n",
+			legendX+14, 2*chartMargin+chartBarHeight+chartMargin+10, s.label,
+			fBytes(fmt.Sprint(s.bytes)))
+	}
+
+	if len(hitRatios) > 1 {
+		writeChartHistory(&b, hitRatios)
+	}
+
+	b.WriteString("</svg>\n")
+
+	return ioutil.WriteFile(path, b.Bytes(), 0644)
+}
+
+// writeChartHistory draws the hit ratio history as a line plot from 0 to
+// 100 percent below the size bar
+func writeChartHistory(b *bytes.Buffer, hitRatios []float64) {
+
+	top := chartPlotTop
+	plotWidth := float64(chartWidth - 2*chartMargin)
+	step := plotWidth / float64(len(hitRatios)-1)
+
+	fmt.Fprintf(b, "<text x=\"%d\" y=\"%d\" font-size=\"14\">Hit ratio per interval</text>\n",
+		chartMargin, top)
+	fmt.Fprintf(b, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"none\" stroke=\"#888\"/>\n",
+		chartMargin, top+chartMargin/2, chartWidth-2*chartMargin, chartPlotHigh)
+
+	var points []string
+
+	for i, r := range hitRatios {
+		px := float64(chartMargin) + float64(i)*step
+		py := float64(top+chartMargin/2) + float64(chartPlotHigh)*(100-r)/100
+		points = append(points, fmt.Sprintf("%.1f,%.1f", px, py))
+	}
+
+	fmt.Fprintf(b, "<polyline points=\"%s\" fill=\"none\" stroke=\"%s\" stroke-width=\"2\"/>\n",
+		strings.Join(points, " "), chartColors[0])
+}
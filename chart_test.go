@@ -0,0 +1,128 @@
+// Test file for chart.go
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIntervalHitRatio(t *testing.T) {
+
+	var tests = []struct {
+		hits, misses float64
+		wanted       float64
+	}{
+		{90, 10, 90},
+		{0, 50, 0},
+		{50, 0, 100},
+		{0, 0, 0},
+	}
+
+	for _, test := range tests {
+		if got := intervalHitRatio(test.hits, test.misses); got != test.wanted {
+			t.Errorf("intervalHitRatio(%v, %v) = %v (wanted \"%v\")", test.hits, test.misses, got, test.wanted)
+		}
+	}
+}
+
+func TestWriteChartHistory(t *testing.T) {
+
+	var tests = []struct {
+		ratios []float64
+		wanted string
+	}{
+		{[]float64{100, 0}, `points="20.0,150.0 580.0,310.0"`},
+		{[]float64{50, 50, 50}, `points="20.0,230.0 300.0,230.0 580.0,230.0"`},
+	}
+
+	for _, test := range tests {
+		var b bytes.Buffer
+		writeChartHistory(&b, test.ratios)
+		if !strings.Contains(b.String(), test.wanted) {
+			t.Errorf("writeChartHistory(%v) = %q (wanted %q)", test.ratios, b.String(), test.wanted)
+		}
+	}
+}
+
+func TestWriteChart(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "arc_summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	saved := kstats
+	kstats = map[string][]string{"arcstats": {"c_max 4 1000", "mfu_size 4 200", "mru_size 4 300", "size 4 600"}}
+	defer func() { kstats = saved }()
+
+	var tests = []struct {
+		name   string
+		ratios []float64
+		fails  bool
+		wanted []string
+	}{
+		{"chart.png", nil, true, nil},
+		{"chart.svg", nil, false, []string{
+			`height="140"`,
+			`<rect x="20.0" y="40" width="112.0" height="40" fill="#3465a4"/>`,
+			`<rect x="132.0" y="40" width="168.0" height="40" fill="#73d216"/>`,
+			`<rect x="300.0" y="40" width="56.0" height="40" fill="#f57900"/>`,
+			`<rect x="356.0" y="40" width="224.0" height="40" fill="#eeeeec"/>`,
+		}},
+		{"history.SVG", []float64{90, 95}, false, []string{`height="320"`, "Hit ratio per interval"}},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(dir, test.name)
+
+		err := writeChart(path, test.ratios)
+		if (err != nil) != test.fails {
+			t.Errorf("writeChart(%s) = %v (wanted failure %v)", test.name, err, test.fails)
+			continue
+		}
+		if test.fails {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, w := range test.wanted {
+			if !strings.Contains(string(data), w) {
+				t.Errorf("writeChart(%s) = %q (wanted %q)", test.name, data, w)
+			}
+		}
+	}
+}
+
+func TestHistoryHitRatios(t *testing.T) {
+
+	rec := func(hits, misses string) historyRecord {
+		return historyRecord{Stats: map[string]string{"arcstats.hits": hits, "arcstats.misses": misses}}
+	}
+
+	var tests = []struct {
+		records []historyRecord
+		wanted  []float64
+	}{
+		{nil, nil},
+		{[]historyRecord{rec("100", "0")}, nil},
+		{[]historyRecord{rec("100", "0"), rec("190", "10"), rec("190", "10")}, []float64{90, 0}},
+		{[]historyRecord{rec("100", "0"), rec("50", "0"), rec("80", "10")}, []float64{75}},
+		{[]historyRecord{rec("100", "0"), {}, rec("180", "20")}, nil},
+	}
+
+	for _, test := range tests {
+		if got := historyHitRatios(test.records); !reflect.DeepEqual(got, test.wanted) {
+			t.Errorf("historyHitRatios(%v) = %v (wanted \"%v\")", test.records, got, test.wanted)
+		}
+	}
+}
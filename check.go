@@ -0,0 +1,111 @@
+// Check results for monitoring systems for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// Monitoring systems such as Sensu, PRTG and Checkmk run arc_summary as a
+// check and want a state, a short summary and a few metrics instead of the
+// whole report. The output formats for them share the code here. See
+// arc_summary.go for the license
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// The check states, as used by Nagios and everybody who copied it
+const (
+	checkOK       = 0
+	checkWarning  = 1
+	checkCritical = 2
+	checkUnknown  = 3
+)
+
+// checkStateNames are the names of the check states
+var checkStateNames = []string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}
+
+// checkMetric is one value reported to the monitoring system. Service is the
+// part of ZFS it belongs to (arc, l2arc or zil)
+type checkMetric struct {
+	service string
+	name    string
+	value   float64
+	unit    string
+}
+
+// hitRatio returns the hits as a percentage of all accesses, or zero if there
+// weren't any
+func hitRatio(hits, misses uint64) float64 {
+
+	if hits+misses == 0 {
+		return 0
+	}
+
+	return 100 * float64(hits) / float64(hits+misses)
+}
+
+// arcDelta returns how much an arcstats counter grew since the last run
+// saved with -state. Counts since boot never go down, so a check based on
+// them would stay in WARNING until the next reboot
+func arcDelta(s *StatsSet, stat string) (uint64, bool) {
+
+	n, ok := s.Kstats["arcstats"][stat]
+	if !ok {
+		return 0, false
+	}
+
+	cur := map[string]string{stat: strconv.FormatUint(n, 10)}
+
+	return reclaimDelta(previousRun, cur, stat)
+}
+
+// throttleDelta returns how often the ARC was throttled since the last run
+func throttleDelta(s *StatsSet) (uint64, bool) {
+	return arcDelta(s, "memory_throttle_count")
+}
+
+// checkMetrics returns the metrics for checks. The ZIL metrics are left out
+// if the zil kstat couldn't be read, the throttles since the last run
+// without -state
+func checkMetrics(s *StatsSet) []checkMetric {
+
+	arc := s.Kstats["arcstats"]
+
+	metrics := []checkMetric{
+		{"arc", "size", float64(arc["size"]), "bytes"},
+		{"arc", "c_max", float64(arc["c_max"]), "bytes"},
+		{"arc", "hit_ratio", hitRatio(arc["hits"], arc["misses"]), "percent"},
+		{"arc", "memory_throttle_count", float64(arc["memory_throttle_count"]), "count"},
+		{"l2arc", "size", float64(arc["l2_size"]), "bytes"},
+		{"l2arc", "hit_ratio", hitRatio(arc["l2_hits"], arc["l2_misses"]), "percent"},
+	}
+
+	if d, ok := throttleDelta(s); ok {
+		metrics = append(metrics, checkMetric{"arc", "memory_throttle_delta", float64(d), "count"})
+	}
+
+	if zil, ok := s.Kstats["zil"]; ok {
+		metrics = append(metrics,
+			checkMetric{"zil", "commit_count", float64(zil["zil_commit_count"]), "count"},
+			checkMetric{"zil", "itx_count", float64(zil["zil_itx_count"]), "count"})
+	}
+
+	return metrics
+}
+
+// checkState returns the state of the ARC and a one-line summary. The ARC is
+// in WARNING if it was throttled since the last run, which needs -state
+func checkState(s *StatsSet) (int, string) {
+
+	arc, ok := s.Kstats["arcstats"]
+	if !ok {
+		return checkUnknown, "couldn't read arcstats"
+	}
+
+	if d, ok := throttleDelta(s); ok && d > 0 {
+		return checkWarning, fmt.Sprintf("ARC throttled %d times since the last run", d)
+	}
+
+	return checkOK, fmt.Sprintf("ARC %s of %s, hit ratio %0.1f %%",
+		fBytes(strconv.FormatUint(arc["size"], 10)), fBytes(strconv.FormatUint(arc["c_max"], 10)),
+		hitRatio(arc["hits"], arc["misses"]))
+}
@@ -0,0 +1,49 @@
+// Test file for check.go
+package main
+
+import "testing"
+
+func TestCheckState(t *testing.T) {
+
+	defer func() { previousRun = nil }()
+
+	last := &historyRecord{Stats: map[string]string{"arcstats.memory_throttle_count": "3"}}
+
+	var tests = []struct {
+		kstats map[string]map[string]uint64
+		prev   *historyRecord
+		want   int
+	}{
+		{map[string]map[string]uint64{}, nil, checkUnknown},
+		{map[string]map[string]uint64{"arcstats": {"hits": 9, "misses": 1}}, nil, checkOK},
+		{map[string]map[string]uint64{"arcstats": {"memory_throttle_count": 3}}, nil, checkOK},
+		{map[string]map[string]uint64{"arcstats": {"memory_throttle_count": 3}}, last, checkOK},
+		{map[string]map[string]uint64{"arcstats": {"memory_throttle_count": 5}}, last, checkWarning},
+	}
+
+	for _, test := range tests {
+		previousRun = test.prev
+		got, summary := checkState(&StatsSet{Kstats: test.kstats})
+		if got != test.want {
+			t.Errorf("checkState(%v, %v) = %v, %s (wanted \"%v\")", test.kstats, test.prev, got, summary, test.want)
+		}
+	}
+}
+
+func TestHitRatio(t *testing.T) {
+
+	var tests = []struct {
+		hits, misses uint64
+		want         float64
+	}{
+		{0, 0, 0},
+		{3, 1, 75},
+		{5, 0, 100},
+	}
+
+	for _, test := range tests {
+		if got := hitRatio(test.hits, test.misses); got != test.want {
+			t.Errorf("hitRatio(%d, %d) = %v (wanted \"%v\")", test.hits, test.misses, got, test.want)
+		}
+	}
+}
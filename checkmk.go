@@ -0,0 +1,130 @@
+// Checkmk local check output for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// "-o checkmk" prints one local check line each for the ARC, the L2ARC and
+// the ZIL, so arc_summary can be dropped into the local/ directory of the
+// Checkmk agent and the services are discovered automatically. The ARC
+// service warns about throttling and the L2ARC service about errors since
+// the last run, for which the agent has to call us with -state. See
+// arc_summary.go for the license
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// checkmkServices are the services we report, with the name Checkmk shows
+var checkmkServices = []struct {
+	service, name string
+}{
+	{"arc", "ZFS ARC"},
+	{"l2arc", "ZFS L2ARC"},
+	{"zil", "ZFS ZIL"},
+}
+
+// checkmkLevels are the warning levels of metrics, which Checkmk shows in
+// the graphs
+var checkmkLevels = map[string]string{
+	"memory_throttle_delta": "1",
+}
+
+// checkmkPerfdata returns the metrics of a service in Checkmk perfdata format,
+// eg "size=7500000000|hit_ratio=95.24", or "-" if there are none
+func checkmkPerfdata(service string, metrics []checkMetric) string {
+
+	var parts []string
+
+	for _, m := range metrics {
+		if m.service != service {
+			continue
+		}
+
+		prec := -1
+		if m.unit == "percent" {
+			prec = 2
+		}
+		part := m.name + "=" + strconv.FormatFloat(m.value, 'f', prec, 64)
+		if level, ok := checkmkLevels[m.name]; ok {
+			part += ";" + level
+		}
+		parts = append(parts, part)
+	}
+
+	if len(parts) == 0 {
+		return "-"
+	}
+
+	return strings.Join(parts, "|")
+}
+
+// l2ErrorDelta returns the checksum and I/O errors of the L2ARC since the
+// last run
+func l2ErrorDelta(s *StatsSet) (uint64, bool) {
+
+	var total uint64
+
+	for _, stat := range []string{"l2_cksum_bad", "l2_io_error"} {
+		d, ok := arcDelta(s, stat)
+		if !ok {
+			return 0, false
+		}
+		total += d
+	}
+
+	return total, true
+}
+
+// checkmkState returns the state and summary of a service
+func checkmkState(service string, s *StatsSet) (int, string) {
+
+	arc, ok := s.Kstats["arcstats"]
+
+	switch service {
+	case "arc":
+		return checkState(s)
+
+	case "l2arc":
+		if !ok {
+			return checkUnknown, "couldn't read arcstats"
+		}
+		if arc["l2_size"] == 0 {
+			return checkOK, "no L2ARC devices"
+		}
+		if errs, ok := l2ErrorDelta(s); ok && errs > 0 {
+			return checkWarning, fmt.Sprintf("%d checksum or I/O errors since the last run", errs)
+		}
+		return checkOK, fmt.Sprintf("L2ARC %s, hit ratio %0.1f %%",
+			fBytes(strconv.FormatUint(arc["l2_size"], 10)), hitRatio(arc["l2_hits"], arc["l2_misses"]))
+
+	case "zil":
+		zil, ok := s.Kstats["zil"]
+		if !ok {
+			return checkUnknown, "couldn't read zil kstats"
+		}
+		return checkOK, fmt.Sprintf("%d commits, %d intent log transactions",
+			zil["zil_commit_count"], zil["zil_itx_count"])
+	}
+
+	return checkUnknown, "unknown service " + service
+}
+
+// checkmkRenderer writes the local check lines
+type checkmkRenderer struct{}
+
+func (checkmkRenderer) Render(w io.Writer, s *StatsSet) error {
+
+	metrics := checkMetrics(s)
+
+	for _, svc := range checkmkServices {
+		state, summary := checkmkState(svc.service, s)
+		_, err := fmt.Fprintf(w, "%d \"%s\" %s %s\n", state, svc.name, checkmkPerfdata(svc.service, metrics), summary)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
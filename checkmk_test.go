@@ -0,0 +1,95 @@
+// Test file for checkmk.go
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCheckmkRender(t *testing.T) {
+
+	defer func() { previousRun = nil }()
+
+	s := &StatsSet{Kstats: map[string]map[string]uint64{
+		"arcstats": {"size": 100, "c_max": 200, "hits": 1, "misses": 1,
+			"l2_size": 50, "l2_hits": 1, "l2_misses": 3, "l2_cksum_bad": 0, "l2_io_error": 1},
+	}}
+	previousRun = &historyRecord{Stats: map[string]string{"arcstats.l2_cksum_bad": "0", "arcstats.l2_io_error": "0"}}
+
+	var b bytes.Buffer
+	if err := (checkmkRenderer{}).Render(&b, s); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `0 "ZFS ARC" size=100|c_max=200|hit_ratio=50.00|memory_throttle_count=0 ARC 100 Bytes of 200 Bytes, hit ratio 50.0 %
+1 "ZFS L2ARC" size=50|hit_ratio=25.00 1 checksum or I/O errors since the last run
+3 "ZFS ZIL" - couldn't read zil kstats
+`
+
+	if b.String() != want {
+		t.Errorf("checkmk output = %q (wanted %q)", b.String(), want)
+	}
+}
+
+func TestCheckmkThrottle(t *testing.T) {
+
+	defer func() { previousRun = nil }()
+
+	s := &StatsSet{Kstats: map[string]map[string]uint64{
+		"arcstats": {"size": 100, "c_max": 200, "hits": 1, "misses": 1, "memory_throttle_count": 7},
+	}}
+
+	var tests = []struct {
+		prev  string
+		state int
+		perf  string
+	}{
+		{"", checkOK, "size=100|c_max=200|hit_ratio=50.00|memory_throttle_count=7"},
+		{"7", checkOK, "size=100|c_max=200|hit_ratio=50.00|memory_throttle_count=7|memory_throttle_delta=0;1"},
+		{"5", checkWarning, "size=100|c_max=200|hit_ratio=50.00|memory_throttle_count=7|memory_throttle_delta=2;1"},
+	}
+
+	for _, test := range tests {
+		previousRun = nil
+		if test.prev != "" {
+			previousRun = &historyRecord{Stats: map[string]string{"arcstats.memory_throttle_count": test.prev}}
+		}
+
+		state, summary := checkmkState("arc", s)
+		perf := checkmkPerfdata("arc", checkMetrics(s))
+		if state != test.state || perf != test.perf {
+			t.Errorf("checkmk arc after %q = %d %s, %s (wanted \"%d %s\")", test.prev, state, perf, summary, test.state, test.perf)
+		}
+	}
+}
+
+func TestCheckmkL2Errors(t *testing.T) {
+
+	defer func() { previousRun = nil }()
+
+	s := &StatsSet{Kstats: map[string]map[string]uint64{
+		"arcstats": {"l2_size": 100, "l2_hits": 1, "l2_misses": 1, "l2_cksum_bad": 3, "l2_io_error": 1},
+	}}
+
+	var tests = []struct {
+		cksum, io string
+		state     int
+	}{
+		{"", "", checkOK},
+		{"3", "1", checkOK},
+		{"2", "1", checkWarning},
+		{"3", "0", checkWarning},
+	}
+
+	for _, test := range tests {
+		previousRun = nil
+		if test.cksum != "" {
+			previousRun = &historyRecord{Stats: map[string]string{
+				"arcstats.l2_cksum_bad": test.cksum, "arcstats.l2_io_error": test.io}}
+		}
+
+		if state, summary := checkmkState("l2arc", s); state != test.state {
+			t.Errorf("checkmk l2arc after %q/%q = %d %s (wanted \"%d\")", test.cksum, test.io, state, summary, test.state)
+		}
+	}
+}
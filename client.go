@@ -0,0 +1,127 @@
+// Client for a running arc_summary server
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// "arc_summary -connect /run/arc_summary.sock report" gets the report from
+// a running "arc_summary serve" instead of reading /proc, so it shows the
+// data the server already has. -connect takes the path of a unix socket or
+// the URL of the server; credentials can be part of the URL. See
+// arc_summary.go for the license
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// connectURL returns the base URL of the server and the client to reach it
+func connectURL(target string) (string, *http.Client) {
+
+	path := strings.TrimPrefix(target, unixPrefix)
+
+	if !strings.HasPrefix(path, "/") {
+		return strings.TrimSuffix(target, "/"), &http.Client{Timeout: *OptTimeout}
+	}
+
+	// The host name of the URL doesn't matter, every connection goes to
+	// the socket
+	tr := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}
+
+	return "http://arc_summary", &http.Client{Transport: tr, Timeout: *OptTimeout}
+}
+
+// reportPath returns the path and Accept header for the output format and
+// section asked for
+func reportPath(format, section string) (string, string) {
+
+	switch format {
+	case "json":
+		return "/api/stats", "application/json"
+	case "prometheus":
+		return "/metrics", "text/plain"
+	case "openmetrics":
+		return "/metrics", "application/openmetrics-text"
+	}
+
+	if section != "" {
+		return "/report?section=" + url.QueryEscape(section), "text/plain"
+	}
+
+	return "/report", "text/plain"
+}
+
+// fetchReport gets the report from the server and copies it to w
+func fetchReport(w io.Writer, target, format, section string) error {
+
+	base, client := connectURL(target)
+	path, accept := reportPath(format, section)
+
+	req, err := http.NewRequest("GET", base+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", accept)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("server said %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// cmdReport handles the "report" subcommand. Without -connect it prints
+// the report of the local system, as if no subcommand was given
+func cmdReport(args []string) {
+
+	if len(args) != 0 {
+		log.Fatal("Usage: arc_summary [-connect socket|url] [-o format] [-s section] report")
+	}
+
+	if *OptPrintSection != "" && !isLegalSection(*OptPrintSection) {
+		log.Fatal("Can't print unknown section '", *OptPrintSection, "'")
+	}
+
+	if *OptConnect != "" {
+		if err := fetchReport(os.Stdout, *OptConnect, *OptOutput, *OptPrintSection); err != nil {
+			log.Fatal("Couldn't get report from ", *OptConnect, ": ", err)
+		}
+		return
+	}
+
+	renderer, ok := renderers[*OptOutput]
+	if !ok {
+		log.Fatal("Unknown output format '", *OptOutput, "'")
+	}
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	set, err := kstatCollector{}.Collect(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := renderer.Render(os.Stdout, set); err != nil {
+		log.Fatal("Couldn't write report: ", err)
+	}
+}
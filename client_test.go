@@ -0,0 +1,54 @@
+// Test file for client.go
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReportPath(t *testing.T) {
+
+	var tests = []struct {
+		format, section string
+		path, accept    string
+	}{
+		{"text", "", "/report", "text/plain"},
+		{"text", "arc", "/report?section=arc", "text/plain"},
+		{"json", "arc", "/api/stats", "application/json"},
+		{"openmetrics", "", "/metrics", "application/openmetrics-text"},
+	}
+
+	for _, test := range tests {
+		path, accept := reportPath(test.format, test.section)
+		if path != test.path || accept != test.accept {
+			t.Errorf("reportPath(%s, %s) = %v, %v (wanted \"%v, %v\")", test.format, test.section,
+				path, accept, test.path, test.accept)
+		}
+	}
+}
+
+func TestFetchReport(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/report" {
+			http.Error(w, "not here", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("report of section " + req.URL.Query().Get("section")))
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	if err := fetchReport(&buf, ts.URL+"/", "text", "arc"); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "report of section arc" {
+		t.Errorf("fetchReport() = %v (wanted \"report of section arc\")", got)
+	}
+
+	if err := fetchReport(&buf, ts.URL, "json", ""); err == nil {
+		t.Errorf("fetchReport() of a missing path succeeded (wanted error)")
+	}
+}
@@ -0,0 +1,101 @@
+// Compressed output files for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// Files whose name ends in ".gz" are written and read with gzip. History
+// files are appended to with a new gzip member each time, which gzip and
+// zcat read as one stream. Snapshots are compressed with -compress gzip. The
+// standard library has no zstd, so that isn't offered. See arc_summary.go
+// for the license
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const gzipSuffix = ".gz"
+
+// compressSuffixes are the compression methods known to -compress
+var compressSuffixes = map[string]string{
+	"":     "",
+	"gzip": gzipSuffix,
+}
+
+// compressSuffix returns the suffix for the method given with -compress
+func compressSuffix(method string) (string, error) {
+
+	suffix, ok := compressSuffixes[method]
+	if !ok {
+		return "", fmt.Errorf("unknown compression '%s' (only gzip is supported)", method)
+	}
+
+	return suffix, nil
+}
+
+// gzipWriteCloser closes both the gzip stream and the file below it
+type gzipWriteCloser struct {
+	*gzip.Writer
+	f *os.File
+}
+
+func (g gzipWriteCloser) Close() error {
+
+	if err := g.Writer.Close(); err != nil {
+		g.f.Close()
+		return err
+	}
+
+	return g.f.Close()
+}
+
+// openOutput opens a file for writing with the given flags, compressing
+// everything written if the name ends in ".gz"
+func openOutput(path string, flag int) (io.WriteCloser, error) {
+
+	f, err := os.OpenFile(path, flag, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasSuffix(path, gzipSuffix) {
+		return f, nil
+	}
+
+	return gzipWriteCloser{gzip.NewWriter(f), f}, nil
+}
+
+// gzipReadCloser closes both the gzip stream and the file below it
+type gzipReadCloser struct {
+	*gzip.Reader
+	f *os.File
+}
+
+func (g gzipReadCloser) Close() error {
+	g.Reader.Close()
+	return g.f.Close()
+}
+
+// openInput opens a file for reading, decompressing it if the name ends in
+// ".gz"
+func openInput(path string) (io.ReadCloser, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasSuffix(path, gzipSuffix) {
+		return f, nil
+	}
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return gzipReadCloser{gz, f}, nil
+}
@@ -0,0 +1,65 @@
+// Test file for compress.go
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "arc_summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"history.ndjson", "history.ndjson.gz"} {
+		path := filepath.Join(dir, name)
+
+		// Two appends give two gzip members, which must read as one stream
+		for _, line := range []string{"one\n", "two\n"} {
+			f, err := openOutput(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.Write([]byte(line))
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		f, err := openInput(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(f)
+		f.Close()
+
+		if err != nil || string(data) != "one\ntwo\n" {
+			t.Errorf("openInput(%s) = %q, %v (wanted \"one\\ntwo\\n\")", name, data, err)
+		}
+	}
+}
+
+func TestCompressSuffix(t *testing.T) {
+
+	var tests = []struct {
+		method string
+		suffix string
+		fails  bool
+	}{
+		{"", "", false},
+		{"gzip", ".gz", false},
+		{"zstd", "", true},
+	}
+
+	for _, test := range tests {
+		suffix, err := compressSuffix(test.method)
+		if suffix != test.suffix || (err != nil) != test.fails {
+			t.Errorf("compressSuffix(%s) = %v, %v (wanted \"%v\")", test.method, suffix, err, test.suffix)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Configuration file handling for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// The configuration file is in JSON format. See arc_summary.go for the
+// license
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+)
+
+const defaultConfigPath = "/etc/arc_summary.json"
+
+// config holds everything read from the configuration file
+type config struct {
+	Derived    []derivedMetric `json:"derived"`
+	Jobs       []jobConfig     `json:"jobs"`
+	Layout     layoutConfig    `json:"layout"`
+	Rules      []issueRule     `json:"rules"`
+	Thresholds []threshold     `json:"thresholds"`
+}
+
+// derivedMetric is a user-defined statistic computed from an expression over
+// the collected stats (see expr.go). Unit is one of "bytes", "hits",
+// "percent" or a free string that is appended to the value; Section is the
+// name of the report section the metric is printed in
+type derivedMetric struct {
+	Name    string `json:"name"`
+	Expr    string `json:"expr"`
+	Unit    string `json:"unit"`
+	Section string `json:"section"`
+}
+
+var cfg config
+
+// configExplicit is set if the configuration file was given with -c
+var configExplicit bool
+
+// explicitFlags are the flags given on the command line. These win over the
+// configuration file
+var explicitFlags = make(map[string]bool)
+
+// loadConfig reads the configuration file at path into c. A missing file is
+// only an error if the user explicitly asked for it with -c
+func loadConfig(path string, explicit bool, c *config) {
+	if err := readConfig(path, explicit, c); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// readConfig is loadConfig without quitting on errors, so a running watch
+// mode can keep its old configuration if a reload fails
+func readConfig(path string, explicit bool, c *config) error {
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && !explicit {
+			return nil
+		}
+		return fmt.Errorf("couldn't read config file %s: %v", path, err)
+	}
+
+	if err := json.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("couldn't parse config file %s: %v", path, err)
+	}
+
+	if err := c.Layout.check(); err != nil {
+		return fmt.Errorf("bad layout in config file %s: %v", path, err)
+	}
+
+	// Check the derived metrics now so the user doesn't find out about a
+	// typo in the middle of the report. We don't have real values yet, so
+	// every stat is replaced by a dummy
+	dummy := func(string) (float64, error) { return 1, nil }
+
+	for _, d := range c.Derived {
+		if d.Name == "" {
+			return fmt.Errorf("derived metric without name in %s", path)
+		}
+		if !isLegalSection(d.Section) {
+			return fmt.Errorf("derived metric '%s' has unknown section '%s'", d.Name, d.Section)
+		}
+		if _, err := evalExpr(d.Expr, dummy); err != nil {
+			return fmt.Errorf("derived metric '%s' has bad expression: %v", d.Name, err)
+		}
+	}
+
+	for _, r := range c.Rules {
+		if r.Name == "" || r.Problem == "" {
+			return fmt.Errorf("rule without name or problem in %s", path)
+		}
+		if _, err := evalExpr(r.Expr, dummy); err != nil {
+			return fmt.Errorf("rule '%s' has bad expression: %v", r.Name, err)
+		}
+	}
+
+	for _, t := range c.Thresholds {
+		if err := t.check(); err != nil {
+			return fmt.Errorf("%v in %s", err, path)
+		}
+	}
+
+	for _, j := range c.Jobs {
+		if err := j.check(); err != nil {
+			return fmt.Errorf("%v in %s", err, path)
+		}
+	}
+
+	return nil
+}
+
+// fDerived formats the value of a derived metric according to its unit
+func fDerived(value float64, unit string) string {
+	return formatterFor(unit).Format(value)
+}
+
+// clampZero clamps negative values to zero so they can be formatted as unsigned
+// byte or hit counts
+func clampZero(f float64) float64 {
+	if f < 0 {
+		return 0
+	}
+	return f
+}
+
+// printDerived prints the derived metrics the user has placed in the given
+// section. Metrics that can't be evaluated (eg because of a division by zero
+// on an idle system) are shown as "n/a"
+func printDerived(section string) {
+
+	first := true
+
+	for _, d := range cfg.Derived {
+
+		if d.Section != section {
+			continue
+		}
+
+		if first {
+			fmt.Fprintln(reportOut, "\nDerived metrics:")
+			first = false
+		}
+
+		value := "n/a"
+		result, err := evalExpr(d.Expr, lookupStat)
+		if err == nil {
+			value = fDerived(result, d.Unit)
+		}
+
+		prtL2(d.Name+":", value)
+	}
+}
@@ -0,0 +1,75 @@
+// Test file for config.go
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFDerived(t *testing.T) {
+	var tests = []struct {
+		value float64
+		unit  string
+		want  string
+	}{
+		{2048, "bytes", "2.0 KiB"},
+		{-5, "bytes", "0 Bytes"},
+		{1500, "hits", "1.5k"},
+		{93.25, "percent", "93.2 %"},
+		{3, "", "3.00"},
+		{1.5, "ms", "1.50 ms"},
+	}
+
+	for _, test := range tests {
+		got := fDerived(test.value, test.unit)
+		if got != test.want {
+			t.Errorf("fDerived(%v, %s) = %v (wanted \"%v\")", test.value, test.unit, got, test.want)
+		}
+	}
+}
+
+func TestReadConfig(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "arc_summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var tests = []struct {
+		contents string
+		explicit bool
+		ok       bool
+	}{
+		{"", false, true}, // missing file
+		{"", true, false}, // missing file asked for with -c
+		{`{"derived": [{"name": "ratio", "expr": "hits/misses", "section": "arc"}]}`, true, true},
+		{`{"derived": [{"name": "ratio", "expr": "hits/", "section": "arc"}]}`, true, false},
+		{`{"derived": [{"name": "ratio", "expr": "hits", "section": "nosuch"}]}`, true, false},
+		{`{"derived": [`, true, false},
+		{`{"layout": {"indent": "2", "width": 100, "separator": "rule"}}`, true, true},
+		{`{"layout": {"separator": "stars"}}`, true, false},
+		{`{"rules": [{"name": "deletes", "expr": "deleted", "max": 100, "problem": "churn"}]}`, true, true},
+		{`{"rules": [{"name": "deletes", "expr": "deleted/", "problem": "churn"}]}`, true, false},
+		{`{"rules": [{"expr": "deleted"}]}`, true, false},
+	}
+
+	for i, test := range tests {
+		path := filepath.Join(dir, "config.json")
+		os.Remove(path)
+
+		if test.contents != "" {
+			if err := ioutil.WriteFile(path, []byte(test.contents), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var c config
+		err := readConfig(path, test.explicit, &c)
+		if (err == nil) != test.ok {
+			t.Errorf("readConfig(%d) = %v (wanted ok %v)", i, err, test.ok)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+// Scheduled jobs for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// "arc_summary serve" can run jobs on their own schedules, given in the
+// "jobs" list of the configuration file: write the report to a file every
+// hour, a textfile for node_exporter every minute, mail the report once a
+// day. Schedules are cron expressions with five fields (minute, hour, day of
+// month, month, day of week) or one of @hourly, @daily, @weekly, @monthly
+// and "@every <duration>" for intervals below a minute. Jobs work on the
+// stats of the most recent collection, so they can't run more often than
+// -interval gives new data. See arc_summary.go for the license
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// jobConfig is a job in the configuration file. Path is the file or
+// directory to write to, Command the shell command for "exec", which gets
+// the report on stdin. Section limits the report to one section
+type jobConfig struct {
+	Name     string `json:"name"`
+	Schedule string `json:"schedule"`
+	Action   string `json:"action"`
+	Path     string `json:"path"`
+	Command  string `json:"command"`
+	Section  string `json:"section"`
+}
+
+// jobActions are the things a job can do with the stats and the report
+var jobActions = map[string]func(j jobConfig, set *StatsSet, report []byte) error{
+	"exec": func(j jobConfig, set *StatsSet, report []byte) error {
+		ctx, cancel := context.WithTimeout(context.Background(), *OptTimeout)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, "/bin/sh", "-c", j.Command)
+		cmd.Stdin = bytes.NewReader(report)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	},
+	"json": func(j jobConfig, set *StatsSet, report []byte) error {
+		return writeJobFile(j.Path, func(b *bytes.Buffer) error {
+			return jsonRenderer{}.Render(b, set)
+		})
+	},
+	"report": func(j jobConfig, set *StatsSet, report []byte) error {
+		return writeJobFile(j.Path, func(b *bytes.Buffer) error {
+			_, err := b.Write(report)
+			return err
+		})
+	},
+	"textfile": func(j jobConfig, set *StatsSet, report []byte) error {
+		return writeTextfile(j.Path, set)
+	},
+}
+
+// writeJobFile replaces the file at path with what write produces,
+// compressed if the name ends in .gz
+func writeJobFile(path string, write func(b *bytes.Buffer) error) error {
+
+	var b bytes.Buffer
+	if err := write(&b); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if strings.HasSuffix(path, gzipSuffix) {
+		tmp = strings.TrimSuffix(path, gzipSuffix) + ".tmp" + gzipSuffix
+	}
+
+	f, err := openOutput(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(b.Bytes()); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// check tests a job for errors in the configuration
+func (j jobConfig) check() error {
+
+	if j.Name == "" {
+		return errors.New("job without name")
+	}
+
+	if _, err := parseCron(j.Schedule); err != nil {
+		return fmt.Errorf("job '%s' has bad schedule: %v", j.Name, err)
+	}
+
+	if _, ok := jobActions[j.Action]; !ok {
+		return fmt.Errorf("job '%s' has unknown action '%s'", j.Name, j.Action)
+	}
+
+	if j.Action == "exec" && j.Command == "" {
+		return fmt.Errorf("job '%s' needs a command", j.Name)
+	}
+
+	if j.Action != "exec" && j.Path == "" {
+		return fmt.Errorf("job '%s' needs a path", j.Name)
+	}
+
+	if j.Section != "" && !isLegalSection(j.Section) {
+		return fmt.Errorf("job '%s' has unknown section '%s'", j.Name, j.Section)
+	}
+
+	return nil
+}
+
+// cronField is the set of values a field of a cron expression matches
+type cronField map[int]bool
+
+// cronSchedule is a parsed cron expression. If every is set, the fields are
+// not used
+type cronSchedule struct {
+	minute, hour, dom, month, dow cronField
+	domAll, dowAll                bool
+	every                         time.Duration
+}
+
+// cronShortcuts are the named schedules
+var cronShortcuts = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// cronLimits are the smallest and largest values of the five fields. Sunday
+// is both 0 and 7 in the day of week
+var cronLimits = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+// parseCron parses a cron expression
+func parseCron(expr string) (*cronSchedule, error) {
+
+	expr = strings.TrimSpace(expr)
+
+	if strings.HasPrefix(expr, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(expr, "@every ")))
+		if err != nil {
+			return nil, err
+		}
+		if d < time.Second {
+			return nil, fmt.Errorf("interval %v is shorter than a second", d)
+		}
+		return &cronSchedule{every: d}, nil
+	}
+
+	if s, ok := cronShortcuts[expr]; ok {
+		expr = s
+	}
+
+	parts := strings.Fields(expr)
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("'%s' doesn't have five fields", expr)
+	}
+
+	var fields [5]cronField
+
+	for i, p := range parts {
+		f, err := parseCronField(p, cronLimits[i][0], cronLimits[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("field %d: %v", i+1, err)
+		}
+		fields[i] = f
+	}
+
+	if fields[4][7] {
+		fields[4][0] = true
+	}
+
+	return &cronSchedule{
+		minute: fields[0],
+		hour:   fields[1],
+		dom:    fields[2],
+		month:  fields[3],
+		dow:    fields[4],
+		domAll: parts[2] == "*",
+		dowAll: parts[4] == "*",
+	}, nil
+}
+
+// parseCronField parses a field such as "*", "*/15", "1-5" or "0,30"
+func parseCronField(s string, min, max int) (cronField, error) {
+
+	f := make(cronField)
+
+	for _, part := range strings.Split(s, ",") {
+
+		step := 1
+		if idx := strings.Index(part, "/"); idx != -1 {
+			n, err := strconv.Atoi(part[idx+1:])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("bad step in '%s'", part)
+			}
+			step, part = n, part[:idx]
+		}
+
+		lo, hi := min, max
+
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("bad value '%s'", part)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("bad value '%s'", part)
+				}
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("'%s' is outside %d-%d", part, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			f[v] = true
+		}
+	}
+
+	return f, nil
+}
+
+// matches says if the schedule runs in the minute of t. As in cron, a
+// restricted day of month and day of week match if either of them does
+func (c *cronSchedule) matches(t time.Time) bool {
+
+	if !c.minute[t.Minute()] || !c.hour[t.Hour()] || !c.month[int(t.Month())] {
+		return false
+	}
+
+	dom, dow := c.dom[t.Day()], c.dow[int(t.Weekday())]
+
+	switch {
+	case c.domAll && c.dowAll:
+		return true
+	case c.domAll:
+		return dow
+	case c.dowAll:
+		return dom
+	}
+
+	return dom || dow
+}
+
+// next returns the first time after t the schedule runs, or the zero time if
+// it never does (such as on February 30th)
+func (c *cronSchedule) next(t time.Time) time.Time {
+
+	if c.every > 0 {
+		return t.Truncate(c.every).Add(c.every)
+	}
+
+	n := t.Truncate(time.Minute).Add(time.Minute)
+
+	// Every schedule that can match does so within four years
+	for end := n.AddDate(4, 0, 0); n.Before(end); n = n.Add(time.Minute) {
+		if c.matches(n) {
+			return n
+		}
+	}
+
+	return time.Time{}
+}
+
+// runJobs runs the jobs on their schedules with the most recent stats of the
+// server. It does not return
+func runJobs(s *server, jobs []jobConfig) {
+
+	if len(jobs) == 0 {
+		return
+	}
+
+	schedules := make([]*cronSchedule, len(jobs))
+	next := make([]time.Time, len(jobs))
+	now := time.Now()
+
+	for i, j := range jobs {
+		schedules[i], _ = parseCron(j.Schedule)
+		next[i] = schedules[i].next(now)
+	}
+
+	for {
+		var wake time.Time
+		for _, n := range next {
+			if !n.IsZero() && (wake.IsZero() || n.Before(wake)) {
+				wake = n
+			}
+		}
+		if wake.IsZero() {
+			return
+		}
+
+		time.Sleep(time.Until(wake))
+		now = time.Now()
+
+		for i, j := range jobs {
+			if next[i].IsZero() || next[i].After(now) {
+				continue
+			}
+			next[i] = schedules[i].next(now)
+
+			set, report := s.current(j.Section)
+			if set == nil {
+				log.Printf("Job '%s' skipped: no stats collected yet", j.Name)
+				continue
+			}
+
+			if err := jobActions[j.Action](j, set, report); err != nil {
+				log.Printf("Job '%s' failed: %v", j.Name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Test file for cron.go
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+
+	var tests = []struct {
+		expr string
+		ok   bool
+	}{
+		{"*/5 * * * *", true},
+		{"0 3 * * 1-5", true},
+		{"0,30 8-18 1 1,6 7", true},
+		{"@daily", true},
+		{"@every 30s", true},
+		{"@every 10ms", false},
+		{"@every soon", false},
+		{"* * * *", false},
+		{"60 * * * *", false},
+		{"* * 0 * *", false},
+		{"*/0 * * * *", false},
+		{"5-1 * * * *", false},
+	}
+
+	for _, test := range tests {
+		_, err := parseCron(test.expr)
+		if (err == nil) != test.ok {
+			t.Errorf("parseCron(%s) = %v (wanted ok %v)", test.expr, err, test.ok)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+
+	// A Thursday
+	start := time.Date(2017, 6, 15, 10, 7, 30, 0, time.UTC)
+
+	var tests = []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2017, 6, 15, 10, 15, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2017, 6, 15, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2017, 6, 16, 0, 0, 0, 0, time.UTC)},
+		{"30 2 * * 7", time.Date(2017, 6, 18, 2, 30, 0, 0, time.UTC)},
+		{"0 0 1,20 * 1", time.Date(2017, 6, 19, 0, 0, 0, 0, time.UTC)},
+		{"@every 30s", time.Date(2017, 6, 15, 10, 8, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, test := range tests {
+		c, err := parseCron(test.expr)
+		if err != nil {
+			t.Fatalf("parseCron(%s) = %v", test.expr, err)
+		}
+		if got := c.next(start); !got.Equal(test.want) {
+			t.Errorf("next(%s) = %v (wanted \"%v\")", test.expr, got, test.want)
+		}
+	}
+}
+
+func TestJobCheck(t *testing.T) {
+
+	var tests = []struct {
+		job jobConfig
+		ok  bool
+	}{
+		{jobConfig{Name: "hourly", Schedule: "@hourly", Action: "report", Path: "/tmp/report.txt"}, true},
+		{jobConfig{Name: "mail", Schedule: "0 6 * * *", Action: "exec", Command: "mail -s ZFS root"}, true},
+		{jobConfig{Name: "mail", Schedule: "0 6 * * *", Action: "exec"}, false},
+		{jobConfig{Name: "bad", Schedule: "whenever", Action: "report", Path: "/tmp/r"}, false},
+		{jobConfig{Name: "bad", Schedule: "@daily", Action: "print", Path: "/tmp/r"}, false},
+		{jobConfig{Name: "bad", Schedule: "@daily", Action: "json"}, false},
+		{jobConfig{Name: "bad", Schedule: "@daily", Action: "report", Path: "/tmp/r", Section: "nope"}, false},
+		{jobConfig{Schedule: "@daily", Action: "report", Path: "/tmp/r"}, false},
+	}
+
+	for _, test := range tests {
+		err := test.job.check()
+		if (err == nil) != test.ok {
+			t.Errorf("check(%+v) = %v (wanted ok %v)", test.job, err, test.ok)
+		}
+	}
+}
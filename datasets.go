@@ -0,0 +1,273 @@
+// Per-dataset activity for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// "arc_summary datasets" ranks the datasets by how much they read and write,
+// from the objset kstats each pool has for every mounted dataset. With
+// -interval, the ranking is by the rates between two samples instead of the
+// totals since the pool was imported. The objset kstats count the reads and
+// writes of applications, which the ARC serves; how many of them missed the
+// ARC and went to disk is only known for the system as a whole, so the
+// ranking shows which datasets put the most load on the ARC, not which ones
+// miss the most. See arc_summary.go for the license
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// objsetStats are the stats of a dataset from its objset kstat
+type objsetStats struct {
+	name     string
+	reads    uint64
+	writes   uint64
+	nread    uint64
+	nwritten uint64
+}
+
+// datasetSorts are the orders the datasets can be ranked in
+var datasetSorts = map[string]func(objsetStats) uint64{
+	"nread":    func(o objsetStats) uint64 { return o.nread },
+	"nwritten": func(o objsetStats) uint64 { return o.nwritten },
+	"reads":    func(o objsetStats) uint64 { return o.reads },
+	"writes":   func(o objsetStats) uint64 { return o.writes },
+}
+
+// datasetSortNames returns the names of the orders in alphabetical order
+func datasetSortNames() []string {
+
+	var names []string
+	for n := range datasetSorts {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// afterFields returns what follows the first n fields of a line, without the
+// blanks in between
+func afterFields(line string, n int) string {
+
+	for i := 0; i < n; i++ {
+		line = strings.TrimLeft(line, " \t")
+		idx := strings.IndexAny(line, " \t")
+		if idx == -1 {
+			return ""
+		}
+		line = line[idx:]
+	}
+
+	return strings.TrimLeft(line, " \t")
+}
+
+// parseObjset parses an objset kstat. The name of the dataset is a string
+// stat, the rest are numbers
+func parseObjset(data []byte) (objsetStats, error) {
+
+	var o objsetStats
+
+	input := bufio.NewScanner(bytes.NewReader(data))
+
+	for input.Scan() {
+		fields := strings.Fields(input.Text())
+		if len(fields) < 3 {
+			continue
+		}
+
+		// Dataset names may contain spaces, so the name is all of the
+		// line after the type
+		if fields[0] == "dataset_name" {
+			o.name = afterFields(input.Text(), 2)
+			continue
+		}
+
+		v, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		switch fields[0] {
+		case "reads":
+			o.reads = v
+		case "writes":
+			o.writes = v
+		case "nread":
+			o.nread = v
+		case "nwritten":
+			o.nwritten = v
+		}
+	}
+
+	if o.name == "" {
+		return o, fmt.Errorf("no dataset_name")
+	}
+
+	return o, input.Err()
+}
+
+// readObjsets returns the stats of all datasets of all imported pools
+func readObjsets(ctx context.Context) ([]objsetStats, error) {
+
+	out, err := runCommand(ctx, "zpool", "list", "-H", "-o", "name")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't list pools: %v", err)
+	}
+
+	var result []objsetStats
+
+	for _, pool := range strings.Fields(string(out)) {
+		names, err := readDirNames(ctx, procPath+pool)
+		if err != nil {
+			continue
+		}
+
+		for _, n := range names {
+			if !strings.HasPrefix(n, "objset-") {
+				continue
+			}
+
+			data, err := readFile(ctx, procPath+pool+"/"+n)
+			if err != nil {
+				continue
+			}
+
+			if o, err := parseObjset(data); err == nil {
+				result = append(result, o)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// objsetDelta returns how much the stats of each dataset grew since the
+// previous sample. Datasets that weren't there before are compared to zero
+func objsetDelta(prev, cur []objsetStats) []objsetStats {
+
+	old := make(map[string]objsetStats)
+	for _, o := range prev {
+		old[o.name] = o
+	}
+
+	sub := func(a, b uint64) uint64 {
+		if a < b {
+			return 0
+		}
+		return a - b
+	}
+
+	var result []objsetStats
+
+	for _, o := range cur {
+		p := old[o.name]
+		result = append(result, objsetStats{
+			name:     o.name,
+			reads:    sub(o.reads, p.reads),
+			writes:   sub(o.writes, p.writes),
+			nread:    sub(o.nread, p.nread),
+			nwritten: sub(o.nwritten, p.nwritten),
+		})
+	}
+
+	return result
+}
+
+// rankObjsets sorts the datasets by the given stat, largest first, and
+// returns the first n
+func rankObjsets(objsets []objsetStats, by func(objsetStats) uint64, n int) []objsetStats {
+
+	sorted := append([]objsetStats{}, objsets...)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		vi, vj := by(sorted[i]), by(sorted[j])
+		if vi != vj {
+			return vi > vj
+		}
+		return sorted[i].name < sorted[j].name
+	})
+
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+
+	return sorted
+}
+
+// printDatasets prints the table of datasets
+func printDatasets(w io.Writer, objsets []objsetStats) {
+
+	if len(objsets) == 0 {
+		fmt.Fprintln(w, indent+"(none)")
+		return
+	}
+
+	fmt.Fprintf(w, indent+"%-32s%10s%12s%10s%12s\n", "Dataset", "Reads", "Read", "Writes", "Written")
+
+	u := func(v uint64) string { return strconv.FormatUint(v, 10) }
+
+	for _, o := range objsets {
+		fmt.Fprintf(w, indent+"%-32s%10s%12s%10s%12s\n", redactName(o.name),
+			strings.TrimSpace(fHits(u(o.reads))), fBytes(u(o.nread)),
+			strings.TrimSpace(fHits(u(o.writes))), fBytes(u(o.nwritten)))
+	}
+}
+
+// cmdDatasets handles the "datasets" subcommand
+func cmdDatasets(args []string) {
+
+	fs := flag.NewFlagSet("datasets", flag.ExitOnError)
+	n := fs.Int("n", 20, "Number of datasets to show")
+	by := fs.String("sort", "nread", "Rank by this stat ("+strings.Join(datasetSortNames(), ", ")+")")
+	interval := fs.Duration("interval", 0, "Rank by the activity over this long instead of since import")
+	fs.Parse(args)
+
+	if *n < 1 {
+		log.Fatal("-n must be at least 1")
+	}
+
+	sortBy, ok := datasetSorts[*by]
+	if !ok {
+		log.Fatal("Can't sort by unknown stat '", *by, "'")
+	}
+
+	ctx, cancel := collectContext()
+	objsets, err := readObjsets(ctx)
+	cancel()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	title := "since import"
+
+	if *interval > 0 {
+		if bundleFiles != nil {
+			log.Fatal("-interval needs a live system")
+		}
+
+		time.Sleep(*interval)
+
+		ctx, cancel := collectContext()
+		cur, err := readObjsets(ctx)
+		cancel()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		objsets = objsetDelta(objsets, cur)
+		title = fmt.Sprintf("over %v", *interval)
+	}
+
+	fmt.Fprintf(reportOut, "Top datasets by %s %s:\n\n", *by, title)
+	printDatasets(os.Stdout, rankObjsets(objsets, sortBy, *n))
+}
@@ -0,0 +1,100 @@
+// Test file for datasets.go
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseObjset(t *testing.T) {
+
+	data := []byte(`44 1 0x01 7 2160 5214936013 15383924717486
+name                            type data
+dataset_name                    7    tank/home
+writes                          4    12
+nwritten                        4    4096
+reads                           4    34
+nread                           4    8192
+nunlinks                        4    1
+`)
+
+	got, err := parseObjset(data)
+	wanted := objsetStats{name: "tank/home", reads: 34, writes: 12, nread: 8192, nwritten: 4096}
+	if err != nil || got != wanted {
+		t.Errorf("parseObjset() = %+v, %v (wanted \"%+v\")", got, err, wanted)
+	}
+
+	data = []byte("name type data\ndataset_name 7  tank/my  files\nreads 4 1\n")
+	if got, err := parseObjset(data); err != nil || got.name != "tank/my  files" {
+		t.Errorf("parseObjset(name with spaces) = %q, %v (wanted \"tank/my  files\")", got.name, err)
+	}
+
+	if _, err := parseObjset([]byte("name type data\nreads 4 1\n")); err == nil {
+		t.Errorf("parseObjset(no name) succeeded (wanted error)")
+	}
+}
+
+func TestAfterFields(t *testing.T) {
+
+	var tests = []struct {
+		line   string
+		n      int
+		wanted string
+	}{
+		{"dataset_name    7    tank/home", 2, "tank/home"},
+		{"dataset_name 7 tank/my data", 2, "tank/my data"},
+		{"\tdataset_name\t7\ttank/a b ", 2, "tank/a b "},
+		{"dataset_name 7", 2, ""},
+		{"a b c", 0, "a b c"},
+	}
+
+	for _, test := range tests {
+		if got := afterFields(test.line, test.n); got != test.wanted {
+			t.Errorf("afterFields(%q, %d) = %q (wanted %q)", test.line, test.n, got, test.wanted)
+		}
+	}
+}
+
+func TestRankObjsets(t *testing.T) {
+
+	prev := []objsetStats{
+		{name: "tank", reads: 100, nread: 1000},
+		{name: "tank/a", reads: 10, nread: 5000},
+	}
+	cur := []objsetStats{
+		{name: "tank", reads: 150, nread: 9000},
+		{name: "tank/a", reads: 20, nread: 6000},
+		{name: "tank/b", reads: 5, writes: 7, nread: 500},
+	}
+
+	delta := objsetDelta(prev, cur)
+	wanted := []objsetStats{
+		{name: "tank", reads: 50, nread: 8000},
+		{name: "tank/a", reads: 10, nread: 1000},
+		{name: "tank/b", reads: 5, writes: 7, nread: 500},
+	}
+	if !reflect.DeepEqual(delta, wanted) {
+		t.Errorf("objsetDelta() = %+v (wanted \"%+v\")", delta, wanted)
+	}
+
+	var tests = []struct {
+		by   string
+		n    int
+		want []string
+	}{
+		{"nread", 3, []string{"tank", "tank/a", "tank/b"}},
+		{"nread", 1, []string{"tank"}},
+		{"reads", 2, []string{"tank", "tank/a"}},
+		{"writes", 3, []string{"tank/b", "tank", "tank/a"}},
+	}
+
+	for _, test := range tests {
+		var names []string
+		for _, o := range rankObjsets(cur, datasetSorts[test.by], test.n) {
+			names = append(names, o.name)
+		}
+		if !reflect.DeepEqual(names, test.want) {
+			t.Errorf("rankObjsets(%s, %d) = %v (wanted \"%v\")", test.by, test.n, names, test.want)
+		}
+	}
+}
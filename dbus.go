@@ -0,0 +1,110 @@
+// D-Bus signals for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// With -dbus, watch mode and the server send a HealthChanged signal on the
+// system bus whenever the ARC health changes, so applets and other services
+// can react without polling. The ARC counts as THROTTLED if it was throttled
+// since the previous sample. The signal carries the health, the ARC size in
+// bytes and the hit ratio in percent. It is sent with gdbus, which comes
+// with GLib. We only send signals and don't own a bus name, so there is no
+// object to ask for the current state: a listener learns it with the next
+// change, or the first signal after arc_summary starts. See arc_summary.go
+// for the license
+package main
+
+import (
+	"context"
+	"log"
+	"os/exec"
+	"strconv"
+)
+
+const (
+	dbusObjectPath = "/org/openzfs/ArcSummary"
+	dbusSignal     = "org.openzfs.ArcSummary.HealthChanged"
+)
+
+// arcHealth returns the state of the ARC as shown at the top of the ARC
+// section
+func arcHealth() string {
+
+	for _, l := range kstats["arcstats"] {
+		if name, value := cleanProcLine(l); name == "memory_throttle_count" && value != "0" {
+			return "THROTTLED"
+		}
+	}
+
+	return "HEALTHY"
+}
+
+// dbusNotifier remembers the last health it announced and the throttle count
+// of the previous sample
+type dbusNotifier struct {
+	last      string
+	throttles uint64
+	sampled   bool
+}
+
+// health returns THROTTLED if the ARC was throttled since the previous
+// sample, else HEALTHY. The count since boot never goes down, so a health
+// based on it would only go back to HEALTHY with the next reboot
+func (n *dbusNotifier) health() string {
+
+	v, _ := kstatValue("arcstats", "memory_throttle_count")
+	cur, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return "HEALTHY"
+	}
+
+	prev, sampled := n.throttles, n.sampled
+	n.throttles, n.sampled = cur, true
+
+	if sampled && cur > prev {
+		return "THROTTLED"
+	}
+
+	return "HEALTHY"
+}
+
+// gdbusArgs returns the arguments for gdbus to send the signal. The values
+// are in GVariant text format
+func gdbusArgs(health string, size uint64, hitRatio float64) []string {
+	return []string{
+		"emit", "--system",
+		"--object-path", dbusObjectPath,
+		"--signal", dbusSignal,
+		strconv.Quote(health),
+		"uint64 " + strconv.FormatUint(size, 10),
+		strconv.FormatFloat(hitRatio, 'f', 2, 64),
+	}
+}
+
+// update sends the signal if the health changed since the last call. The
+// first call always sends it, so listeners learn the current state
+func (n *dbusNotifier) update(ctx context.Context) {
+
+	health := n.health()
+	if health == n.last {
+		return
+	}
+
+	size, _ := lookupStat("size")
+
+	hitRatio := 0.0
+	if r, err := evalExpr(exprHitRatio, lookupStat); err == nil {
+		hitRatio = r
+	}
+
+	if err := execLimit.wait(ctx); err != nil {
+		log.Print("Couldn't send D-Bus signal: ", err)
+		return
+	}
+
+	out, err := exec.CommandContext(ctx, "gdbus", gdbusArgs(health, uint64(size), hitRatio)...).CombinedOutput()
+	if err != nil {
+		log.Print("Couldn't send D-Bus signal: ", err, ": ", string(out))
+		return
+	}
+
+	n.last = health
+}
@@ -0,0 +1,61 @@
+// Test file for dbus.go
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestArcHealth(t *testing.T) {
+
+	saved := kstats["arcstats"]
+	defer func() { kstats["arcstats"] = saved }()
+
+	var tests = []struct {
+		throttle string
+		wanted   string
+	}{
+		{"0", "HEALTHY"},
+		{"12", "THROTTLED"},
+	}
+
+	for _, test := range tests {
+		kstats["arcstats"] = []string{"memory_throttle_count           4    " + test.throttle}
+		if got := arcHealth(); got != test.wanted {
+			t.Errorf("arcHealth(%s) = %v (wanted \"%v\")", test.throttle, got, test.wanted)
+		}
+	}
+}
+
+func TestGdbusArgs(t *testing.T) {
+
+	got := gdbusArgs("THROTTLED", 1024, 93.456)
+	wanted := []string{"emit", "--system", "--object-path", dbusObjectPath, "--signal", dbusSignal,
+		`"THROTTLED"`, "uint64 1024", "93.46"}
+
+	if !reflect.DeepEqual(got, wanted) {
+		t.Errorf("gdbusArgs() = %v (wanted \"%v\")", got, wanted)
+	}
+}
+
+func TestDbusNotifierHealth(t *testing.T) {
+
+	saved := kstats["arcstats"]
+	defer func() { kstats["arcstats"] = saved }()
+
+	var n dbusNotifier
+
+	// Throttles before the first sample don't count, and the health goes
+	// back to HEALTHY once the count stops growing
+	for _, test := range []struct{ throttle, wanted string }{
+		{"12", "HEALTHY"},
+		{"12", "HEALTHY"},
+		{"13", "THROTTLED"},
+		{"13", "HEALTHY"},
+	} {
+		kstats["arcstats"] = []string{"memory_throttle_count           4    " + test.throttle}
+		if got := n.health(); got != test.wanted {
+			t.Errorf("dbusNotifier.health(%s) = %v (wanted \"%v\")", test.throttle, got, test.wanted)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Changes since the last run for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// "arc_summary -delta /var/lib/arc_summary/last.json" prints what changed
+// since the last run with the same file and then saves the current stats
+// there, so a cron job can answer "what happened in the last hour" without a
+// daemon. The first run only saves the stats. The output is the same as that
+// of the diff subcommand. See arc_summary.go for the license
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// saveStatsSet writes a StatsSet as JSON via a temporary file, so an
+// interrupted run doesn't leave a corrupt file behind
+func saveStatsSet(path string, s *StatsSet) error {
+
+	tmp := path + ".tmp"
+
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	if err := (jsonRenderer{}).Render(f, s); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// countersReset returns true if the counters of the ARC went backwards
+// between the two sets, which means the module was reloaded
+func countersReset(a, b *StatsSet) bool {
+	return b.Kstats["arcstats"]["hits"] < a.Kstats["arcstats"]["hits"]
+}
+
+// runDelta collects the stats, prints the changes since the stats saved in
+// path and saves the new ones
+func runDelta(ctx context.Context, path string) error {
+
+	cur, err := kstatCollector{}.Collect(ctx)
+	if err != nil {
+		return err
+	}
+
+	prev, err := readStatsSet(path)
+	switch {
+	case os.IsNotExist(err):
+		fmt.Fprintf(os.Stderr, "No earlier run in %s, saving this one\n", path)
+	case err != nil:
+		return err
+	default:
+		if countersReset(prev, cur) {
+			fmt.Fprintln(os.Stderr, "WARNING: Counters went backwards, ZFS was reloaded since the last run")
+		}
+		if err := writeDiff(diffStats(prev, cur)); err != nil {
+			return err
+		}
+	}
+
+	return saveStatsSet(path, cur)
+}
@@ -0,0 +1,52 @@
+// Test file for delta.go
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaveStatsSet(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "arc_summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "last.json")
+	s := &StatsSet{
+		Time:   time.Unix(1000, 0),
+		Kstats: map[string]map[string]uint64{"arcstats": {"hits": 42}},
+	}
+
+	if err := saveStatsSet(path, s); err != nil {
+		t.Fatalf("saveStatsSet() = %v", err)
+	}
+
+	got, err := readStatsSet(path)
+	if err != nil || got.Kstats["arcstats"]["hits"] != 42 || !got.Time.Equal(s.Time) {
+		t.Errorf("readStatsSet() = %v, %v (wanted hits 42)", got, err)
+	}
+
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("saveStatsSet() left %s.tmp behind", path)
+	}
+}
+
+func TestCountersReset(t *testing.T) {
+
+	a := &StatsSet{Kstats: map[string]map[string]uint64{"arcstats": {"hits": 100}}}
+	b := &StatsSet{Kstats: map[string]map[string]uint64{"arcstats": {"hits": 150}}}
+
+	if countersReset(a, b) {
+		t.Errorf("countersReset(100, 150) = true (wanted false)")
+	}
+
+	if !countersReset(b, a) {
+		t.Errorf("countersReset(150, 100) = false (wanted true)")
+	}
+}
@@ -0,0 +1,356 @@
+// Descriptions of the kstats for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// "-describe arcstats.mfu_ghost_hits" prints what a counter means and how to
+// read it. The descriptions are compiled into the program, so they are
+// there on systems without internet access. "-describe arcstats" lists all
+// stats of a file we have a description for. Stats without a file are
+// looked for in arcstats. See arc_summary.go for the license
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// kstatDesc is what we know about a stat. Meaning says what is counted,
+// Reading how to interpret it
+type kstatDesc struct {
+	meaning string
+	reading string
+}
+
+// kstatDescs are the stats we have descriptions for, keyed by file and name
+var kstatDescs = map[string]kstatDesc{
+	"arcstats.hits": {
+		"Number of reads that were served from the ARC.",
+		"Compare with misses: hits/(hits+misses) is the ARC hit ratio since boot. Well above 90 % is normal for most workloads."},
+	"arcstats.misses": {
+		"Number of reads that weren't in the ARC and had to go to the L2ARC or the pool.",
+		"A high number on its own means little; look at the hit ratio and at the ghost list hits to see if a larger ARC would help."},
+	"arcstats.demand_data_hits": {
+		"Hits on file data an application asked for.",
+		"This is the hit rate users notice. Prefetched data that is later read counts here as well."},
+	"arcstats.demand_data_misses": {
+		"Misses on file data an application asked for.",
+		"Every one of these is a read the application had to wait for."},
+	"arcstats.demand_metadata_hits": {
+		"Hits on metadata (dnodes, indirect blocks, directories) needed to serve a request.",
+		"Metadata hit ratios should be very high; misses here make every operation slow."},
+	"arcstats.demand_metadata_misses": {
+		"Misses on metadata needed to serve a request.",
+		"Many of these point to an ARC too small for the metadata, often with millions of files."},
+	"arcstats.prefetch_data_hits": {
+		"Hits on data the prefetcher asked for, which was already in the ARC.",
+		"The prefetcher checks before reading, so these are prefetches that cost nothing."},
+	"arcstats.prefetch_data_misses": {
+		"Data the prefetcher read from disk into the ARC.",
+		"Compare with zfetchstats: a lot of prefetching with few later demand hits wastes disk bandwidth."},
+	"arcstats.prefetch_metadata_hits": {
+		"Hits on metadata the prefetcher asked for.",
+		"Usually small and of little interest."},
+	"arcstats.prefetch_metadata_misses": {
+		"Metadata the prefetcher read from disk.",
+		"Usually small and of little interest."},
+	"arcstats.mru_hits": {
+		"Hits on buffers in the Most Recently Used list, which holds data accessed once.",
+		"A buffer hit here moves to the MFU list. Many MRU hits mean data is often read twice within a short time."},
+	"arcstats.mfu_hits": {
+		"Hits on buffers in the Most Frequently Used list, which holds data accessed more than once.",
+		"A high share of MFU hits is a sign of a stable working set."},
+	"arcstats.mru_ghost_hits": {
+		"Misses on data that was recently evicted from the MRU list.",
+		"The ARC keeps the headers of evicted buffers. Hits here mean the data would still be cached with a larger MRU list, and the ARC shifts its target towards MRU."},
+	"arcstats.mfu_ghost_hits": {
+		"Misses on data that was recently evicted from the MFU list.",
+		"Hits here mean frequently used data was pushed out, and the ARC shifts its target towards MFU. Together with mru_ghost_hits, a high rate means a larger ARC would help."},
+	"arcstats.size": {
+		"Current size of the ARC in bytes, including headers and metadata.",
+		"It moves between c_min and c_max; compare with c to see if the ARC is growing or shrinking."},
+	"arcstats.c": {
+		"Target size of the ARC in bytes.",
+		"The ARC grows towards this size. It drops when the kernel asks for memory back, so a c far below c_max means memory pressure."},
+	"arcstats.c_min": {
+		"Smallest size the ARC will shrink to, set by zfs_arc_min.",
+		"If size sits at c_min, the system is short of memory and the ARC can't do its job."},
+	"arcstats.c_max": {
+		"Largest size the ARC will grow to, set by zfs_arc_max.",
+		"Before OpenZFS 2.3 the default on Linux was half of the RAM; newer versions allow more."},
+	"arcstats.p": {
+		"Target size of the MRU part of the ARC, the rest is for MFU. OpenZFS 2.2 replaced it with pd and pm.",
+		"Moves with the ghost list hits; it tells how the ARC currently weights recency against frequency."},
+	"arcstats.mru_size": {
+		"Bytes in the Most Recently Used list.",
+		"Together with mfu_size this is most of the ARC."},
+	"arcstats.mfu_size": {
+		"Bytes in the Most Frequently Used list.",
+		"Together with mru_size this is most of the ARC."},
+	"arcstats.mru_ghost_size": {
+		"Bytes of data evicted from MRU whose headers are still kept.",
+		"This is how much larger the MRU list could usefully be."},
+	"arcstats.mfu_ghost_size": {
+		"Bytes of data evicted from MFU whose headers are still kept.",
+		"This is how much larger the MFU list could usefully be."},
+	"arcstats.anon_size": {
+		"Bytes in anonymous buffers, which are dirty data not yet written to a pool.",
+		"Grows with heavy writes and goes down after each transaction group is synced."},
+	"arcstats.hdr_size": {
+		"Bytes used for the headers of the ARC buffers.",
+		"Overhead that grows with the number of buffers; small blocks mean more of it."},
+	"arcstats.data_size": {
+		"Bytes of file data in the ARC.",
+		"Compare with metadata_size to see what the ARC is used for."},
+	"arcstats.metadata_size": {
+		"Bytes of metadata in the ARC.",
+		"Large on systems with many small files or deduplication."},
+	"arcstats.dbuf_size": {
+		"Bytes used by DMU buffer headers.",
+		"Part of the metadata overhead of the ARC."},
+	"arcstats.dnode_size": {
+		"Bytes used by dnodes, the on-disk inodes of ZFS.",
+		"Grows with the number of open or recently used files."},
+	"arcstats.bonus_size": {
+		"Bytes used by bonus buffers, which hold file attributes.",
+		"Grows with the number of recently used files."},
+	"arcstats.mru_data": {
+		"Bytes of file data in the MRU list (OpenZFS 2.2 and later).",
+		"Together with mru_metadata this splits mru_size by what the buffers hold."},
+	"arcstats.mfu_data": {
+		"Bytes of file data in the MFU list (OpenZFS 2.2 and later).",
+		"Together with mfu_metadata this splits mfu_size by what the buffers hold."},
+	"arcstats.anon_data": {
+		"Bytes of dirty file data in anonymous buffers (OpenZFS 2.2 and later).",
+		"The data part of anon_size; grows with heavy writes."},
+	"arcstats.uncached_size": {
+		"Bytes in the uncached list, which holds buffers read with primarycache=none or metadata (OpenZFS 2.2 and later).",
+		"These buffers are evicted as soon as they are no longer in use, so this should stay small."},
+	"arcstats.uncached_hits": {
+		"Hits on buffers in the uncached list (OpenZFS 2.2 and later).",
+		"Data that was read again before it could be evicted."},
+	"arcstats.iohits": {
+		"Reads of buffers that were still being read from disk (OpenZFS 2.2 and later).",
+		"Neither a hit nor a miss: the read had to wait for the disk, but didn't cause more I/O. Common with parallel readers of the same data."},
+	"arcstats.l2_hdr_size": {
+		"Bytes of RAM used for the headers of the buffers in the L2ARC.",
+		"This is the RAM the L2ARC costs. If it is a large part of the ARC, the L2ARC pushes data out of RAM."},
+	"arcstats.l2_hits": {
+		"Reads that missed the ARC but were found in the L2ARC.",
+		"Every hit is a read that didn't go to the pool devices."},
+	"arcstats.l2_misses": {
+		"Reads that missed both the ARC and the L2ARC.",
+		"With an L2ARC, hits/(hits+misses) is its hit ratio; a low ratio means the L2ARC doesn't help much."},
+	"arcstats.l2_size": {
+		"Bytes of data in the L2ARC before compression.",
+		"Compare with l2_asize to see how well the L2ARC data compresses."},
+	"arcstats.l2_asize": {
+		"Bytes allocated on the L2ARC devices.",
+		"This is how full the cache devices are."},
+	"arcstats.l2_cksum_bad": {
+		"Reads from the L2ARC that failed their checksum.",
+		"Should be zero. The data is read from the pool instead, but the cache device may be failing."},
+	"arcstats.l2_io_error": {
+		"Reads from the L2ARC that failed with an I/O error.",
+		"Should be zero. The cache device may be failing."},
+	"arcstats.l2_writes_sent": {
+		"Writes sent to the L2ARC devices.",
+		"The L2ARC is filled by a feed thread limited by l2arc_write_max."},
+	"arcstats.memory_throttle_count": {
+		"Number of times ZFS throttled writes because the system was short of memory.",
+		"Should be zero. Anything else means the ARC or dirty data are fighting with applications for memory."},
+	"arcstats.memory_direct_count": {
+		"Number of times the kernel reclaimed memory directly in the allocating thread.",
+		"Direct reclaim stalls the thread; a quickly growing count means memory pressure."},
+	"arcstats.memory_indirect_count": {
+		"Number of times the kernel reclaimed memory in the background.",
+		"Normal on a busy system. Compare with memory_direct_count."},
+	"arcstats.arc_no_grow": {
+		"Set to 1 while the ARC isn't allowed to grow because memory is low.",
+		"A 1 that doesn't go away means the ARC is stuck below its target."},
+	"arcstats.arc_need_free": {
+		"Bytes the ARC has been asked to free.",
+		"Non-zero while the kernel is reclaiming memory from the ARC."},
+	"arcstats.evict_skip": {
+		"Number of buffers eviction skipped because they were in use.",
+		"A high rate means eviction is working hard, usually under memory pressure."},
+	"arcstats.deleted": {
+		"Number of buffers removed from the ARC, including ghost list entries.",
+		"A rough measure of churn: compare with hits to see how fast the ARC turns over."},
+	"zfetchstats.hits": {
+		"Reads that matched a prefetch stream.",
+		"The prefetcher recognized a sequential access pattern."},
+	"zfetchstats.misses": {
+		"Reads that didn't match any prefetch stream.",
+		"High for random I/O; there is nothing to prefetch then."},
+	"zfetchstats.max_streams": {
+		"Number of times a new prefetch stream couldn't be created because the limit was reached.",
+		"If this grows quickly with many sequential readers, zfetch_max_streams may be too low."},
+	"dmu_tx.dmu_tx_assigned": {
+		"Number of transactions assigned to a transaction group.",
+		"The total number of write transactions since boot."},
+	"dmu_tx.dmu_tx_delay": {
+		"Transactions that were delayed.",
+		"Should be low; see dmu_tx_dirty_delay for the usual reason."},
+	"dmu_tx.dmu_tx_error": {
+		"Transactions that failed to be assigned.",
+		"Should be zero."},
+	"dmu_tx.dmu_tx_dirty_throttle": {
+		"Transactions that were stopped because the dirty data limit was reached.",
+		"Writers are producing data faster than the pool can write it. Anything but zero hurts write latency."},
+	"dmu_tx.dmu_tx_dirty_delay": {
+		"Transactions that were slowed down because there was a lot of dirty data.",
+		"This is the write throttle doing its job. A high rate means the pool is the bottleneck for writes."},
+	"dmu_tx.dmu_tx_dirty_over_max": {
+		"Transactions that found more dirty data than zfs_dirty_data_max.",
+		"Should be zero or close to it."},
+	"dmu_tx.dmu_tx_memory_reclaim": {
+		"Transactions that had to wait for memory to be reclaimed.",
+		"Points to memory pressure."},
+	"dmu_tx.dmu_tx_quota": {
+		"Transactions that failed because of a quota or reservation.",
+		"Non-zero means writes ran into quota limits."},
+	"fm.erpt-dropped": {
+		"Error reports (ereports) that were dropped because the queue to zed was full.",
+		"Should be zero. Anything else means faults went unreported; check 'zpool status' by hand."},
+	"fm.erpt-set-failed": {
+		"Error reports whose fields couldn't be filled in.",
+		"Should be zero. These reports reached zed without all their details."},
+	"fm.fmri-set-failed": {
+		"Error reports without the name (FMRI) of the faulty device.",
+		"Should be zero. zed can't tell which device such a report is about."},
+	"fm.payload-set-failed": {
+		"Error reports whose payload couldn't be attached.",
+		"Should be zero."},
+	"fm.erpt-duplicates": {
+		"Error reports that were suppressed because the same one was just posted.",
+		"Normal when a device keeps failing the same way; it keeps zed from being flooded."},
+	"vdev_cache_stats.hits": {
+		"Reads served from the vdev cache, which reads ahead small blocks on each device.",
+		"The vdev cache is off by default and was removed in OpenZFS 2.2."},
+	"vdev_cache_stats.misses": {
+		"Reads that missed the vdev cache.",
+		"Only meaningful if zfs_vdev_cache_size is set."},
+	"vdev_cache_stats.delegations": {
+		"Reads the vdev cache passed on without caching.",
+		"Only meaningful if zfs_vdev_cache_size is set."},
+	"vdev_mirror_stats.rotating_linear": {
+		"Mirror reads sent to a spinning disk right next to its last read.",
+		"The cheapest kind of read on a spinning disk."},
+	"vdev_mirror_stats.rotating_offset": {
+		"Mirror reads sent to a spinning disk close to its last read (within zfs_vdev_mirror_rotating_seek_offset).",
+		"Cheaper than a seek, as the head barely has to move."},
+	"vdev_mirror_stats.rotating_seek": {
+		"Mirror reads sent to a spinning disk far from its last read.",
+		"The expensive case the balancing tries to avoid."},
+	"vdev_mirror_stats.non_rotating_linear": {
+		"Mirror reads sent to an SSD right next to its last read.",
+		"On SSDs the position matters little."},
+	"vdev_mirror_stats.non_rotating_seek": {
+		"Mirror reads sent to an SSD elsewhere than its last read.",
+		"Together with non_rotating_linear, how many reads went to SSDs."},
+	"vdev_mirror_stats.preferred_found": {
+		"Reads where one child was clearly the best choice.",
+		"High is normal."},
+	"vdev_mirror_stats.preferred_not_found": {
+		"Reads where several children were equally good and one was picked at random.",
+		"Common on mirrors of identical idle disks."},
+	"zil.zil_commit_count": {
+		"Number of times the ZIL was committed, usually because of fsync or sync writes.",
+		"A high rate means a sync-heavy workload that can profit from a SLOG device."},
+	"zil.zil_commit_writer_count": {
+		"Number of commits that actually wrote log blocks.",
+		"Lower than zil_commit_count when several commits are combined into one write."},
+	"zil.zil_itx_count": {
+		"Number of intent log transactions created.",
+		"Each is a record of one change that has to survive a crash."},
+	"zil.zil_itx_indirect_count": {
+		"Sync writes logged by pointing to the data written in the pool (WR_INDIRECT).",
+		"Used for large writes or logbias=throughput; the data isn't written twice."},
+	"zil.zil_itx_copied_count": {
+		"Sync writes whose data was copied into the log record (WR_COPIED).",
+		"Used for small writes."},
+	"zil.zil_itx_needcopy_count": {
+		"Writes whose data is copied into the log only if they must be committed (WR_NEED_COPY).",
+		"Used for async writes that may later be made sync."},
+	"zil.zil_itx_metaslab_normal_count": {
+		"Log blocks written to the normal pool devices.",
+		"Without a SLOG all log blocks go here."},
+	"zil.zil_itx_metaslab_slog_count": {
+		"Log blocks written to a separate log device (SLOG).",
+		"Zero means there is no SLOG or it isn't used."},
+	"zil.zil_itx_indirect_bytes": {
+		"Bytes of sync writes logged indirectly.",
+		"The data itself is written to the pool, not the log."},
+	"zil.zil_itx_copied_bytes": {
+		"Bytes of data copied into log records.",
+		"This data is written twice, to the log and later to the pool."},
+	"zil.zil_itx_needcopy_bytes": {
+		"Bytes of writes that had to be copied into the log when committed.",
+		"Async writes that were made sync by a later fsync."},
+	"zil.zil_itx_metaslab_normal_bytes": {
+		"Bytes of log blocks written to the normal pool devices.",
+		"Without a SLOG all log bytes go here."},
+	"zil.zil_itx_metaslab_slog_bytes": {
+		"Bytes of log blocks written to a separate log device (SLOG).",
+		"Zero means there is no SLOG or it isn't used."},
+}
+
+// wrapText breaks text into lines of at most width characters at spaces
+func wrapText(text string, width int) string {
+
+	var b strings.Builder
+	col := 0
+
+	for _, w := range strings.Fields(text) {
+		if col > 0 && col+1+len(w) > width {
+			b.WriteString("\n")
+			col = 0
+		}
+		if col > 0 {
+			b.WriteString(" ")
+			col++
+		}
+		b.WriteString(w)
+		col += len(w)
+	}
+
+	return b.String() + "\n"
+}
+
+// describeKey returns the key of a stat as the user typed it. Stats without
+// a file are looked for in arcstats
+func describeKey(name string) string {
+
+	if !strings.Contains(name, ".") {
+		return defaultExprSection + "." + name
+	}
+
+	return name
+}
+
+// describe returns the description of a stat or, if name is a file, the
+// list of described stats in it
+func describe(name string) (string, error) {
+
+	if d, ok := kstatDescs[describeKey(name)]; ok {
+		return describeKey(name) + "\n\n" + wrapText(d.meaning, lineLen) + "\n" + wrapText(d.reading, lineLen), nil
+	}
+
+	var names []string
+	for k := range kstatDescs {
+		if strings.HasPrefix(k, name+".") {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	if len(names) > 0 {
+		var b strings.Builder
+		for _, n := range names {
+			fmt.Fprintf(&b, "%-40s%s\n", n, kstatDescs[n].meaning)
+		}
+		return b.String(), nil
+	}
+
+	return "", fmt.Errorf("no description for '%s'", name)
+}
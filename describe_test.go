@@ -0,0 +1,47 @@
+// Test file for describe.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDescribe(t *testing.T) {
+
+	var tests = []struct {
+		name  string
+		start string
+		fails bool
+	}{
+		{"arcstats.mfu_ghost_hits", "arcstats.mfu_ghost_hits\n\nMisses on data", false},
+		{"mfu_ghost_hits", "arcstats.mfu_ghost_hits\n", false},
+		{"zfetchstats", "zfetchstats.hits ", false},
+		{"arcstats.nosuch", "", true},
+		{"zfetch", "", true},
+	}
+
+	for _, test := range tests {
+		got, err := describe(test.name)
+		if (err != nil) != test.fails || !strings.HasPrefix(got, test.start) {
+			t.Errorf("describe(%s) = %q, %v (wanted start %q)", test.name, got, err, test.start)
+		}
+	}
+}
+
+func TestKstatDescs(t *testing.T) {
+	for k, d := range kstatDescs {
+		if !strings.Contains(k, ".") || d.meaning == "" || d.reading == "" {
+			t.Errorf("kstatDescs[%s] is incomplete", k)
+		}
+	}
+}
+
+func TestWrapText(t *testing.T) {
+
+	got := wrapText("one two three four", 9)
+	want := "one two\nthree\nfour\n"
+
+	if got != want {
+		t.Errorf("wrapText = %q (wanted %q)", got, want)
+	}
+}
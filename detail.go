@@ -0,0 +1,86 @@
+// Detail levels of the report for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// -detail brief prints only the summary line of each part of a section,
+// which is enough for a dashboard. -detail normal is the usual report.
+// -detail full adds every counter of the kstat file behind the section,
+// formatted like the rest of the report, so there is no need to switch to
+// the raw dump with -r. See arc_summary.go for the license
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// detailLevels are the levels known to -detail
+var detailLevels = map[string]int{
+	"brief":  0,
+	"normal": 1,
+	"full":   2,
+}
+
+// detailBrief says if only the summary lines are printed
+func detailBrief() bool {
+	return *OptDetail == "brief"
+}
+
+// sectionCounters returns the names of the stats in a kstat file that belong
+// to a section in alphabetical order. The L2ARC stats are the ones in
+// arcstats that start with l2_, the ARC section gets the others
+func sectionCounters(section string, stats map[string]string) []string {
+
+	var names []string
+
+	for n := range stats {
+		l2 := strings.HasPrefix(n, "l2_")
+		if (section == "l2arc") != l2 && (section == "arc" || section == "l2arc") {
+			continue
+		}
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// fCounter formats the value of a stat by its unit
+func fCounter(file, stat, value string) string {
+
+	if _, unit := kstatType(file, stat); unit == "bytes" {
+		return fBytes(value)
+	}
+
+	if _, err := strconv.ParseUint(value, 10, 64); err != nil {
+		return value
+	}
+
+	return fHits(value)
+}
+
+// printAllCounters prints every stat of the kstat file of a section
+func printAllCounters(section string) {
+
+	file, ok := sectionPaths[section]
+	if section == "l2arc" {
+		file, ok = sectionPaths["arc"], true
+	}
+	if !ok {
+		return
+	}
+
+	stats := make(map[string]string)
+	procSection(file, stats)
+
+	names := sectionCounters(section, stats)
+	if len(names) == 0 {
+		return
+	}
+
+	fmt.Fprintln(reportOut, "\nAll counters:")
+	for _, n := range names {
+		prtL2(n+":", fCounter(file, n, stats[n]))
+	}
+}
@@ -0,0 +1,54 @@
+// Test file for detail.go
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSectionCounters(t *testing.T) {
+
+	stats := map[string]string{
+		"size":     "1",
+		"hits":     "2",
+		"l2_size":  "3",
+		"l2_hits":  "4",
+		"c_max":    "5",
+		"l2_asize": "6",
+	}
+
+	var tests = []struct {
+		section string
+		want    []string
+	}{
+		{"arc", []string{"c_max", "hits", "size"}},
+		{"l2arc", []string{"l2_asize", "l2_hits", "l2_size"}},
+		{"dmu", []string{"c_max", "hits", "l2_asize", "l2_hits", "l2_size", "size"}},
+	}
+
+	for _, test := range tests {
+		if got := sectionCounters(test.section, stats); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("sectionCounters(%s) = %v (wanted \"%v\")", test.section, got, test.want)
+		}
+	}
+}
+
+func TestFCounter(t *testing.T) {
+
+	var tests = []struct {
+		file, stat, value string
+		want              string
+	}{
+		{"arcstats", "c_max", "1024", fBytes("1024")},
+		{"arcstats", "l2_asize", "2048", fBytes("2048")},
+		{"arcstats", "hits", "5000", fHits("5000")},
+		{"zil", "zil_itx_metaslab_normal_bytes", "1024", fBytes("1024")},
+		{"arcstats", "weird", "abc", "abc"},
+	}
+
+	for _, test := range tests {
+		if got := fCounter(test.file, test.stat, test.value); got != test.want {
+			t.Errorf("fCounter(%s) = %v (wanted \"%v\")", test.stat, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+// Comparing two sets of stats for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// "arc_summary diff old.json new.json" compares two StatsSets as written by
+// -o json or a watch mode snapshot. With -o json the result is JSON with
+// the old and new value, the change and the change in percent of every
+// stat, for automated checks. See arc_summary.go for the license
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+)
+
+// statDiff is the change of a single stat. Percent is nil if the old value
+// was zero
+type statDiff struct {
+	Old     uint64   `json:"old"`
+	New     uint64   `json:"new"`
+	Delta   int64    `json:"delta"`
+	Percent *float64 `json:"percent_change"`
+}
+
+// statsDiff is the change between two StatsSets. Stats are keyed by file and
+// name, eg "arcstats.hits"
+type statsDiff struct {
+	OldTime  int64               `json:"old_time"`
+	NewTime  int64               `json:"new_time"`
+	Interval float64             `json:"interval_seconds"`
+	Stats    map[string]statDiff `json:"stats"`
+}
+
+// diffStats compares two StatsSets. Only stats that are in both are
+// included
+func diffStats(a, b *StatsSet) statsDiff {
+
+	d := statsDiff{
+		OldTime:  a.Time.Unix(),
+		NewTime:  b.Time.Unix(),
+		Interval: b.Time.Sub(a.Time).Seconds(),
+		Stats:    make(map[string]statDiff),
+	}
+
+	for section, m := range a.Kstats {
+		for name, old := range m {
+			cur, ok := b.Kstats[section][name]
+			if !ok {
+				continue
+			}
+
+			sd := statDiff{Old: old, New: cur, Delta: int64(cur - old)}
+			if old != 0 {
+				p := 100 * float64(sd.Delta) / float64(old)
+				sd.Percent = &p
+			}
+
+			d.Stats[section+"."+name] = sd
+		}
+	}
+
+	return d
+}
+
+// readStatsSet reads a StatsSet written by -o json
+func readStatsSet(path string) (*StatsSet, error) {
+
+	f, err := openInput(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var s StatsSet
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("%s is not a JSON stats file: %v", path, err)
+	}
+
+	return &s, nil
+}
+
+// printDiff prints the stats that changed as a table
+func printDiff(w io.Writer, d statsDiff) {
+
+	var names []string
+	for n, sd := range d.Stats {
+		if sd.Delta != 0 {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "Changes over %0.0f seconds:\n\n", d.Interval)
+
+	if len(names) == 0 {
+		fmt.Fprintln(w, indent+"(none)")
+		return
+	}
+
+	fmt.Fprintf(w, indent+"%-40s%16s%16s%16s%10s\n", "Stat", "Old", "New", "Change", "%")
+
+	for _, n := range names {
+		sd := d.Stats[n]
+
+		perc := "-"
+		if sd.Percent != nil {
+			perc = fmt.Sprintf("%+0.1f", *sd.Percent)
+		}
+
+		fmt.Fprintf(w, indent+"%-40s%16d%16d%16s%10s\n", n, sd.Old, sd.New,
+			fmt.Sprintf("%+d", sd.Delta), perc)
+	}
+}
+
+// cmdDiff handles the "diff" subcommand
+func cmdDiff(args []string) {
+
+	if len(args) != 2 {
+		log.Fatal("Usage: arc_summary [-o json] diff <old.json> <new.json>")
+	}
+
+	a, err := readStatsSet(args[0])
+	if err != nil {
+		log.Fatal("Couldn't read ", args[0], ": ", err)
+	}
+
+	b, err := readStatsSet(args[1])
+	if err != nil {
+		log.Fatal("Couldn't read ", args[1], ": ", err)
+	}
+
+	if err := writeDiff(diffStats(a, b)); err != nil {
+		log.Fatal("Couldn't write diff: ", err)
+	}
+}
+
+// writeDiff prints the diff in the output format given with -o
+func writeDiff(d statsDiff) error {
+
+	switch *OptOutput {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d)
+	case "text":
+		printDiff(os.Stdout, d)
+		return nil
+	}
+
+	return fmt.Errorf("only text and json output are supported, not '%s'", *OptOutput)
+}
@@ -0,0 +1,50 @@
+// Test file for diff.go
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiffStats(t *testing.T) {
+
+	now := time.Now()
+
+	a := &StatsSet{
+		Time:   now,
+		Kstats: map[string]map[string]uint64{"arcstats": {"hits": 100, "misses": 0, "size": 50, "gone": 1}},
+	}
+	b := &StatsSet{
+		Time:   now.Add(10 * time.Second),
+		Kstats: map[string]map[string]uint64{"arcstats": {"hits": 150, "misses": 5, "size": 25, "new": 1}},
+	}
+
+	d := diffStats(a, b)
+
+	if d.Interval != 10 {
+		t.Errorf("diffStats().Interval = %v (wanted \"10\")", d.Interval)
+	}
+
+	if len(d.Stats) != 3 {
+		t.Errorf("diffStats() has %d stats (wanted 3)", len(d.Stats))
+	}
+
+	var tests = []struct {
+		name    string
+		delta   int64
+		percent float64
+		hasPerc bool
+	}{
+		{"arcstats.hits", 50, 50, true},
+		{"arcstats.misses", 5, 0, false},
+		{"arcstats.size", -25, -50, true},
+	}
+
+	for _, test := range tests {
+		sd := d.Stats[test.name]
+		if sd.Delta != test.delta || (sd.Percent != nil) != test.hasPerc ||
+			(sd.Percent != nil && *sd.Percent != test.percent) {
+			t.Errorf("diffStats(%s) = %+v (wanted delta %v, percent %v)", test.name, sd, test.delta, test.percent)
+		}
+	}
+}
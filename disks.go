@@ -0,0 +1,184 @@
+// Pool device statistics for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// Maps the devices of each pool to their block devices and shows how busy
+// they are according to /proc/diskstats, so ARC misses can be related to
+// actual disk load. See arc_summary.go for the license
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const diskstatsPath = "/proc/diskstats"
+
+// diskStat holds the fields of /proc/diskstats we are interested in. Times
+// are in milliseconds
+type diskStat struct {
+	reads, readMs   uint64
+	writes, writeMs uint64
+	ioMs            uint64
+}
+
+// parseZpoolDevices takes the output of "zpool status -P" and returns the
+// device paths of each pool
+func parseZpoolDevices(out string) map[string][]string {
+
+	m := make(map[string][]string)
+	pool := ""
+
+	input := bufio.NewScanner(strings.NewReader(out))
+
+	for input.Scan() {
+		fields := strings.Fields(input.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		if fields[0] == "pool:" && len(fields) > 1 {
+			pool = fields[1]
+			continue
+		}
+
+		if pool != "" && strings.HasPrefix(fields[0], "/dev/") {
+			m[pool] = append(m[pool], fields[0])
+		}
+	}
+
+	return m
+}
+
+// parseDiskstats takes the contents of /proc/diskstats and returns the
+// statistics for each block device by name, eg "sda1"
+func parseDiskstats(data string) map[string]diskStat {
+
+	m := make(map[string]diskStat)
+
+	input := bufio.NewScanner(strings.NewReader(data))
+
+	for input.Scan() {
+		fields := strings.Fields(input.Text())
+		if len(fields) < 13 {
+			continue
+		}
+
+		n := make([]uint64, len(fields))
+		for i := 3; i < len(fields); i++ {
+			n[i], _ = strconv.ParseUint(fields[i], 10, 64)
+		}
+
+		m[fields[2]] = diskStat{
+			reads:   n[3],
+			readMs:  n[6],
+			writes:  n[7],
+			writeMs: n[10],
+			ioMs:    n[12],
+		}
+	}
+
+	return m
+}
+
+// blockDevice returns the kernel name of the block device behind a path
+// such as /dev/disk/by-id/ata-XYZ-part1
+func blockDevice(path string) string {
+
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		resolved = path
+	}
+
+	return filepath.Base(resolved)
+}
+
+// fLatency returns the average time per operation in milliseconds
+func fLatency(ms, ops uint64) string {
+
+	if ops == 0 {
+		return "-"
+	}
+
+	return fmt.Sprintf("%0.1f ms", float64(ms)/float64(ops))
+}
+
+// printDisks displays the pool devices with their load since boot
+func printDisks() {
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	out, err := runCommand(ctx, "zpool", "status", "-P")
+	if err != nil {
+		skipSection("disks", fmt.Errorf("couldn't run 'zpool status': %v", err))
+		return
+	}
+
+	data, err := readFile(ctx, diskstatsPath)
+	if err != nil {
+		skipSection("disks", fmt.Errorf("couldn't read %s: %v", diskstatsPath, err))
+		return
+	}
+
+	pools := parseZpoolDevices(string(out))
+	stats := parseDiskstats(string(data))
+
+	uptime, err := readUptime(ctx)
+	if err != nil {
+		uptime = 0
+	}
+
+	var names []string
+	for p := range pools {
+		names = append(names, p)
+	}
+	sort.Strings(names)
+
+	for _, p := range names {
+
+		prtL1("Pool "+redactName(p)+":", " ")
+
+		var table layoutTable
+		table.add("Device", "Reads", "Writes", "Read lat", "Write lat", "Busy")
+
+		for _, path := range pools[p] {
+			dev := blockDevice(path)
+
+			s, ok := stats[dev]
+			if !ok && *OptPlain {
+				printPlain(dev, "", "no statistics")
+				continue
+			}
+			if !ok {
+				table.add(dev, "(no statistics)")
+				continue
+			}
+
+			busy := "-"
+			if uptime > 0 {
+				busy = fmt.Sprintf("%0.1f %%", 100*float64(s.ioMs)/(uptime*1000))
+			}
+
+			if *OptPlain {
+				printPlain(dev, "", plainList(
+					"reads", fHits(strconv.FormatUint(s.reads, 10)),
+					"writes", fHits(strconv.FormatUint(s.writes, 10)),
+					"read latency", fLatency(s.readMs, s.reads),
+					"write latency", fLatency(s.writeMs, s.writes),
+					"busy", busy))
+				continue
+			}
+
+			table.add(dev, fHits(strconv.FormatUint(s.reads, 10)), fHits(strconv.FormatUint(s.writes, 10)),
+				fLatency(s.readMs, s.reads), fLatency(s.writeMs, s.writes), busy)
+		}
+
+		if !*OptPlain {
+			table.print()
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Test file for disks.go
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseZpoolDevices(t *testing.T) {
+	out := `  pool: tank
+ state: ONLINE
+config:
+
+	NAME                                   STATE     READ WRITE CKSUM
+	tank                                   ONLINE       0     0     0
+	  mirror-0                             ONLINE       0     0     0
+	    /dev/sda1                          ONLINE       0     0     0
+	    /dev/disk/by-id/ata-DISK2-part1    ONLINE       0     0     0
+
+errors: No known data errors
+
+  pool: rpool
+ state: ONLINE
+config:
+
+	NAME          STATE     READ WRITE CKSUM
+	rpool         ONLINE       0     0     0
+	  /dev/nvme0n1p3  ONLINE       0     0     0
+`
+	want := map[string][]string{
+		"tank":  {"/dev/sda1", "/dev/disk/by-id/ata-DISK2-part1"},
+		"rpool": {"/dev/nvme0n1p3"},
+	}
+
+	got := parseZpoolDevices(out)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseZpoolDevices() = %v (wanted %v)", got, want)
+	}
+}
+
+func TestParseDiskstats(t *testing.T) {
+	data := "   8       1 sda1 100 0 800 50 200 0 1600 400 0 300 450 0 0 0 0\n" +
+		"   7       0 loop0 0 0 0 0\n"
+
+	got := parseDiskstats(data)
+
+	want := diskStat{reads: 100, readMs: 50, writes: 200, writeMs: 400, ioMs: 300}
+	if got["sda1"] != want {
+		t.Errorf("parseDiskstats() sda1 = %v (wanted %v)", got["sda1"], want)
+	}
+
+	if _, ok := got["loop0"]; ok {
+		t.Errorf("parseDiskstats() accepted short line for loop0")
+	}
+}
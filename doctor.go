@@ -0,0 +1,203 @@
+// Diagnosis of the environment for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// "arc_summary doctor" checks everything the report needs: that the zfs
+// module is loaded, that the kstats and tunables exist and can be read,
+// which kstat files this version of ZFS has and if the commands we call can
+// be found. Every problem comes with a hint on what to do about it, where
+// the report would just stop with an error. See arc_summary.go for the
+// license
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+const modulesPath = "/proc/modules"
+
+// Results of a check. Optional things that are missing only get a warning
+const (
+	doctorOK = iota
+	doctorWarn
+	doctorFail
+)
+
+var doctorStateNames = map[int]string{
+	doctorOK:   "OK",
+	doctorWarn: "WARN",
+	doctorFail: "FAIL",
+}
+
+// doctorResult is the result of one check
+type doctorResult struct {
+	state int
+	what  string
+	hint  string
+}
+
+// doctorHint returns what the user can do about an error reading path
+func doctorHint(path string, err error) string {
+
+	switch {
+	case os.IsPermission(err):
+		return fmt.Sprintf("run as root or give this user read access to %s", path)
+	case os.IsNotExist(err):
+		return fmt.Sprintf("%s is missing; check that the zfs module is loaded", path)
+	}
+
+	return err.Error()
+}
+
+// moduleLoaded says if the zfs module is in the contents of /proc/modules
+func moduleLoaded(modules string) bool {
+
+	for _, l := range strings.Split(modules, "\n") {
+		if f := strings.Fields(l); len(f) > 0 && f[0] == "zfs" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkModule checks that the zfs module is loaded. Only Linux has
+// /proc/modules
+func checkModule(ctx context.Context) doctorResult {
+
+	if runtime.GOOS != "linux" && bundleFiles == nil {
+		return doctorResult{doctorOK, "zfs module: not checked on " + runtime.GOOS, ""}
+	}
+
+	data, err := readFile(ctx, modulesPath)
+	if err != nil {
+		return doctorResult{doctorWarn, "zfs module: couldn't read " + modulesPath, doctorHint(modulesPath, err)}
+	}
+
+	if !moduleLoaded(string(data)) {
+		return doctorResult{doctorFail, "zfs module: not loaded", "load it with 'modprobe zfs'"}
+	}
+
+	return doctorResult{doctorOK, "zfs module: loaded", ""}
+}
+
+// checkDir checks that a directory exists and can be read
+func checkDir(ctx context.Context, what, path string) doctorResult {
+
+	if runtime.GOOS != "linux" && bundleFiles == nil {
+		return doctorResult{doctorOK, what + ": not used on " + runtime.GOOS, ""}
+	}
+
+	if _, err := readDirNames(ctx, path); err != nil {
+		return doctorResult{doctorFail, fmt.Sprintf("%s: can't read %s", what, path), doctorHint(path, err)}
+	}
+
+	return doctorResult{doctorOK, fmt.Sprintf("%s: %s", what, path), ""}
+}
+
+// checkKstatFiles checks which of the kstat files of the report this
+// version of ZFS has. Only arcstats is needed, the others are optional
+func checkKstatFiles(ctx context.Context) []doctorResult {
+
+	var files []string
+	for _, f := range sectionPaths {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+
+	var result []doctorResult
+
+	for _, f := range files {
+		_, err := readKstat(ctx, f)
+
+		switch {
+		case err == nil:
+			result = append(result, doctorResult{doctorOK, "kstat " + f + ": present", ""})
+		case f == "arcstats":
+			result = append(result, doctorResult{doctorFail, "kstat " + f + ": can't read", doctorHint(procPath+f, err)})
+		case os.IsNotExist(err):
+			result = append(result, doctorResult{doctorWarn, "kstat " + f + ": not in this version of ZFS",
+				"the " + strings.ToUpper(statSection(f, "")) + " section will be skipped"})
+		default:
+			result = append(result, doctorResult{doctorWarn, "kstat " + f + ": can't read", doctorHint(procPath+f, err)})
+		}
+	}
+
+	return result
+}
+
+// checkCommand checks that an external command can be found. Without it,
+// only the sections that use it are skipped
+func checkCommand(name, usedBy string) doctorResult {
+
+	if bundleFiles != nil {
+		return doctorResult{doctorOK, name + ": taken from the bundle", ""}
+	}
+
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return doctorResult{doctorWarn, name + ": not found", "install it or add it to PATH to get " + usedBy}
+	}
+
+	return doctorResult{doctorOK, name + ": " + path, ""}
+}
+
+// doctorChecks runs all checks
+func doctorChecks(ctx context.Context) []doctorResult {
+
+	result := []doctorResult{
+		checkModule(ctx),
+		checkDir(ctx, "kstats", procPath),
+		checkDir(ctx, "tunables", tunablesPath),
+	}
+
+	// Without the kstat directory, every file would be reported missing
+	if result[1].state == doctorOK {
+		result = append(result, checkKstatFiles(ctx)...)
+	}
+
+	return append(result,
+		checkCommand("zpool", "the disks and queues sections"),
+		checkCommand(modinfoCommand(), "descriptions of all tunables with -d"))
+}
+
+// printDoctor prints the results and returns the worst state
+func printDoctor(results []doctorResult) int {
+
+	worst := doctorOK
+
+	for _, r := range results {
+		fmt.Fprintf(reportOut, "%-5s %s\n", doctorStateNames[r.state], r.what)
+		if r.hint != "" {
+			fmt.Fprintf(reportOut, "%-5s -> %s\n", "", r.hint)
+		}
+		if r.state > worst {
+			worst = r.state
+		}
+	}
+
+	return worst
+}
+
+// cmdDoctor handles the "doctor" subcommand. It exits with 1 if something
+// the report needs is broken
+func cmdDoctor(args []string) {
+
+	if len(args) != 0 {
+		log.Fatal("Usage: arc_summary doctor")
+	}
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	if printDoctor(doctorChecks(ctx)) == doctorFail {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,61 @@
+// Test file for doctor.go
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestModuleLoaded(t *testing.T) {
+
+	var tests = []struct {
+		modules string
+		want    bool
+	}{
+		{"", false},
+		{"zfs 3821568 6 - Live 0x0000000000000000 (POE)\nspl 102400 1 zfs, Live 0x0 (OE)\n", true},
+		{"zfs_common 1 0 - Live 0x0\nzunicode 1 0 - Live 0x0\n", false},
+	}
+
+	for _, test := range tests {
+		if got := moduleLoaded(test.modules); got != test.want {
+			t.Errorf("moduleLoaded(%q) = %v (wanted \"%v\")", test.modules, got, test.want)
+		}
+	}
+}
+
+func TestDoctorHint(t *testing.T) {
+
+	var tests = []struct {
+		err  error
+		want string
+	}{
+		{os.ErrPermission, "run as root"},
+		{os.ErrNotExist, "/proc/spl/kstat/zfs/ is missing"},
+		{errors.New("disk on fire"), "disk on fire"},
+	}
+
+	for _, test := range tests {
+		if got := doctorHint(procPath, test.err); !strings.HasPrefix(got, test.want) {
+			t.Errorf("doctorHint(%v) = %v (wanted \"%v...\")", test.err, got, test.want)
+		}
+	}
+}
+
+func TestPrintDoctor(t *testing.T) {
+
+	results := []doctorResult{
+		{doctorOK, "zfs module: loaded", ""},
+		{doctorWarn, "zpool: not found", "install it"},
+	}
+
+	var worst int
+	out := string(captureOutput(func() { worst = printDoctor(results) }))
+
+	wanted := "OK    zfs module: loaded\nWARN  zpool: not found\n      -> install it\n"
+	if out != wanted || worst != doctorWarn {
+		t.Errorf("printDoctor() = %q, %d (wanted %q, %d)", out, worst, wanted, doctorWarn)
+	}
+}
@@ -0,0 +1,130 @@
+// Reading dumps of the stats for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// With "-f file", the stats are read from a dump made by -r or -o json
+// instead of the live system, and "-f -" reads the dump from stdin. That
+// way, any report can be made for another host, eg "ssh nas arc_summary -r
+// | arc_summary -f - -s arc". The dump is turned into the files of a
+// bundle, so everything that works with -bundle works with -f. See
+// arc_summary.go for the license
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// dumpSectionRE matches the title of a section of -r, eg "ARCSTATS:"
+var dumpSectionRE = regexp.MustCompile(`^([A-Z0-9_]+):$`)
+
+// parseDump returns the stats of a dump in JSON or the format of -r
+func parseDump(data []byte) (*StatsSet, error) {
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		var s StatsSet
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, fmt.Errorf("not a JSON dump: %v", err)
+		}
+		if len(s.Kstats) == 0 {
+			return nil, errors.New("no kstats in JSON dump")
+		}
+		return &s, nil
+	}
+
+	return parseRawDump(bytes.NewReader(data))
+}
+
+// parseRawDump returns the stats of a dump made by -r. The header and
+// derived metrics are skipped, as are sections that couldn't be read
+func parseRawDump(r io.Reader) (*StatsSet, error) {
+
+	s := &StatsSet{
+		Time:     time.Now(),
+		Kstats:   make(map[string]map[string]uint64),
+		Tunables: make(map[string]string),
+	}
+
+	section := ""
+	input := bufio.NewScanner(r)
+
+	for input.Scan() {
+		l := input.Text()
+
+		if m := dumpSectionRE.FindStringSubmatch(l); m != nil {
+			section = strings.ToLower(m[1])
+			continue
+		}
+
+		// Stats are indented, everything else is header or description
+		fields := strings.Fields(l)
+		if section == "" || !strings.HasPrefix(l, "\t") || len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		switch section {
+		case "derived":
+		case "tunables":
+			s.Tunables[fields[0]] = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(l), fields[0]))
+		default:
+			if fields[0] == "(skipped:" {
+				continue
+			}
+			v, ok := parseKstatValue(fields[1])
+			if !ok {
+				return nil, fmt.Errorf("bad value '%s' for %s in %s", fields[1], fields[0], section)
+			}
+			if s.Kstats[section] == nil {
+				s.Kstats[section] = make(map[string]uint64)
+			}
+			s.Kstats[section][fields[0]] = v
+		}
+	}
+
+	if err := input.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(s.Kstats) == 0 {
+		return nil, errors.New("no kstats in dump (wanted the output of -r or -o json)")
+	}
+
+	return s, nil
+}
+
+// loadDump reads the dump given with -f
+func loadDump(path string) {
+
+	var data []byte
+	var err error
+
+	if path == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		var f io.ReadCloser
+		if f, err = openInput(path); err == nil {
+			data, err = ioutil.ReadAll(f)
+			f.Close()
+		}
+	}
+
+	if err != nil {
+		log.Fatal("Couldn't read dump ", path, ": ", err)
+	}
+
+	s, err := parseDump(data)
+	if err != nil {
+		log.Fatal("Couldn't read dump ", path, ": ", err)
+	}
+
+	bundleFiles = statsSetFiles(s)
+}
@@ -0,0 +1,64 @@
+// Test file for dump.go
+package main
+
+import (
+	"testing"
+)
+
+func TestParseDump(t *testing.T) {
+
+	raw := "------------------------------------------------------------------------\n" +
+		"ZFS Subsystem Report                            Thu Oct 15 10:56:49 2026\n" +
+		"Host:     nas\n\n" +
+		"ARCSTATS:\n" +
+		"\thits                                              1000\n" +
+		"\tl2_size                                           18446744073709551615\n\n" +
+		"ZIL:\n" +
+		"\t(skipped: no permission to read /proc/spl/kstat/zfs/zil)\n\n" +
+		"DERIVED:\n" +
+		"\tratio                                             0.5\n\n" +
+		"TUNABLES:\n" +
+		"\t# Max size of ARC in bytes\n" +
+		"\tzfs_arc_max                                       0\n" +
+		"\tzfs_vdev_scheduler                                [noop] none\n"
+
+	s, err := parseDump([]byte(raw))
+	if err != nil {
+		t.Fatalf("parseDump(raw) = %v", err)
+	}
+
+	if v := s.Kstats["arcstats"]["hits"]; v != 1000 {
+		t.Errorf("arcstats.hits = %d (wanted \"1000\")", v)
+	}
+
+	if v := s.Kstats["arcstats"]["l2_size"]; v != 18446744073709551615 {
+		t.Errorf("arcstats.l2_size = %d (wanted \"18446744073709551615\")", v)
+	}
+
+	if _, ok := s.Kstats["zil"]; ok {
+		t.Errorf("skipped section zil = %v (wanted none)", s.Kstats["zil"])
+	}
+
+	if _, ok := s.Kstats["derived"]; ok {
+		t.Errorf("derived metrics were read as kstats")
+	}
+
+	if v := s.Tunables["zfs_vdev_scheduler"]; v != "[noop] none" {
+		t.Errorf("zfs_vdev_scheduler = %q (wanted \"[noop] none\")", v)
+	}
+
+	if len(s.Tunables) != 2 {
+		t.Errorf("tunables = %v (wanted 2)", s.Tunables)
+	}
+
+	js := `{"time": "2026-10-15T10:00:00Z", "kstats": {"arcstats": {"hits": 5}}, "tunables": {}}`
+	if s, err := parseDump([]byte(js)); err != nil || s.Kstats["arcstats"]["hits"] != 5 {
+		t.Errorf("parseDump(json) = %v, %v (wanted hits 5)", s, err)
+	}
+
+	for _, bad := range []string{"", "junk\n", "{}", "ARCSTATS:\n\thits lots\n"} {
+		if _, err := parseDump([]byte(bad)); err == nil {
+			t.Errorf("parseDump(%q) succeeded (wanted error)", bad)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Encryption overhead section for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// Encrypted datasets cost memory and CPU. The ARC keeps the blocks of
+// encrypted datasets decrypted, but sometimes also needs them as they are on
+// disk, for example for raw sends or the L2ARC, and then holds an encrypted
+// copy as well, counted in arc_raw_size. The CPU time goes to the ICP,
+// whose operations we put in relation to the uptime. Only pools where the
+// encryption feature is active are affected; there are no kstats for the
+// time spent encrypting. Linux only. See arc_summary.go for the license
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseEncryptionFeature takes the output of "zpool get -H -o name,value
+// feature@encryption" and returns the pools where the feature is active,
+// which means they have encrypted datasets
+func parseEncryptionFeature(out string) []string {
+
+	var pools []string
+
+	input := bufio.NewScanner(strings.NewReader(out))
+
+	for input.Scan() {
+		fields := strings.Fields(input.Text())
+		if len(fields) == 2 && fields[1] == "active" {
+			pools = append(pools, fields[0])
+		}
+	}
+
+	return pools
+}
+
+// opsPerSecond returns the average rate of a counter since boot
+func opsPerSecond(count string, uptime float64) (float64, bool) {
+
+	v, err := strconv.ParseUint(count, 10, 64)
+	if err != nil || uptime <= 0 {
+		return 0, false
+	}
+
+	return float64(v) / uptime, true
+}
+
+// printEncryption displays what encryption costs the ARC and the CPU
+func printEncryption() {
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	out, err := runCommand(ctx, "zpool", "get", "-H", "-o", "name,value", "feature@encryption")
+	if err != nil {
+		skipSection("encryption", fmt.Errorf("couldn't run 'zpool get': %v", err))
+		return
+	}
+
+	pools := parseEncryptionFeature(string(out))
+
+	var names []string
+	for _, p := range pools {
+		names = append(names, redactName(p))
+	}
+
+	if len(names) == 0 {
+		prtL1("Pools with encrypted datasets:", "none")
+		explain(explainEncryption(false, 0, 0))
+		return
+	}
+
+	prtL1("Pools with encrypted datasets:", strings.Join(names, ", "))
+
+	var raw, size uint64
+
+	if _, ok := kstats["arcstats"]; ok {
+		var arcStats = make(map[string]string)
+		procSection("arcstats", arcStats)
+
+		var hasRaw bool
+		raw, hasRaw = statValue(arcStats, "arc_raw_size")
+		size, _ = statValue(arcStats, "size")
+
+		if hasRaw {
+			r, s := strconv.FormatUint(raw, 10), strconv.FormatUint(size, 10)
+			prtL1p("Encrypted copies in ARC:", fPerc(r, s), fBytes(r))
+		}
+	}
+
+	prtL1("Crypto (ICP):", "")
+
+	var impls []string
+	for _, i := range icpImpls {
+		if data, err := readFile(ctx, icpParamsPath+"/"+i.param); err == nil {
+			active, available := parseImpl(string(data))
+			impls = append(impls, fImpl(active, available))
+		}
+	}
+	if len(impls) > 0 {
+		prtL2("AES / GCM implementation:", strings.Join(impls, " / "))
+	}
+
+	var rate float64
+
+	if kcf, err := readKCFStats(ctx); err == nil {
+		if uptime, err := readUptime(ctx); err == nil {
+			var ok bool
+			if rate, ok = opsPerSecond(kcf["kcf_ops_total"], uptime); ok {
+				prtL2("Operations per second (since boot):", fmt.Sprintf("%.1f", rate))
+			}
+		}
+		if v, ok := kcf["kcf_ops_failed"]; ok && v != "0" {
+			prtL2p("Failed operations:", fPerc(v, kcf["kcf_ops_total"]), fHits(v))
+		}
+	}
+
+	explain(explainEncryption(true, raw, size))
+}
@@ -0,0 +1,49 @@
+// Test file for encryption.go
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseEncryptionFeature(t *testing.T) {
+
+	var tests = []struct {
+		input  string
+		wanted []string
+	}{
+		{"tank\tactive\nbackup\tenabled\n", []string{"tank"}},
+		{"tank\tactive\nbackup\tactive\n", []string{"tank", "backup"}},
+		{"old\tdisabled\n", nil},
+		{"old\t-\n", nil},
+		{"", nil},
+	}
+
+	for _, test := range tests {
+		if got := parseEncryptionFeature(test.input); !reflect.DeepEqual(got, test.wanted) {
+			t.Errorf("parseEncryptionFeature(%q) = %v (wanted \"%v\")", test.input, got, test.wanted)
+		}
+	}
+}
+
+func TestOpsPerSecond(t *testing.T) {
+
+	var tests = []struct {
+		count  string
+		uptime float64
+		wanted float64
+		ok     bool
+	}{
+		{"1000", 100, 10, true},
+		{"0", 100, 0, true},
+		{"1000", 0, 0, false},
+		{"lots", 100, 0, false},
+	}
+
+	for _, test := range tests {
+		got, ok := opsPerSecond(test.count, test.uptime)
+		if got != test.wanted || ok != test.ok {
+			t.Errorf("opsPerSecond(%v, %v) = %v, %v (wanted \"%v, %v\")", test.count, test.uptime, got, ok, test.wanted, test.ok)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+// Device error counters for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// The READ, WRITE and CKSUM columns of "zpool status" count the I/O errors
+// of each device since the pool was imported or the errors were cleared. The
+// errors section adds them up for each pool and top-level vdev, so a single
+// line says if the pool is clean, and lists every device whose counters are
+// not zero. Counters of a vdev that has children are errors the redundancy
+// couldn't repair; they are shown on their own instead of being added to
+// those of the devices. See arc_summary.go for the license
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// noDataErrors is what zpool status says when there are no permanent errors
+const noDataErrors = "No known data errors"
+
+// errorCounts are the read, write and checksum errors of a device
+type errorCounts struct {
+	read, write, cksum uint64
+}
+
+func (c errorCounts) add(o errorCounts) errorCounts {
+	return errorCounts{c.read + o.read, c.write + o.write, c.cksum + o.cksum}
+}
+
+func (c errorCounts) zero() bool {
+	return c.read == 0 && c.write == 0 && c.cksum == 0
+}
+
+func (c errorCounts) String() string {
+	return fmt.Sprintf("%d / %d / %d", c.read, c.write, c.cksum)
+}
+
+// vdevErrors is a line of the config part of zpool status. Depth is 0 for
+// the pool, 1 for top-level vdevs and so on. Leaf is true for devices
+// without children
+type vdevErrors struct {
+	name   string
+	state  string
+	depth  int
+	leaf   bool
+	counts errorCounts
+}
+
+// poolErrors is what zpool status says about the errors of a pool. Data is
+// the "errors:" line about permanent errors in files
+type poolErrors struct {
+	pool  string
+	data  string
+	vdevs []vdevErrors
+}
+
+// parseErrorCount reads an error counter. Large counts are abbreviated with
+// the same units as sizes, eg "1.2K"
+func parseErrorCount(s string) (uint64, bool) {
+
+	mult := 1.0
+
+	if idx := strings.IndexByte("KMGTPE", s[len(s)-1]); idx != -1 {
+		s = s[:len(s)-1]
+		for i := 0; i <= idx; i++ {
+			mult *= 1024
+		}
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 {
+		return 0, false
+	}
+
+	return uint64(v * mult), true
+}
+
+// parseVdevLine returns the counters of the fields of a line of the config
+// part, eg "/dev/sda1  ONLINE  0  0  0". Headers such as "logs" and spares
+// don't have counters
+func parseVdevLine(fields []string) (errorCounts, bool) {
+
+	var c errorCounts
+
+	if len(fields) < 5 {
+		return c, false
+	}
+
+	var ok [3]bool
+	c.read, ok[0] = parseErrorCount(fields[2])
+	c.write, ok[1] = parseErrorCount(fields[3])
+	c.cksum, ok[2] = parseErrorCount(fields[4])
+
+	return c, ok[0] && ok[1] && ok[2]
+}
+
+// parseZpoolErrors takes the output of "zpool status" and returns the error
+// counters of each pool in the order of the output. The depth of a vdev
+// comes from its indentation, two spaces per level
+func parseZpoolErrors(out string) []poolErrors {
+
+	var result []poolErrors
+	var p *poolErrors
+	inConfig := false
+	base := 0
+
+	input := bufio.NewScanner(strings.NewReader(out))
+
+	for input.Scan() {
+		raw := strings.TrimLeft(input.Text(), "\t")
+		line := strings.TrimSpace(raw)
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch {
+		case fields[0] == "pool:" && len(fields) > 1:
+			result = append(result, poolErrors{pool: fields[1]})
+			p = &result[len(result)-1]
+			inConfig = false
+			continue
+		case p == nil:
+			continue
+		case fields[0] == "config:":
+			inConfig = true
+			continue
+		case fields[0] == "errors:":
+			p.data = strings.TrimSpace(strings.TrimPrefix(line, "errors:"))
+			inConfig = false
+			continue
+		case !inConfig || fields[0] == "NAME":
+			continue
+		}
+
+		c, ok := parseVdevLine(fields)
+		if !ok {
+			continue
+		}
+
+		spaces := len(raw) - len(strings.TrimLeft(raw, " "))
+		if len(p.vdevs) == 0 {
+			base = spaces
+		}
+
+		depth := (spaces - base) / 2
+		if n := len(p.vdevs); n > 0 && p.vdevs[n-1].depth >= depth {
+			p.vdevs[n-1].leaf = true
+		}
+
+		p.vdevs = append(p.vdevs, vdevErrors{name: fields[0], state: fields[1], depth: depth, counts: c})
+	}
+
+	for i := range result {
+		if n := len(result[i].vdevs); n > 1 {
+			result[i].vdevs[n-1].leaf = true
+		}
+	}
+
+	return result
+}
+
+// leafTotal returns the sum of the counters of the devices below the vdev
+// at index i, or of the vdev itself if it is a device
+func (p poolErrors) leafTotal(i int) errorCounts {
+
+	var total errorCounts
+
+	for j := i; j < len(p.vdevs); j++ {
+		if j > i && p.vdevs[j].depth <= p.vdevs[i].depth {
+			break
+		}
+		if p.vdevs[j].leaf {
+			total = total.add(p.vdevs[j].counts)
+		}
+	}
+
+	return total
+}
+
+// clean says if no counter of the pool is above zero and there are no
+// permanent errors
+func (p poolErrors) clean() bool {
+
+	for _, v := range p.vdevs {
+		if !v.counts.zero() {
+			return false
+		}
+	}
+
+	return p.data == "" || p.data == noDataErrors
+}
+
+// printErrors displays the error counters of every pool
+func printErrors() {
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	out, err := runCommand(ctx, "zpool", "status", "-P")
+	if err != nil {
+		skipSection("errors", fmt.Errorf("couldn't run 'zpool status': %v", err))
+		return
+	}
+
+	clean := true
+
+	for _, p := range parseZpoolErrors(string(out)) {
+		fmt.Fprintf(reportOut, "\nPool %s:\n", redactName(p.pool))
+
+		if len(p.vdevs) == 0 {
+			prtL1("Read / write / checksum errors:", "unknown")
+			continue
+		}
+
+		prtL1("Read / write / checksum errors:", p.leafTotal(0).String())
+
+		for i, v := range p.vdevs {
+			if v.depth == 1 {
+				prtL2(redactPath(v.name)+":", p.leafTotal(i).String())
+			}
+		}
+
+		for _, v := range p.vdevs {
+			if v.counts.zero() {
+				continue
+			}
+			kind := "device"
+			if !v.leaf {
+				kind = "vdev, not repaired"
+			}
+			addWarning("errors", "%s: %s (%s, %s) has %s errors",
+				redactName(p.pool), redactPath(v.name), v.state, kind, v.counts)
+		}
+
+		if p.data != "" && p.data != noDataErrors {
+			addWarning("errors", "%s: %s", redactName(p.pool), p.data)
+		}
+
+		if !p.clean() {
+			clean = false
+		}
+	}
+
+	explain(explainErrors(clean))
+}
@@ -0,0 +1,100 @@
+// Test file for errors.go
+package main
+
+import "testing"
+
+const zpoolErrorsData = `  pool: tank
+ state: DEGRADED
+config:
+
+	NAME           STATE     READ WRITE CKSUM
+	tank           DEGRADED     0     0     0
+	  mirror-0     ONLINE       0     0     0
+	    /dev/sda1  ONLINE       0     0     0
+	    /dev/sdb1  ONLINE       0     0     2
+	  raidz1-1     DEGRADED     0     0     1
+	    /dev/sdc1  FAULTED      3  1.5K     0  too many errors
+	    /dev/sdd1  ONLINE       0     0     0
+	logs
+	  /dev/nvme0n1p1  ONLINE    0     0     0
+	spares
+	  /dev/sde1    AVAIL
+
+errors: 1 data errors, use '-v' for a list
+
+  pool: backup
+ state: ONLINE
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	backup      ONLINE       0     0     0
+	  /dev/sdf  ONLINE       0     0     0
+
+errors: No known data errors
+`
+
+func TestParseErrorCount(t *testing.T) {
+
+	var tests = []struct {
+		input  string
+		wanted uint64
+		ok     bool
+	}{
+		{"0", 0, true},
+		{"17", 17, true},
+		{"1.5K", 1536, true},
+		{"2M", 2 << 20, true},
+		{"-", 0, false},
+		{"ONLINE", 0, false},
+	}
+
+	for _, test := range tests {
+		got, ok := parseErrorCount(test.input)
+		if got != test.wanted || ok != test.ok {
+			t.Errorf("parseErrorCount(%q) = %v, %v (wanted \"%v, %v\")", test.input, got, ok, test.wanted, test.ok)
+		}
+	}
+}
+
+func TestParseZpoolErrors(t *testing.T) {
+
+	pools := parseZpoolErrors(zpoolErrorsData)
+	if len(pools) != 2 {
+		t.Fatalf("parseZpoolErrors() = %d pools (wanted \"2\")", len(pools))
+	}
+
+	tank := pools[0]
+	if len(tank.vdevs) != 8 {
+		t.Fatalf("parseZpoolErrors() tank = %d vdevs (wanted \"8\")", len(tank.vdevs))
+	}
+
+	var tests = []struct {
+		index  int
+		name   string
+		depth  int
+		leaf   bool
+		wanted errorCounts
+	}{
+		{0, "tank", 0, false, errorCounts{3, 1536, 2}},
+		{1, "mirror-0", 1, false, errorCounts{0, 0, 2}},
+		{4, "raidz1-1", 1, false, errorCounts{3, 1536, 0}},
+		{5, "/dev/sdc1", 2, true, errorCounts{3, 1536, 0}},
+		{7, "/dev/nvme0n1p1", 1, true, errorCounts{}},
+	}
+
+	for _, test := range tests {
+		v := tank.vdevs[test.index]
+		got := tank.leafTotal(test.index)
+		if v.name != test.name || v.depth != test.depth || v.leaf != test.leaf || got != test.wanted {
+			t.Errorf("parseZpoolErrors() vdev %d = %s, %d, %v, %v (wanted \"%s, %d, %v, %v\")",
+				test.index, v.name, v.depth, v.leaf, got, test.name, test.depth, test.leaf, test.wanted)
+		}
+	}
+
+	if tank.clean() {
+		t.Errorf("parseZpoolErrors() tank is clean (wanted errors)")
+	}
+	if !pools[1].clean() || !pools[1].vdevs[1].leaf {
+		t.Errorf("parseZpoolErrors() backup = %v (wanted clean with one device)", pools[1])
+	}
+}
@@ -0,0 +1,328 @@
+// Explanations for the report of arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// With -explain, the major items of the report are followed by a short note
+// on what the numbers mean on this system. The notes are for people who know
+// what the ARC is but not what a healthy one looks like. See -describe for
+// the meaning of single stats. See arc_summary.go for the license
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// explainPrefix starts every line of an explanation
+const explainPrefix = "> "
+
+// explain prints an explanation below the current item if -explain is set
+func explain(text string) {
+
+	if !*OptExplain || text == "" {
+		return
+	}
+
+	if *OptPlain {
+		printPlain("Explanation", "", text)
+		return
+	}
+
+	printNote(text)
+}
+
+// printNote prints text indented, marked and wrapped to the report width
+func printNote(text string) {
+
+	width := lineLen - displayWidth(indent+explainPrefix)
+	for _, l := range strings.Split(strings.TrimSuffix(wrapText(text, width), "\n"), "\n") {
+		fmt.Fprintln(reportOut, indent+explainPrefix+l)
+	}
+}
+
+// explainHealth explains the ARC health as returned by arcHealth
+func explainHealth(health string) string {
+
+	if health == "THROTTLED" {
+		return "ZFS had to slow down writes because the system ran short of memory. " +
+			"Applications and the ARC are competing for RAM; consider lowering zfs_arc_max or adding memory."
+	}
+
+	return "ZFS never had to slow down writes for lack of memory since boot."
+}
+
+// explainReclaim explains the memory reclaim indicators. The number of direct
+// reclaims since the last run is only known with -state
+func explainReclaim(noGrow bool, needFree, direct uint64, directKnown bool) string {
+
+	switch {
+	case needFree > 0:
+		return "The kernel asked the ARC to give back memory and it hasn't finished yet. " +
+			"Applications are competing with the ARC for RAM right now."
+	case noGrow:
+		return "Free memory is low, so the ARC may not grow until the kernel has enough again. " +
+			"This is normal for short periods, but if it lasts the ARC is kept too small to be useful."
+	case directKnown && direct > 0:
+		return "Allocations had to wait for the ARC to free memory since the last run. " +
+			"Lowering zfs_arc_max leaves more room for applications and avoids these stalls."
+	}
+
+	return "Reclaims count how often the kernel took memory back from the ARC: directly when an " +
+		"allocation had to wait, indirectly in the background. A rising direct count means memory pressure."
+}
+
+// explainBalloon explains what to do about memory ballooning in a VM
+func explainBalloon(balloon string) string {
+
+	if balloon == "" {
+		return "This system runs in a virtual machine without a balloon driver, so the memory it " +
+			"sees is the memory it has."
+	}
+
+	return "The host can take memory back from this VM at any time, and the ARC may be too slow " +
+		"to shrink. Pin zfs_arc_max well below the memory the VM is guaranteed, set zfs_arc_min " +
+		"so the ARC isn't squeezed to nothing, and raise zfs_arc_sys_free to keep room for the balloon."
+}
+
+// explainVMSysctls explains how the kernel VM settings interact with the ARC
+func explainVMSysctls(values map[string]uint64, memTotal uint64) string {
+
+	var notes []string
+
+	if v, ok := values["min_free_kbytes"]; ok && memTotal > 0 {
+		perc := float64(v*1024) / float64(memTotal) * 100
+		note := fmt.Sprintf("The kernel starts reclaiming, and asks the ARC to shrink, when free memory "+
+			"gets close to vm.min_free_kbytes, %.1f %% of RAM here.", perc)
+		if perc > 5 {
+			note += " That is a lot, so the ARC is asked to shrink early."
+		}
+		notes = append(notes, note)
+	}
+
+	if v, ok := values["swappiness"]; ok {
+		note := "vm.swappiness balances swapping out programs against dropping caches, and the ARC " +
+			"is asked to shrink along with the page cache."
+		if v <= 10 {
+			note += " With a value this low, most of the pressure falls on the caches, ARC included."
+		}
+		notes = append(notes, note)
+	}
+
+	notes = append(notes, "The vm.dirty_* settings only apply to the page cache. ZFS limits its own dirty data "+
+		"with zfs_dirty_data_max, so they matter for other filesystems and files on ZFS that are mapped "+
+		"into memory, whose dirty pages compete with the ARC for RAM.")
+
+	return strings.Join(notes, " ")
+}
+
+// explainZIL explains what the write paths and devices of the ZIL say about
+// logbias and the SLOG
+func explainZIL(itxs, indirect, copied, normal, slog uint64) string {
+
+	switch {
+	case itxs == 0:
+		return "Nothing was written to the intent log since boot, so there were no sync writes."
+	case slog == 0 && normal > 0 && copied > indirect:
+		return "Sync writes are logged to the normal pool devices, mostly with their data copied into the " +
+			"log. A fast SLOG device would take these writes off the pool and lower the latency of fsync."
+	case slog > 0 && normal > slog:
+		return "There is a SLOG, but most log blocks still go to the normal pool devices. Datasets with " +
+			"logbias=throughput and large writes bypass the SLOG, and a full SLOG falls back to the pool."
+	case indirect > copied:
+		return "Most logged data is written indirectly, straight into the pool with only a pointer in the " +
+			"log, as happens with large writes and logbias=throughput. A SLOG helps these writes little."
+	}
+
+	return "Log blocks go where they should. Copied writes are small sync writes whose data is in the " +
+		"log itself, indirect ones point to data written straight into the pool."
+}
+
+// explainTxgs explains the txg timing of the pools whose syncs took longer
+// than zfs_txg_timeout, and what the write throttle did about it
+func explainTxgs(slow []string, delays uint64) string {
+
+	if len(slow) == 0 {
+		return "Every txg was synced within zfs_txg_timeout, so the disks keep up with the writes."
+	}
+
+	text := fmt.Sprintf("Syncing took longer than zfs_txg_timeout on %s, so the disks can't keep up with "+
+		"the writes. Dirty data piles up until the write throttle slows down writers", strings.Join(slow, ", "))
+
+	if delays > 0 {
+		return text + fmt.Sprintf(", which happened %d times (dmu_tx_dirty_delay). See zfs_dirty_data_max "+
+			"and zfs_delay_min_dirty_percent.", delays)
+	}
+
+	return text + ". See zfs_dirty_data_max and zfs_delay_min_dirty_percent."
+}
+
+// explainScans explains how running scrubs, resilvers and trims affect the
+// rest of the report
+func explainScans(active bool) string {
+
+	if !active {
+		return "No scrub, resilver or trim is running, so the cache stats show the normal workload."
+	}
+
+	return "Scrubs and resilvers read the data of the pool without keeping it in the ARC, but the " +
+		"metadata they walk through goes through it, and they compete with normal reads for the " +
+		"disks. Expect slower misses and more metadata in the ARC until they are done."
+}
+
+// explainErrors explains the device error counters. Clean is true if no pool
+// has any errors
+func explainErrors(clean bool) string {
+
+	if clean {
+		return "No device has reported an I/O or checksum error, so everything the ARC reads " +
+			"from disk arrives as it was written."
+	}
+
+	return "Read and write errors are I/O errors of the device, checksum errors are blocks that " +
+		"came back different from what was written. Errors of single devices are repaired from " +
+		"the other copies; errors of a vdev could not be, and show up as permanent errors in " +
+		"files. A few checksum errors often come from cables or controllers; clear them with " +
+		"'zpool clear' and see if they return, and replace devices whose counters keep growing."
+}
+
+// explainEncryption explains what encryption costs on this system. Raw is
+// the size of the encrypted copies in the ARC, size that of the ARC
+func explainEncryption(encrypted bool, raw, size uint64) string {
+
+	if !encrypted {
+		return "No pool has encrypted datasets, so encryption costs nothing here."
+	}
+
+	text := "Blocks of encrypted datasets are decrypted when they are read from disk and encrypted " +
+		"when written, which costs CPU time in the ICP; with AES-NI this is rarely the bottleneck."
+
+	if size > 0 && raw*10 > size {
+		text += fmt.Sprintf(" The ARC also keeps encrypted copies of some blocks, for raw sends or "+
+			"the L2ARC, which take %d %% of its memory.", 100*raw/size)
+	}
+
+	return text
+}
+
+// explainARCSize explains the size of the ARC compared to its target and
+// maximum
+func explainARCSize(size, c, cMax uint64) string {
+
+	if cMax == 0 {
+		return ""
+	}
+
+	switch {
+	case c < cMax/2:
+		return fmt.Sprintf("The target size is only %d %% of the maximum: the kernel has taken memory "+
+			"back from the ARC. Memory pressure from applications keeps the ARC small.", 100*c/cMax)
+	case size < cMax/2:
+		return "The ARC is well below its maximum but free to grow. This is normal after a reboot " +
+			"or when there is little I/O, as the ARC only grows when data is read or written."
+	}
+
+	return "The ARC is close to its maximum size, as expected on a system that has been busy for a while."
+}
+
+// explainMFU explains the split between the MRU and MFU lists
+func explainMFU(mru, mfu uint64) string {
+
+	if mru+mfu == 0 {
+		return ""
+	}
+
+	text := "MRU holds data that was read once, MFU data that was read more than once. "
+
+	if mfu >= mru {
+		return text + "Most of the ARC is MFU, so the same data is read again and again: a stable working set that caches well."
+	}
+
+	return text + "Most of the ARC is MRU, so much data is read only once, as with backups, " +
+		"scans or streaming. Such workloads profit little from a larger ARC."
+}
+
+// explainLimits explains the configured ARC limits
+func explainLimits() string {
+	return "The limits come from zfs_arc_min and zfs_arc_max. 0 means ZFS picks the value, " +
+		"which is usually what you want."
+}
+
+// explainVDEV explains the vdev cache stats
+func explainVDEV() string {
+	return "The vdev cache is off by default and was removed in OpenZFS 2.2. " +
+		"These numbers only mean something if zfs_vdev_cache_size is set."
+}
+
+// explainTunables explains the origin column of the tunables
+func explainTunables() string {
+	return "Tunables 'changed at runtime' are lost at the next reboot unless they are also set in " +
+		modprobePath + ". 'default' means nobody touched them."
+}
+
+// explainPinned explains the part of the ARC that can't be evicted
+func explainPinned(pinned, size uint64) string {
+
+	if size == 0 {
+		return ""
+	}
+
+	text := "Buffers that are in use, dirty or belong to the headers can't be evicted. "
+
+	if 100*pinned/size > pinnedWarning {
+		return text + "Most of the ARC is in this state, which is why it doesn't shrink when memory runs short. " +
+			"Large amounts of metadata held by dnodes and dbufs are the usual cause."
+	}
+
+	return text + "Most of the ARC can be evicted, so it will give memory back when applications need it."
+}
+
+// explainFM says what lost error reports mean
+func explainFM(lost uint64) string {
+
+	if lost == 0 {
+		return "No error reports were lost, so zed and 'zpool events' saw every fault ZFS found."
+	}
+
+	return fmt.Sprintf("%d error reports were lost, usually because too many came at once (eg a failing "+
+		"disk). Check 'zpool status -v' and the kernel log, since zed can't have acted on them.", lost)
+}
+
+// explainZstd says what errors and fallbacks of zstd mean
+func explainZstd(failures uint64, fallback string) string {
+
+	text := "Blocks that fail to compress are simply written uncompressed, so errors here cost space, not data."
+	if failures == 0 {
+		text = "Zstd hasn't run into any errors."
+	}
+
+	if fallback != "" && fallback != "0" {
+		text += " The fallback buffer is used when memory is tight; it works, but one block at a time."
+	}
+
+	return text
+}
+
+// explainICP explains the crypto implementations
+func explainICP(slow []string) string {
+
+	if len(slow) > 0 {
+		return "Encryption uses the slow generic code for " + strings.Join(slow, " and ") +
+			". Use 'fastest' instead unless you are debugging the ICP."
+	}
+
+	return "'fastest' means the ICP benchmarked the implementations when it was loaded and uses the " +
+		"best one. Encrypted datasets are only as fast as this choice."
+}
+
+// explainMirror says what the split of mirror reads means
+func explainMirror(rotating, nonRotating uint64) string {
+
+	text := "Each read from a mirror goes to one child. Seeks are what the balancing tries to avoid on spinning disks."
+
+	if rotating > 0 && nonRotating > 0 && rotating > nonRotating {
+		text += " Most reads go to spinning disks although there are SSDs; raising " +
+			"zfs_vdev_mirror_rotating_seek_inc or lowering zfs_vdev_mirror_non_rotating_seek_inc " +
+			"moves more of them to the SSDs."
+	}
+
+	return text
+}
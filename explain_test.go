@@ -0,0 +1,42 @@
+// Test file for explain.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExplainARCSize(t *testing.T) {
+
+	var tests = []struct {
+		size, c, cMax uint64
+		want          string
+	}{
+		{0, 0, 0, ""},
+		{10, 20, 100, "The target size is only 20 %"},
+		{10, 90, 100, "The ARC is well below its maximum"},
+		{90, 95, 100, "The ARC is close to its maximum"},
+	}
+
+	for _, test := range tests {
+		got := explainARCSize(test.size, test.c, test.cMax)
+		if !strings.HasPrefix(got, test.want) {
+			t.Errorf("explainARCSize(%d, %d, %d) = %v (wanted \"%v...\")", test.size, test.c, test.cMax, got, test.want)
+		}
+	}
+}
+
+func TestExplainMFU(t *testing.T) {
+
+	if got := explainMFU(0, 0); got != "" {
+		t.Errorf("explainMFU(0, 0) = %v (wanted \"\")", got)
+	}
+
+	if got := explainMFU(1, 3); !strings.Contains(got, "stable working set") {
+		t.Errorf("explainMFU(1, 3) = %v (wanted stable working set)", got)
+	}
+
+	if got := explainMFU(3, 1); !strings.Contains(got, "read only once") {
+		t.Errorf("explainMFU(3, 1) = %v (wanted read only once)", got)
+	}
+}
@@ -0,0 +1,242 @@
+// Expression evaluation for derived metrics in arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// Expressions are simple arithmetic over stat names, eg
+// "hits/(hits+misses)*100". Bare names refer to arcstats, other sections are
+// accessed with a prefix, eg "zfetchstats.hits". See arc_summary.go for the
+// license
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const defaultExprSection = "arcstats"
+
+// exprParser is a simple recursive descent parser that evaluates the
+// expression while it is being parsed. Grammar:
+//
+//	expr   = term { ("+" | "-") term }
+//	term   = factor { ("*" | "/") factor }
+//	factor = number | name | "(" expr ")" | "-" factor
+type exprParser struct {
+	tokens []string
+	pos    int
+	lookup func(string) (float64, error)
+}
+
+// tokenizeExpr splits an expression into numbers, names, operators and
+// parens
+func tokenizeExpr(s string) ([]string, error) {
+
+	var tokens []string
+	runes := []rune(s)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case strings.ContainsRune("+-*/()", r):
+			tokens = append(tokens, string(r))
+			i++
+		case unicode.IsDigit(r) || r == '.':
+			j := i
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, string(runes[i:j]))
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(runes) && isNameRune(runes[j]) {
+				j++
+			}
+			tokens = append(tokens, string(runes[i:j]))
+			i = j
+		default:
+			return nil, fmt.Errorf("illegal character '%c' in expression", r)
+		}
+	}
+
+	return tokens, nil
+}
+
+// isNameRune tests if the rune can be part of a stat name. The dot separates
+// the section from the name
+func isNameRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.'
+}
+
+// evalExpr parses and evaluates an expression, using lookup to convert stat
+// names to values
+func evalExpr(s string, lookup func(string) (float64, error)) (float64, error) {
+
+	tokens, err := tokenizeExpr(s)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(tokens) == 0 {
+		return 0, fmt.Errorf("empty expression")
+	}
+
+	p := &exprParser{tokens: tokens, lookup: lookup}
+
+	result, err := p.expr()
+	if err != nil {
+		return 0, err
+	}
+
+	if p.pos < len(p.tokens) {
+		return 0, fmt.Errorf("unexpected '%s' in expression", p.tokens[p.pos])
+	}
+
+	return result, nil
+}
+
+// peek returns the current token without consuming it, or an empty string
+// at the end of the expression
+func (p *exprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *exprParser) expr() (float64, error) {
+
+	result, err := p.term()
+	if err != nil {
+		return 0, err
+	}
+
+	for p.peek() == "+" || p.peek() == "-" {
+		op := p.peek()
+		p.pos++
+
+		value, err := p.term()
+		if err != nil {
+			return 0, err
+		}
+
+		if op == "+" {
+			result += value
+		} else {
+			result -= value
+		}
+	}
+
+	return result, nil
+}
+
+func (p *exprParser) term() (float64, error) {
+
+	result, err := p.factor()
+	if err != nil {
+		return 0, err
+	}
+
+	for p.peek() == "*" || p.peek() == "/" {
+		op := p.peek()
+		p.pos++
+
+		value, err := p.factor()
+		if err != nil {
+			return 0, err
+		}
+
+		if op == "*" {
+			result *= value
+			continue
+		}
+
+		if value == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		result /= value
+	}
+
+	return result, nil
+}
+
+func (p *exprParser) factor() (float64, error) {
+
+	t := p.peek()
+	p.pos++
+
+	switch {
+	case t == "":
+		return 0, fmt.Errorf("unexpected end of expression")
+	case t == "-":
+		value, err := p.factor()
+		return -value, err
+	case t == "(":
+		value, err := p.expr()
+		if err != nil {
+			return 0, err
+		}
+		if p.peek() != ")" {
+			return 0, fmt.Errorf("missing ')' in expression")
+		}
+		p.pos++
+		return value, nil
+	case unicode.IsDigit(rune(t[0])) || t[0] == '.':
+		value, err := strconv.ParseFloat(t, 64)
+		if err != nil {
+			return 0, fmt.Errorf("bad number '%s' in expression", t)
+		}
+		return value, nil
+	case unicode.IsLetter(rune(t[0])) || t[0] == '_':
+		return p.lookup(t)
+	}
+
+	return 0, fmt.Errorf("unexpected '%s' in expression", t)
+}
+
+// lookupStat returns the value of a stat from kstats for use in expressions.
+// Names without a section prefix are taken from arcstats. Stats the kernel
+// doesn't have are computed from their aliases
+func lookupStat(name string) (float64, error) {
+
+	section, stat := defaultExprSection, name
+
+	if idx := strings.Index(name, "."); idx != -1 {
+		section, stat = name[:idx], name[idx+1:]
+	}
+
+	if _, ok := kstats[section]; !ok {
+		return 0, fmt.Errorf("unknown section '%s'", section)
+	}
+
+	lookup := func(n string) (float64, error) {
+		if v, ok := kstatValue(section, n); ok {
+			return strconv.ParseFloat(v, 64)
+		}
+		return 0, fmt.Errorf("unknown stat '%s' in section '%s'", n, section)
+	}
+
+	if _, ok := kstatValue(section, stat); !ok {
+		if v, ok := aliasValue(section, stat, lookup); ok {
+			return v, nil
+		}
+	}
+
+	return lookup(stat)
+}
+
+// kstatValue returns the value of a stat of a kstat file as it was read
+func kstatValue(file, name string) (string, bool) {
+
+	for _, l := range kstats[file] {
+		if n, v := cleanProcLine(l); n == name {
+			return v, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,55 @@
+// Test file for expr.go
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEvalExpr(t *testing.T) {
+	stats := map[string]float64{
+		"hits":             90,
+		"misses":           10,
+		"zfetchstats.hits": 5,
+	}
+
+	lookup := func(name string) (float64, error) {
+		v, ok := stats[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown stat %s", name)
+		}
+		return v, nil
+	}
+
+	var tests = []struct {
+		have string
+		want float64
+	}{
+		{"1", 1},
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"-4 + 10", 6},
+		{"0.5 * 4", 2},
+		{"hits/(hits+misses)*100", 90},
+		{"zfetchstats.hits - 1", 4},
+	}
+
+	for _, test := range tests {
+		got, err := evalExpr(test.have, lookup)
+		if err != nil {
+			t.Errorf("evalExpr(%s) returned error: %v", test.have, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("evalExpr(%s) = %v (wanted \"%v\")", test.have, got, test.want)
+		}
+	}
+
+	var bad = []string{"", "1 +", "(1 + 2", "1 / 0", "nosuchstat", "2 $ 3", "1 2"}
+
+	for _, b := range bad {
+		if _, err := evalExpr(b, lookup); err == nil {
+			t.Errorf("evalExpr(%s) did not return an error", b)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Fault management section for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// When ZFS finds a checksum error or a failing device, it posts an error
+// report (ereport) that zed turns into mails and hot spare actions. The fm
+// kstat counts the reports that were lost on the way, which otherwise goes
+// unnoticed: a pool can be in trouble without anybody being told. See
+// arc_summary.go for the license
+package main
+
+// fmCounters are the counters of the fm kstat with their labels. Any of them
+// above zero means error reports were lost or incomplete
+var fmCounters = []struct{ stat, label string }{
+	{"erpt-dropped", "Error reports dropped:"},
+	{"erpt-set-failed", "Error reports incomplete:"},
+	{"fmri-set-failed", "Device names (FMRI) not set:"},
+	{"payload-set-failed", "Payloads not set:"},
+	{"erpt-duplicates", "Duplicate reports suppressed:"},
+}
+
+// fmLost returns the number of error reports that were lost or damaged.
+// Duplicates are suppressed on purpose and don't count, older versions of
+// ZFS don't have all counters
+func fmLost(fmStats map[string]string) uint64 {
+
+	var lost uint64
+
+	for _, c := range fmCounters {
+		if v, ok := fmStats[c.stat]; ok && c.stat != "erpt-duplicates" {
+			lost += stringToUint64(v)
+		}
+	}
+
+	return lost
+}
+
+// printFM displays the statistics of ZFS fault management
+func printFM() {
+
+	var fmStats = make(map[string]string)
+	procSection("fm", fmStats)
+
+	lost := fmLost(fmStats)
+
+	status := "OK"
+	if lost > 0 {
+		status = "REPORTS LOST"
+		addWarning("fm", "%d ZFS error reports were lost or incomplete, zed may not have seen every fault", lost)
+	}
+
+	prtL1("Error reports:", status)
+
+	for _, c := range fmCounters {
+		if v, ok := fmStats[c.stat]; ok {
+			prtL2(c.label, fHits(v))
+		}
+	}
+
+	explain(explainFM(lost))
+}
@@ -0,0 +1,24 @@
+// Test file for fm.go
+package main
+
+import (
+	"testing"
+)
+
+func TestFMLost(t *testing.T) {
+
+	var tests = []struct {
+		stats  map[string]string
+		wanted uint64
+	}{
+		{map[string]string{}, 0},
+		{map[string]string{"erpt-dropped": "0", "erpt-duplicates": "12"}, 0},
+		{map[string]string{"erpt-dropped": "3", "erpt-set-failed": "1", "erpt-duplicates": "12"}, 4},
+	}
+
+	for _, test := range tests {
+		if got := fmLost(test.stats); got != test.wanted {
+			t.Errorf("fmLost(%v) = %d (wanted \"%d\")", test.stats, got, test.wanted)
+		}
+	}
+}
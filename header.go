@@ -0,0 +1,130 @@
+// System context for the report header of arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// A report that is looked at weeks later should say where it came from. The
+// header lists the host, the kernel, the ZFS and SPL module versions, the
+// uptime and the imported pools. Anything that can't be read is left out.
+// See arc_summary.go for the license
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	osReleasePath  = "/proc/sys/kernel/osrelease"
+	zfsVersionPath = "/sys/module/zfs/version"
+	splVersionPath = "/sys/module/spl/version"
+)
+
+// systemContext is what the header says about the system
+type systemContext struct {
+	host   string
+	kernel string
+	zfs    string
+	spl    string
+	uptime float64
+	pools  []string
+}
+
+// readFirstLine returns the first line of a file, or an empty string if it
+// can't be read
+func readFirstLine(ctx context.Context, path string) string {
+
+	data, err := readFile(ctx, path)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(strings.SplitN(string(data), "\n", 2)[0])
+}
+
+// readSystemContext collects the system context for the header
+func readSystemContext(ctx context.Context) systemContext {
+
+	sc := systemContext{
+		host:   readFirstLine(ctx, hostnamePath),
+		kernel: readFirstLine(ctx, osReleasePath),
+		zfs:    readFirstLine(ctx, zfsVersionPath),
+		spl:    readFirstLine(ctx, splVersionPath),
+	}
+
+	if uptime, err := readUptime(ctx); err == nil {
+		sc.uptime = uptime
+	}
+
+	if out, err := runCommand(ctx, "zpool", "list", "-H", "-o", "name"); err == nil {
+		sc.pools = strings.Fields(string(out))
+	}
+
+	return sc
+}
+
+// fUptime returns a number of seconds as days, hours and minutes
+func fUptime(seconds float64) string {
+
+	s := int64(seconds)
+	days, hours, minutes := s/86400, s%86400/3600, s%3600/60
+
+	if days == 0 {
+		return fmt.Sprintf("%d:%02d", hours, minutes)
+	}
+
+	unit := "days"
+	if days == 1 {
+		unit = "day"
+	}
+
+	return fmt.Sprintf("%d %s, %d:%02d", days, unit, hours, minutes)
+}
+
+// headerLines returns the label and value of each header line. Names are
+// redacted if -redact is given
+func headerLines(sc systemContext) [][2]string {
+
+	var lines [][2]string
+
+	add := func(label, value string) {
+		if value != "" {
+			lines = append(lines, [2]string{label, value})
+		}
+	}
+
+	add("Host:", redactName(sc.host))
+	add("Kernel:", sc.kernel)
+
+	zfs := sc.zfs
+	if sc.spl != "" && sc.spl != sc.zfs {
+		zfs += " (SPL " + sc.spl + ")"
+	}
+	add("ZFS:", zfs)
+
+	if sc.uptime > 0 {
+		add("Uptime:", fUptime(sc.uptime))
+	}
+
+	var pools []string
+	for _, p := range sc.pools {
+		pools = append(pools, redactName(p))
+	}
+	add("Pools:", strings.Join(pools, ", "))
+
+	return lines
+}
+
+// printSystemContext prints the system context below the title of the header
+func printSystemContext() {
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	for _, l := range headerLines(readSystemContext(ctx)) {
+		if *OptPlain {
+			printPlain(l[0], "", l[1])
+			continue
+		}
+		fmt.Fprintf(reportOut, "%-10s%s\n", l[0], l[1])
+	}
+}
@@ -0,0 +1,54 @@
+// Test file for header.go
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFUptime(t *testing.T) {
+
+	var tests = []struct {
+		have float64
+		want string
+	}{
+		{59, "0:00"},
+		{3661.5, "1:01"},
+		{86400, "1 day, 0:00"},
+		{3*86400 + 4*3600 + 5*60, "3 days, 4:05"},
+	}
+
+	for _, test := range tests {
+		if got := fUptime(test.have); got != test.want {
+			t.Errorf("fUptime(%v) = %v (wanted \"%v\")", test.have, got, test.want)
+		}
+	}
+}
+
+func TestHeaderLines(t *testing.T) {
+
+	sc := systemContext{
+		host:   "server",
+		zfs:    "2.2.0-1",
+		spl:    "2.2.0-1",
+		uptime: 90,
+		pools:  []string{"tank", "backup"},
+	}
+
+	got := headerLines(sc)
+	want := [][2]string{
+		{"Host:", "server"},
+		{"ZFS:", "2.2.0-1"},
+		{"Uptime:", "0:01"},
+		{"Pools:", "tank, backup"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("headerLines(%v) = %v (wanted \"%v\")", sc, got, want)
+	}
+
+	sc.spl = "2.1.5-1"
+	if got := headerLines(sc)[1][1]; got != "2.2.0-1 (SPL 2.1.5-1)" {
+		t.Errorf("headerLines with different SPL = %v (wanted \"2.2.0-1 (SPL 2.1.5-1)\")", got)
+	}
+}
@@ -0,0 +1,90 @@
+// Highlighting of changed values in watch mode for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// With -highlight, watch mode shows rows whose value changed since the last
+// refresh in bold and dims the rows that haven't changed once since watch
+// mode started, so the few busy counters stand out among the static ones.
+// Rows are told apart by their label and how often that label came up
+// before in the same refresh, since labels like "Hits:" show up in several
+// sections. See arc_summary.go for the license
+package main
+
+import (
+	"fmt"
+)
+
+const (
+	ansiBold  = "\033[1m"
+	ansiDim   = "\033[2m"
+	ansiReset = "\033[0m"
+)
+
+// rowHighlighter remembers the values of the rows between refreshes
+type rowHighlighter struct {
+	refreshes int
+	prev      map[string]string
+	cur       map[string]string
+	seen      map[string]int
+	changed   map[string]bool // rows that changed at least once
+}
+
+// highlight is set in watch mode with -highlight, nil otherwise
+var highlight *rowHighlighter
+
+// begin starts a new refresh
+func (h *rowHighlighter) begin() {
+	h.cur = make(map[string]string)
+	h.seen = make(map[string]int)
+	if h.changed == nil {
+		h.changed = make(map[string]bool)
+	}
+}
+
+// end finishes a refresh, whose values are then compared with the next one
+func (h *rowHighlighter) end() {
+	h.prev = h.cur
+	h.refreshes++
+}
+
+// style returns the escape sequence a row is printed with, if any. Nothing is
+// highlighted on the first refresh, since there is nothing to compare with
+func (h *rowHighlighter) style(r layoutRow) string {
+
+	key := fmt.Sprintf("%s#%d", r.label, h.seen[r.label])
+	h.seen[r.label]++
+
+	value := r.perc + " " + r.value
+	h.cur[key] = value
+
+	if h.refreshes == 0 {
+		return ""
+	}
+
+	old, ok := h.prev[key]
+	switch {
+	case !ok:
+		return ""
+	case old != value:
+		h.changed[key] = true
+		return ansiBold
+	case !h.changed[key]:
+		return ansiDim
+	}
+
+	return ""
+}
+
+// highlightRow returns the line of a row with the escape sequences for its
+// highlighting
+func highlightRow(r layoutRow, line string) string {
+
+	if highlight == nil {
+		return line
+	}
+
+	if s := highlight.style(r); s != "" {
+		return s + line + ansiReset
+	}
+
+	return line
+}
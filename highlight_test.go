@@ -0,0 +1,41 @@
+// Test file for highlight.go
+package main
+
+import (
+	"testing"
+)
+
+func TestRowHighlighter(t *testing.T) {
+
+	var h rowHighlighter
+
+	refresh := func(rows ...layoutRow) []string {
+		var styles []string
+		h.begin()
+		for _, r := range rows {
+			styles = append(styles, h.style(r))
+		}
+		h.end()
+		return styles
+	}
+
+	// The second "Hits:" is a different row than the first
+	var tests = []struct {
+		rows   []layoutRow
+		wanted []string
+	}{
+		{[]layoutRow{{2, "Hits:", "", "1"}, {2, "Size:", "", "5"}, {2, "Hits:", "", "7"}}, []string{"", "", ""}},
+		{[]layoutRow{{2, "Hits:", "", "2"}, {2, "Size:", "", "5"}, {2, "Hits:", "", "7"}}, []string{ansiBold, ansiDim, ansiDim}},
+		{[]layoutRow{{2, "Hits:", "", "2"}, {2, "Size:", "", "5"}, {2, "Hits:", "", "8"}}, []string{"", ansiDim, ansiBold}},
+		{[]layoutRow{{2, "Hits:", "", "2"}, {2, "Size:", "", "5"}, {2, "Hits:", "", "8"}, {2, "New:", "", "1"}}, []string{"", ansiDim, "", ""}},
+	}
+
+	for i, test := range tests {
+		got := refresh(test.rows...)
+		for j := range got {
+			if got[j] != test.wanted[j] {
+				t.Errorf("refresh %d, row %d (%s) = %q (wanted \"%q\")", i+1, j, test.rows[j].label, got[j], test.wanted[j])
+			}
+		}
+	}
+}
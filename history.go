@@ -0,0 +1,269 @@
+// History recording and display for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// With -history, every set of stats collected is appended to a file with one
+// JSON record per line. The "history" subcommand reads this file back. See
+// arc_summary.go for the license
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultHistoryPath = "/var/lib/arc_summary/history.ndjson"
+	historyWidth       = 60
+	historyHeight      = 12
+)
+
+// historyRecord is one sample in the history file. Stats are keyed by
+// section and name, eg "arcstats.hits", and kept as strings so no precision
+// is lost on 64 bit counters. Seq is the number of the sample in watch and
+// serve mode
+type historyRecord struct {
+	Time  int64             `json:"time"`
+	Seq   int64             `json:"seq,omitempty"`
+	Stats map[string]string `json:"stats"`
+}
+
+// flattenKstats returns all stats in kstats in a single map keyed by
+// section and name, eg "arcstats.hits"
+func flattenKstats() map[string]string {
+
+	m := make(map[string]string)
+
+	for section, lines := range kstats {
+		for _, l := range lines {
+			name, value := cleanProcLine(l)
+			m[section+"."+name] = value
+		}
+	}
+
+	return m
+}
+
+// appendHistory adds the current stats as a new record to the history file
+func appendHistory(path string) {
+
+	if err := maybeRotateHistory(path); err != nil {
+		log.Fatal("Couldn't rotate history file ", path, ": ", err)
+	}
+
+	f, err := openOutput(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY)
+	if err != nil {
+		log.Fatal("Couldn't open history file ", path, ": ", err)
+	}
+
+	rec := historyRecord{Time: time.Now().Unix(), Seq: sampleSeq, Stats: flattenKstats()}
+
+	if err := json.NewEncoder(f).Encode(rec); err != nil {
+		f.Close()
+		log.Fatal("Couldn't write history file ", path, ": ", err)
+	}
+
+	if err := f.Close(); err != nil {
+		log.Fatal("Couldn't write history file ", path, ": ", err)
+	}
+}
+
+// readHistory returns all records in the history file and its rotated old
+// files that are not older than since, and the number of corrupt lines that
+// were skipped
+func readHistory(path string, since time.Time) ([]historyRecord, int, error) {
+
+	var records []historyRecord
+	corrupt := 0
+
+	for _, fn := range historyFiles(path) {
+		recs, bad, err := readHistoryFile(fn, since)
+		if err != nil {
+			return nil, 0, err
+		}
+		records = append(records, recs...)
+		corrupt += bad
+	}
+
+	return records, corrupt, nil
+}
+
+// readHistoryFile returns the records of a single history file that are not
+// older than since. Lines that aren't records, such as one cut short by a
+// crash while writing, are skipped and counted
+func readHistoryFile(path string, since time.Time) ([]historyRecord, int, error) {
+
+	f, err := openInput(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	var records []historyRecord
+	corrupt := 0
+
+	input := bufio.NewScanner(f)
+	input.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for input.Scan() {
+		var rec historyRecord
+
+		if err := json.Unmarshal(input.Bytes(), &rec); err != nil {
+			corrupt++
+			continue
+		}
+
+		if rec.Time >= since.Unix() {
+			records = append(records, rec)
+		}
+	}
+
+	return records, corrupt, input.Err()
+}
+
+// cmdHistory handles the "history" subcommand. Currently only "history graph
+// <stat>" is supported
+func cmdHistory(args []string) {
+
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	since := fs.Duration("since", 24*time.Hour, "Only show samples this recent")
+
+	if len(args) < 2 || args[0] != "graph" {
+		log.Fatal("Usage: arc_summary [-history file] history graph <stat> [-since 24h]")
+	}
+
+	stat := args[1]
+	fs.Parse(args[2:])
+
+	// Names without a section are taken from arcstats as with -expr
+	if !strings.Contains(stat, ".") {
+		stat = defaultExprSection + "." + stat
+	}
+
+	path := *OptHistory
+	if path == "" {
+		path = defaultHistoryPath
+	}
+
+	records, corrupt, err := readHistory(path, time.Now().Add(-*since))
+	if err != nil {
+		log.Fatal("Couldn't read history: ", err)
+	}
+
+	if corrupt > 0 {
+		log.Printf("WARNING: skipped %d corrupt records in %s", corrupt, path)
+	}
+
+	var values []float64
+	found := false
+
+	for _, rec := range records {
+		v, err := strconv.ParseFloat(rec.Stats[stat], 64)
+		if err != nil {
+			v = math.NaN()
+		} else {
+			found = true
+		}
+		values = append(values, v)
+	}
+
+	if !found {
+		log.Fatal("No samples for ", stat, " in the last ", *since)
+	}
+
+	first := time.Unix(records[0].Time, 0).Format(time.RFC3339)
+	last := time.Unix(records[len(records)-1].Time, 0).Format(time.RFC3339)
+
+	fmt.Fprintf(reportOut, "\n%s (%d samples, %s to %s)\n\n", stat, len(values), first, last)
+
+	for _, l := range asciiGraph(values, historyWidth, historyHeight) {
+		fmt.Fprintln(reportOut, indent+l)
+	}
+}
+
+// asciiGraph draws values as a simple line chart with the given number of
+// columns and rows, with the y axis labelled with the largest and smallest
+// value. If there are more values than columns, each column shows the
+// average of its values. Missing values (NaN) are skipped
+func asciiGraph(values []float64, width, height int) []string {
+
+	if len(values) < width {
+		width = len(values)
+	}
+
+	columns := make([]float64, width)
+
+	for c := range columns {
+		start := c * len(values) / width
+		end := (c + 1) * len(values) / width
+
+		sum, n := 0.0, 0
+		for _, v := range values[start:end] {
+			if !math.IsNaN(v) {
+				sum += v
+				n++
+			}
+		}
+
+		columns[c] = math.NaN()
+		if n > 0 {
+			columns[c] = sum / float64(n)
+		}
+	}
+
+	min, max := math.Inf(1), math.Inf(-1)
+
+	for _, v := range columns {
+		if !math.IsNaN(v) {
+			min = math.Min(min, v)
+			max = math.Max(max, v)
+		}
+	}
+
+	grid := make([][]rune, height)
+	for r := range grid {
+		grid[r] = []rune(strings.Repeat(" ", width))
+	}
+
+	for c, v := range columns {
+		if math.IsNaN(v) {
+			continue
+		}
+
+		row := 0
+		if max > min {
+			row = int((v - min) / (max - min) * float64(height-1))
+		}
+		grid[height-1-row][c] = '*'
+	}
+
+	maxLabel := strconv.FormatFloat(max, 'f', -1, 64)
+	minLabel := strconv.FormatFloat(min, 'f', -1, 64)
+	labelWidth := len(maxLabel)
+	if len(minLabel) > labelWidth {
+		labelWidth = len(minLabel)
+	}
+
+	lines := make([]string, height+1)
+
+	for r := range grid {
+		label := ""
+		switch r {
+		case 0:
+			label = maxLabel
+		case height - 1:
+			label = minLabel
+		}
+		lines[r] = fmt.Sprintf("%*s |%s", labelWidth, label, string(grid[r]))
+	}
+	lines[height] = fmt.Sprintf("%*s +%s", labelWidth, "", strings.Repeat("-", width))
+
+	return lines
+}
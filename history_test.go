@@ -0,0 +1,92 @@
+// Test file for history.go
+package main
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAsciiGraph(t *testing.T) {
+	got := asciiGraph([]float64{1, 2, 3}, 60, 3)
+	want := []string{
+		"3 |  *",
+		"  | * ",
+		"1 |*  ",
+		"  +---",
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("asciiGraph returned %d lines (wanted %d)", len(got), len(want))
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("asciiGraph line %d = \"%v\" (wanted \"%v\")", i, got[i], want[i])
+		}
+	}
+
+	// More values than columns are averaged, missing values skipped
+	got = asciiGraph([]float64{0, 2, math.NaN(), 10}, 2, 2)
+	want = []string{
+		"10 | *",
+		" 1 |* ",
+		"   +--",
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("asciiGraph line %d = \"%v\" (wanted \"%v\")", i, got[i], want[i])
+		}
+	}
+}
+
+func TestReadHistory(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "arc_summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "history.ndjson")
+	data := `{"time": 100, "stats": {"arcstats.hits": "1"}}
+{"time": 200, "stats": {"arcstats.hits": "2"}}
+not a record
+{"time": 300, "stats": {"arcstats.hits": "3"}}
+{"time": 400, "stats": {"arcst`
+
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		since   int64
+		hits    []string
+		corrupt int
+	}{
+		{0, []string{"1", "2", "3"}, 2},
+		{200, []string{"2", "3"}, 2},
+		{500, nil, 2},
+	}
+
+	for _, test := range tests {
+		records, corrupt, err := readHistory(path, time.Unix(test.since, 0))
+		if err != nil {
+			t.Fatalf("readHistory(since %d) = %v", test.since, err)
+		}
+
+		var hits []string
+		for _, r := range records {
+			hits = append(hits, r.Stats["arcstats.hits"])
+		}
+
+		if !reflect.DeepEqual(hits, test.hits) || corrupt != test.corrupt {
+			t.Errorf("readHistory(since %d) = %v, %d corrupt (wanted \"%v, %d\")", test.since, hits, corrupt, test.hits, test.corrupt)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// ICP crypto section for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// Encrypted datasets use the Illumos Crypto Port (ICP) module. How fast they
+// are depends mostly on which AES and GCM implementations it picked: the
+// generic ones are many times slower than those using AES-NI or AVX. The
+// choice is in /sys/module/icp/parameters, where the active one is shown in
+// brackets, and the kcf kstat counts the crypto operations. Linux only. See
+// arc_summary.go for the license
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	icpParamsPath = "/sys/module/icp/parameters"
+	kcfStatsPath  = "/proc/spl/kstat/kcf/NONAME_provider_stats"
+)
+
+// icpImpls are the parameters that select an implementation
+var icpImpls = []struct{ param, label string }{
+	{"icp_aes_impl", "AES implementation:"},
+	{"icp_gcm_impl", "GCM implementation:"},
+}
+
+// icpPseudoImpls aren't implementations but ways to choose one
+var icpPseudoImpls = map[string]bool{"cycle": true, "fastest": true}
+
+// parseImpl takes the contents of an implementation parameter, eg "cycle
+// [fastest] generic x86_64 aesni", and returns the active choice and the
+// real implementations available
+func parseImpl(s string) (string, []string) {
+
+	var active string
+	var available []string
+
+	for _, f := range strings.Fields(s) {
+		name := strings.Trim(f, "[]")
+		if name != f {
+			active = name
+		}
+		if !icpPseudoImpls[name] {
+			available = append(available, name)
+		}
+	}
+
+	return active, available
+}
+
+// fImpl formats an implementation parameter for the report
+func fImpl(active string, available []string) string {
+
+	if active == "" {
+		return "n/a"
+	}
+
+	if icpPseudoImpls[active] && len(available) > 0 {
+		return fmt.Sprintf("%s (of %s)", active, strings.Join(available, ", "))
+	}
+
+	return active
+}
+
+// slowImpl returns true if the generic implementation was chosen by hand
+// although a faster one is available
+func slowImpl(active string, available []string) bool {
+	return active == "generic" && len(available) > 1
+}
+
+// readKCFStats returns the crypto operation counters of the kcf kstat
+func readKCFStats(ctx context.Context) (map[string]string, error) {
+
+	data, err := readFile(ctx, kcfStatsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	kcf := make(map[string]string)
+	lines := strings.Split(string(bytes.TrimSpace(data)), "\n")
+	if len(lines) > 2 {
+		for _, l := range lines[2:] {
+			name, value := cleanProcLine(l)
+			kcf[name] = value
+		}
+	}
+
+	return kcf, nil
+}
+
+// printICP displays the crypto implementations and operations of the ICP
+func printICP() {
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	if _, err := readDirNames(ctx, icpParamsPath); err != nil {
+		skipSection("icp", fmt.Errorf("couldn't read %s: %v", icpParamsPath, err))
+		return
+	}
+
+	var slow []string
+
+	prtL1("Crypto implementations:", "")
+	for _, i := range icpImpls {
+		data, err := readFile(ctx, icpParamsPath+"/"+i.param)
+		if err != nil {
+			prtL2(i.label, "n/a")
+			continue
+		}
+		active, available := parseImpl(string(data))
+		prtL2(i.label, fImpl(active, available))
+		if slowImpl(active, available) {
+			slow = append(slow, i.param)
+		}
+	}
+
+	for _, p := range slow {
+		addWarning("icp", "%s is set to generic although faster implementations are available", p)
+	}
+
+	kcf, err := readKCFStats(ctx)
+	if err != nil {
+		explain(explainICP(slow))
+		return
+	}
+
+	if total, ok := kcf["kcf_ops_total"]; ok {
+		prtL1("Crypto operations:", fHits(total))
+		for _, c := range []struct{ stat, label string }{
+			{"kcf_ops_passed", "Passed:"},
+			{"kcf_ops_failed", "Failed:"},
+			{"kcf_ops_returned_busy", "Returned busy:"},
+		} {
+			if v, ok := kcf[c.stat]; ok {
+				prtL2p(c.label, fPerc(v, total), fHits(v))
+			}
+		}
+	}
+
+	explain(explainICP(slow))
+}
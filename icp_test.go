@@ -0,0 +1,36 @@
+// Test file for icp.go
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseImpl(t *testing.T) {
+
+	var tests = []struct {
+		in        string
+		active    string
+		available []string
+		formatted string
+		slow      bool
+	}{
+		{"cycle [fastest] generic x86_64 aesni\n", "fastest", []string{"generic", "x86_64", "aesni"}, "fastest (of generic, x86_64, aesni)", false},
+		{"cycle fastest [generic] avx pclmulqdq", "generic", []string{"generic", "avx", "pclmulqdq"}, "generic", true},
+		{"cycle fastest [generic]", "generic", []string{"generic"}, "generic", false},
+		{"", "", nil, "n/a", false},
+	}
+
+	for _, test := range tests {
+		active, available := parseImpl(test.in)
+		if active != test.active || !reflect.DeepEqual(available, test.available) {
+			t.Errorf("parseImpl(%q) = %s, %v (wanted \"%s, %v\")", test.in, active, available, test.active, test.available)
+		}
+		if got := fImpl(active, available); got != test.formatted {
+			t.Errorf("fImpl(%q) = %s (wanted \"%s\")", test.in, got, test.formatted)
+		}
+		if got := slowImpl(active, available); got != test.slow {
+			t.Errorf("slowImpl(%q) = %v (wanted \"%v\")", test.in, got, test.slow)
+		}
+	}
+}
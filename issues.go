@@ -0,0 +1,152 @@
+// Known issues for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// Some stats point straight to a known problem, such as checksum errors on
+// the L2ARC to a failing cache device. Each rule evaluates an expression
+// with the syntax of -expr and matches when the result lies outside of the
+// range the rule allows. Counters only ever grow, so rules about events such
+// as throttling divide them by "hours", the time the counters have been
+// running, and stop matching once the events are long past. Matches are
+// printed at the top of the report. Users can add their own rules in the
+// "rules" list of the configuration file. See arc_summary.go for the license
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// knownIssue is a rule for a known problem. It matches if the value of expr
+// is below min or above max
+type knownIssue struct {
+	name    string
+	expr    string
+	min     float64
+	max     float64
+	problem string
+}
+
+// issueRule is a rule as given in the configuration file. Missing limits
+// don't limit
+type issueRule struct {
+	Name    string   `json:"name"`
+	Expr    string   `json:"expr"`
+	Min     *float64 `json:"min"`
+	Max     *float64 `json:"max"`
+	Problem string   `json:"problem"`
+}
+
+// knownIssues are the rules that come with arc_summary
+var knownIssues = []knownIssue{
+	{"L2ARC checksum errors", "l2_cksum_bad", 0, 0,
+		"Reads from the cache device failed their checksum. The device is probably failing; check its SMART data and replace it."},
+	{"L2ARC I/O errors", "l2_io_error", 0, 0,
+		"Reads from the cache device failed. The device or its connection is probably failing."},
+	{"Memory throttling", "memory_throttle_count/hours", 0, 1,
+		"ZFS often had to slow down writes for lack of memory. Lower zfs_arc_max or add RAM."},
+	{"ARC at its minimum", "c/c_min", 1.1, math.Inf(1),
+		"The kernel has pushed the ARC down to zfs_arc_min. Something else needs the memory, and the ARC can't cache much."},
+	{"L2ARC headers crowd the ARC", "l2_hdr_size/size*100", 0, 10,
+		"More than a tenth of the ARC holds headers for the L2ARC. The L2ARC is too large for this much RAM, or the record size is small."},
+	{"Metadata doesn't fit", exprDemandMetaRatio, 80, 100,
+		"Less than 80 % of metadata reads hit the ARC. Directory listings and file opens go to disk; the ARC is too small for the number of files."},
+	{"Write throttle", "dmu_tx.dmu_tx_dirty_throttle/hours", 0, 1,
+		"Writes often had to stop because dirty data reached its limit. The pool can't keep up with the writers."},
+}
+
+// rules returns the built-in rules followed by those from the configuration
+// file
+func rules() []knownIssue {
+
+	result := append([]knownIssue{}, knownIssues...)
+
+	for _, r := range cfg.Rules {
+		ki := knownIssue{name: r.Name, expr: r.Expr, min: math.Inf(-1), max: math.Inf(1), problem: r.Problem}
+		if r.Min != nil {
+			ki.min = *r.Min
+		}
+		if r.Max != nil {
+			ki.max = *r.Max
+		}
+		result = append(result, ki)
+	}
+
+	return result
+}
+
+// issueMatch is a rule that matched with the value that made it match
+type issueMatch struct {
+	issue knownIssue
+	value float64
+}
+
+// matchIssues returns the rules that match. Rules whose stats don't exist,
+// such as the L2ARC ones on systems without, or that divide by zero are
+// skipped
+func matchIssues(issues []knownIssue, lookup func(string) (float64, error)) []issueMatch {
+
+	var result []issueMatch
+
+	for _, ki := range issues {
+		v, err := evalExpr(ki.expr, lookup)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		if v < ki.min || v > ki.max {
+			result = append(result, issueMatch{ki, v})
+		}
+	}
+
+	return result
+}
+
+// counterHours returns how many hours the counters of the ZFS module have
+// been running, from the kstat header of arcstats or else the uptime
+func counterHours() (float64, error) {
+
+	if h, ok := kstatHeaders["arcstats"]; ok {
+		return (h.snaptime - h.crtime).Hours(), nil
+	}
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	uptime, err := readUptime(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return uptime / 3600, nil
+}
+
+// issueLookup looks up stats for the rules, which can also use "hours"
+func issueLookup(name string) (float64, error) {
+
+	if name == "hours" {
+		return counterHours()
+	}
+
+	return lookupStat(name)
+}
+
+// printKnownIssues prints the rules that match, if any
+func printKnownIssues() {
+
+	matches := matchIssues(rules(), issueLookup)
+	if len(matches) == 0 {
+		return
+	}
+
+	printTitle("known issues")
+
+	for _, m := range matches {
+		value := strconv.FormatFloat(m.value, 'f', -1, 64)
+		if *OptPlain {
+			printPlain(m.issue.name, "", m.issue.problem+" ("+m.issue.expr+" = "+value+")")
+			continue
+		}
+		fmt.Fprintf(reportOut, "\n%sWARNING: %s (%s = %s)\n", indent, m.issue.name, m.issue.expr, value)
+		printNote(m.issue.problem)
+	}
+}
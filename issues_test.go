@@ -0,0 +1,79 @@
+// Test file for issues.go
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMatchIssues(t *testing.T) {
+
+	stats := map[string]float64{
+		"l2_cksum_bad": 3,
+		"c":            100,
+		"c_min":        100,
+		"size":         0,
+		"l2_hdr_size":  5,
+		"hours":        100,
+		"throttles":    50,
+		"dirty":        500,
+	}
+
+	lookup := func(name string) (float64, error) {
+		if v, ok := stats[name]; ok {
+			return v, nil
+		}
+		return 0, fmt.Errorf("unknown stat '%s'", name)
+	}
+
+	issues := []knownIssue{
+		{"checksum", "l2_cksum_bad", 0, 0, ""},
+		{"minimum", "c/c_min", 1.1, math.Inf(1), ""},
+		{"fine", "c", 0, 1000, ""},
+		{"missing", "nosuch", 0, 0, ""},
+		{"division by zero", "l2_hdr_size/size*100", 0, 10, ""},
+		{"old throttles", "throttles/hours", 0, 1, ""},
+		{"recent throttles", "dirty/hours", 0, 1, ""},
+	}
+
+	var got []string
+	for _, m := range matchIssues(issues, lookup) {
+		got = append(got, m.issue.name)
+	}
+
+	want := []string{"checksum", "minimum", "recent throttles"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("matchIssues = %v (wanted \"%v\")", got, want)
+	}
+}
+
+func TestRules(t *testing.T) {
+
+	defer func(old []issueRule) { cfg.Rules = old }(cfg.Rules)
+
+	max := 5.0
+	cfg.Rules = []issueRule{{Name: "mine", Expr: "deleted", Max: &max, Problem: "too many"}}
+
+	r := rules()
+	if len(r) != len(knownIssues)+1 {
+		t.Fatalf("rules() returned %d rules (wanted %d)", len(r), len(knownIssues)+1)
+	}
+
+	mine := r[len(r)-1]
+	if mine.max != 5 || !math.IsInf(mine.min, -1) {
+		t.Errorf("rules() converted %v to %v (wanted min -Inf, max 5)", cfg.Rules[0], mine)
+	}
+}
+
+func TestCounterHours(t *testing.T) {
+
+	defer delete(kstatHeaders, "arcstats")
+
+	kstatHeaders["arcstats"] = kstatHeader{crtime: time.Hour, snaptime: 3 * time.Hour}
+
+	if got, err := issueLookup("hours"); err != nil || got != 2 {
+		t.Errorf("issueLookup(hours) = %v, %v (wanted \"2\")", got, err)
+	}
+}
@@ -0,0 +1,96 @@
+// Jitter and rate limits for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// When hundreds of hosts start watch or serve mode at the same time, they
+// would all collect in the same second forever after. -jitter moves every
+// interval by a random amount of up to half the given duration either way, so
+// the hosts drift apart while the average interval stays the same. In serve
+// mode, the first collection waits up to the full duration. -exec-rate limits
+// how many external commands such as zpool and modinfo are started per
+// second, for sites where these hit shared infrastructure. See arc_summary.go
+// for the license
+package main
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// jittered returns the interval moved by a random amount of up to half the
+// jitter either way
+func jittered(interval, jitter time.Duration) time.Duration {
+
+	if jitter <= 0 {
+		return interval
+	}
+
+	d := interval + time.Duration(rand.Int63n(int64(jitter))) - jitter/2
+	if d < 0 {
+		return 0
+	}
+
+	return d
+}
+
+// firstDelay returns a random delay of up to jitter before the first
+// collection
+func firstDelay(jitter time.Duration) time.Duration {
+
+	if jitter <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(jitter)))
+}
+
+// rateLimiter spaces out events so no more than rate of them happen per
+// second. A rate of zero means no limit
+type rateLimiter struct {
+	mu   sync.Mutex
+	next time.Time
+}
+
+// execLimit is the limit for external commands
+var execLimit rateLimiter
+
+// reserve returns how long the caller has to wait for its turn at time now
+// and books that turn
+func (r *rateLimiter) reserve(now time.Time, rate float64) time.Duration {
+
+	if rate <= 0 {
+		return 0
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.next.Before(now) {
+		r.next = now
+	}
+
+	wait := r.next.Sub(now)
+	r.next = r.next.Add(time.Duration(float64(time.Second) / rate))
+
+	return wait
+}
+
+// wait blocks until it is the turn of the caller or ctx is done
+func (r *rateLimiter) wait(ctx context.Context) error {
+
+	d := r.reserve(time.Now(), *OptExecRate)
+	if d == 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
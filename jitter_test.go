@@ -0,0 +1,76 @@
+// Test file for jitter.go
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJittered(t *testing.T) {
+
+	if got := jittered(time.Minute, 0); got != time.Minute {
+		t.Errorf("jittered(1m, 0) = %v (wanted 1m)", got)
+	}
+
+	var tests = []struct {
+		interval, jitter time.Duration
+		min, max         time.Duration
+	}{
+		{time.Minute, 10 * time.Second, 55 * time.Second, 65 * time.Second},
+		{time.Second, 10 * time.Second, 0, 6 * time.Second},
+	}
+
+	for _, test := range tests {
+		below, above := false, false
+		for i := 0; i < 1000; i++ {
+			got := jittered(test.interval, test.jitter)
+			if got < test.min || got >= test.max {
+				t.Fatalf("jittered(%v, %v) = %v (wanted %v to %v)", test.interval, test.jitter, got, test.min, test.max)
+			}
+			below = below || got < test.interval
+			above = above || got > test.interval
+		}
+		if !below || !above {
+			t.Errorf("jittered(%v, %v) only moved one way", test.interval, test.jitter)
+		}
+	}
+}
+
+func TestFirstDelay(t *testing.T) {
+
+	if got := firstDelay(0); got != 0 {
+		t.Errorf("firstDelay(0) = %v (wanted 0)", got)
+	}
+
+	for i := 0; i < 100; i++ {
+		if got := firstDelay(10 * time.Second); got < 0 || got >= 10*time.Second {
+			t.Fatalf("firstDelay(10s) = %v (wanted 0 to 10s)", got)
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+
+	var r rateLimiter
+	now := time.Date(2017, 6, 15, 10, 0, 0, 0, time.UTC)
+
+	var tests = []struct {
+		at   time.Duration
+		want time.Duration
+	}{
+		{0, 0},
+		{0, 500 * time.Millisecond},
+		{100 * time.Millisecond, 900 * time.Millisecond},
+		{5 * time.Second, 0},
+	}
+
+	for _, test := range tests {
+		if got := r.reserve(now.Add(test.at), 2); got != test.want {
+			t.Errorf("reserve(+%v) = %v (wanted %v)", test.at, got, test.want)
+		}
+	}
+
+	if got := r.reserve(now, 0); got != 0 {
+		t.Errorf("reserve() without limit = %v (wanted 0)", got)
+	}
+}
@@ -0,0 +1,30 @@
+// Reading kstats on macOS and FreeBSD
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// See arc_summary.go for the license
+
+//go:build darwin || freebsd
+// +build darwin freebsd
+
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// readKstat returns the contents of a kstat file, eg "arcstats". Bundles
+// hold the kstats in the Linux format wherever they were made
+func readKstat(ctx context.Context, name string) ([]byte, error) {
+
+	if bundleFiles != nil {
+		return readFile(ctx, procPath+name)
+	}
+
+	out, err := runCommand(ctx, "sysctl", sysctlKstatPrefix+name)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't run 'sysctl %s': %v", sysctlKstatPrefix+name, err)
+	}
+
+	return sysctlToKstat(out, name), nil
+}
@@ -0,0 +1,16 @@
+// Reading kstats from /proc
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// See arc_summary.go for the license
+
+//go:build !windows && !darwin && !freebsd
+// +build !windows,!darwin,!freebsd
+
+package main
+
+import "context"
+
+// readKstat returns the contents of a kstat file, eg "arcstats"
+func readKstat(ctx context.Context, name string) ([]byte, error) {
+	return readFile(ctx, procPath+name)
+}
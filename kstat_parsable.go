@@ -0,0 +1,62 @@
+// Conversion of kstats from "kstat -p"
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// OpenZFS on Windows keeps the kstats in the driver and comes with a port of
+// the Solaris kstat tool to read them. Its parsable output has one line per
+// stat such as "zfs:0:arcstats:hits	12345", which we turn into the format of
+// the Linux kstat files. See arc_summary.go for the license
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parsableKstatPrefix is the module and instance of the ZFS kstats
+const parsableKstatPrefix = "zfs:0:"
+
+// parsableToKstat takes the output of "kstat -p zfs:0:<name>" and returns it
+// in the format of /proc/spl/kstat/zfs/<name>, including the two header
+// lines, and the number of stats found. The kstat tool adds the class and
+// the times of the kstat itself, which aren't numbers or not counters and
+// are skipped
+func parsableToKstat(out []byte, name string) ([]byte, int) {
+
+	var b bytes.Buffer
+	prefix := parsableKstatPrefix + name + ":"
+	n := 0
+
+	fmt.Fprintf(&b, "0 1 0x01 0 0 0 0\n")
+	fmt.Fprintf(&b, "%-32s%-5s%s\n", "name", "type", "data")
+
+	input := bufio.NewScanner(bytes.NewReader(out))
+
+	for input.Scan() {
+		fields := strings.Fields(input.Text())
+		if len(fields) != 2 || !strings.HasPrefix(fields[0], prefix) {
+			continue
+		}
+
+		stat := fields[0][len(prefix):]
+		if stat == "" || stat == "crtime" || stat == "snaptime" {
+			continue
+		}
+
+		if _, ok := parseKstatValue(fields[1]); !ok {
+			continue
+		}
+
+		typ := "4"
+		if _, err := strconv.ParseUint(fields[1], 10, 64); err != nil {
+			typ = "3"
+		}
+
+		fmt.Fprintf(&b, "%-32s%-5s%s\n", stat, typ, fields[1])
+		n++
+	}
+
+	return b.Bytes(), n
+}
@@ -0,0 +1,46 @@
+// Test file for kstat_parsable.go
+package main
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParsableToKstat(t *testing.T) {
+
+	out := "zfs:0:arcstats:class\tmisc\n" +
+		"zfs:0:arcstats:crtime\t12.345678\n" +
+		"zfs:0:arcstats:hits\t12345\n" +
+		"zfs:0:arcstats:memory_available_bytes\t-1024\n" +
+		"zfs:0:arcstats:size\t1073741824\n" +
+		"zfs:0:arcstats:snaptime\t99.5\n" +
+		"zfs:0:zil:zil_commit_count\t5\n" +
+		"garbage\n"
+
+	data, n := parsableToKstat([]byte(out), "arcstats")
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+
+	if n != 3 || len(lines) != 5 {
+		t.Fatalf("parsableToKstat() = %d stats, %d lines (wanted 3, 5)", n, len(lines))
+	}
+
+	var got []string
+	for _, l := range lines[2:] {
+		name, value, ok := parseKstatLine(l)
+		if !ok {
+			t.Errorf("parsableToKstat() line '%s' doesn't parse", l)
+		}
+		got = append(got, name+"="+strconv.FormatUint(value, 10))
+	}
+
+	wanted := []string{"hits=12345", "memory_available_bytes=0", "size=1073741824"}
+	if !reflect.DeepEqual(got, wanted) {
+		t.Errorf("parsableToKstat() = %v (wanted \"%v\")", got, wanted)
+	}
+
+	if _, n := parsableToKstat([]byte(out), "zstd"); n != 0 {
+		t.Errorf("parsableToKstat(zstd) = %d stats (wanted 0)", n)
+	}
+}
@@ -0,0 +1,51 @@
+// Conversion of kstats from sysctl
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// OpenZFS on macOS and FreeBSD exports the kstats as sysctls such as
+// kstat.zfs.misc.arcstats.hits. We turn them into the format of the Linux
+// kstat files so the rest of the code doesn't need to know the difference.
+// See arc_summary.go for the license
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const sysctlKstatPrefix = "kstat.zfs.misc."
+
+// sysctlToKstat takes the output of "sysctl kstat.zfs.misc.<name>" and
+// returns it in the format of /proc/spl/kstat/zfs/<name>, including the two
+// header lines. Values are marked as unsigned 64 bit numbers (type 4)
+func sysctlToKstat(out []byte, name string) []byte {
+
+	var b bytes.Buffer
+	prefix := sysctlKstatPrefix + name + "."
+
+	fmt.Fprintf(&b, "0 1 0x01 0 0 0 0\n")
+	fmt.Fprintf(&b, "%-32s%-5s%s\n", "name", "type", "data")
+
+	input := bufio.NewScanner(bytes.NewReader(out))
+
+	for input.Scan() {
+		l := input.Text()
+
+		idx := strings.Index(l, ":")
+		if idx == -1 || !strings.HasPrefix(l, prefix) {
+			continue
+		}
+
+		stat := l[len(prefix):idx]
+		value := strings.TrimSpace(l[idx+1:])
+
+		if stat == "" || value == "" || strings.Contains(stat, ".") {
+			continue
+		}
+
+		fmt.Fprintf(&b, "%-32s%-5s%s\n", stat, "4", value)
+	}
+
+	return b.Bytes()
+}
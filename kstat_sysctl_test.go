@@ -0,0 +1,38 @@
+// Test file for kstat_sysctl.go
+package main
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSysctlToKstat(t *testing.T) {
+
+	out := "kstat.zfs.misc.arcstats.hits: 12345\n" +
+		"kstat.zfs.misc.arcstats.misses: 678\n" +
+		"kstat.zfs.misc.arcstats.size: 1073741824\n" +
+		"kstat.zfs.misc.zil.zil_commit_count: 5\n" +
+		"garbage\n"
+
+	lines := strings.Split(strings.TrimSpace(string(sysctlToKstat([]byte(out), "arcstats"))), "\n")
+
+	if len(lines) != 5 {
+		t.Fatalf("sysctlToKstat() has %d lines (wanted 5)", len(lines))
+	}
+
+	var got []string
+	for _, l := range lines[2:] {
+		name, value, ok := parseKstatLine(l)
+		if !ok {
+			t.Errorf("sysctlToKstat() line '%s' doesn't parse", l)
+		}
+		got = append(got, name+"="+strconv.FormatUint(value, 10))
+	}
+
+	wanted := []string{"hits=12345", "misses=678", "size=1073741824"}
+	if !reflect.DeepEqual(got, wanted) {
+		t.Errorf("sysctlToKstat() = %v (wanted \"%v\")", got, wanted)
+	}
+}
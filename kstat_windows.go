@@ -0,0 +1,46 @@
+// Reading kstats on Windows
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// OpenZFS on Windows keeps its statistics in the driver rather than in a
+// file system. We read them with the kstat tool it installs, see
+// kstat_parsable.go. The tunables live in the registry, which we don't read
+// yet, so the tunables section is skipped. See arc_summary.go for the license
+
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// readKstat returns the contents of a kstat file, eg "arcstats". Bundles
+// hold the kstats in the Linux format wherever they were made
+func readKstat(ctx context.Context, name string) ([]byte, error) {
+
+	if bundleFiles != nil {
+		return readFile(ctx, procPath+name)
+	}
+
+	out, err := runCommand(ctx, "kstat", "-p", parsableKstatPrefix+name)
+
+	// kstat fails without output if there is no such kstat, which happens
+	// with the ones this version of ZFS doesn't have
+	if _, ok := err.(*exec.ExitError); ok && len(out) == 0 {
+		return nil, &os.PathError{Op: "kstat", Path: parsableKstatPrefix + name, Err: os.ErrNotExist}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("couldn't run 'kstat -p %s': %v", parsableKstatPrefix+name, err)
+	}
+
+	data, n := parsableToKstat(out, name)
+	if n == 0 {
+		return nil, &os.PathError{Op: "kstat", Path: parsableKstatPrefix + name, Err: os.ErrNotExist}
+	}
+
+	return data, nil
+}
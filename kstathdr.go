@@ -0,0 +1,96 @@
+// Kstat header times for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// The first line of every kstat file ends with the times the kstat was
+// created (crtime) and last updated (snaptime), in nanoseconds since boot.
+// The difference is how long the counters have been running, which is less
+// than the uptime if the module was loaded late or reloaded. Compared with
+// the uptime, snaptime tells how old the data is, which matters for stats
+// taken from a bundle. Systems that make up the header (BSD via sysctl, or
+// replayed snapshots) leave the times at zero and show nothing. See
+// arc_summary.go for the license
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// kstatHeader holds the times from the first line of a kstat file
+type kstatHeader struct {
+	crtime   time.Duration
+	snaptime time.Duration
+}
+
+// kstatHeaders are the headers of the kstat files last read, by short name
+var kstatHeaders = make(map[string]kstatHeader)
+
+// lateLoadThreshold is how long after boot a kstat can be created before we
+// point out the counters started late
+const lateLoadThreshold = 10 * time.Minute
+
+// parseKstatHeader returns the times in the first line of a kstat file, eg
+// "13 1 0x01 96 26112 8184917433 1234567890123"
+func parseKstatHeader(l string) (kstatHeader, bool) {
+
+	fields := strings.Fields(l)
+	if len(fields) != 7 {
+		return kstatHeader{}, false
+	}
+
+	crtime, err1 := strconv.ParseInt(fields[5], 10, 64)
+	snaptime, err2 := strconv.ParseInt(fields[6], 10, 64)
+	if err1 != nil || err2 != nil || snaptime == 0 || snaptime < crtime {
+		return kstatHeader{}, false
+	}
+
+	return kstatHeader{time.Duration(crtime), time.Duration(snaptime)}, true
+}
+
+// kstatAge returns how long the counters of a kstat have been running and, if
+// the uptime in seconds is known, how old the snapshot is
+func kstatAge(h kstatHeader, uptime float64) string {
+
+	result := fUptime((h.snaptime - h.crtime).Seconds())
+
+	var notes []string
+
+	if h.crtime > lateLoadThreshold {
+		notes = append(notes, "started "+fUptime(h.crtime.Seconds())+" after boot")
+	}
+
+	if uptime > 0 {
+		age := uptime - h.snaptime.Seconds()
+		if age < 0 {
+			age = 0
+		}
+		notes = append(notes, fmt.Sprintf("data %0.1f s old", age))
+	}
+
+	if len(notes) > 0 {
+		result += " (" + strings.Join(notes, ", ") + ")"
+	}
+
+	return result
+}
+
+// printKstatAge prints how long the counters of a kstat file have been
+// running and how fresh they are
+func printKstatAge(file string) {
+
+	h, ok := kstatHeaders[file]
+	if !ok || detailBrief() {
+		return
+	}
+
+	ctx, cancel := collectContext()
+	uptime, err := readUptime(ctx)
+	cancel()
+	if err != nil {
+		uptime = 0
+	}
+
+	prtL1("Counters running for:", kstatAge(h, uptime))
+}
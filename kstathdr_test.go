@@ -0,0 +1,47 @@
+// Test file for kstathdr.go
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseKstatHeader(t *testing.T) {
+
+	var tests = []struct {
+		in     string
+		wanted kstatHeader
+		ok     bool
+	}{
+		{"13 1 0x01 96 26112 8184917433 1234567890123", kstatHeader{8184917433, 1234567890123}, true},
+		{"0 1 0x01 0 0 0 0", kstatHeader{}, false},
+		{"13 1 0x01 96 26112 9000 8000", kstatHeader{}, false},
+		{"name type data", kstatHeader{}, false},
+	}
+
+	for _, test := range tests {
+		got, ok := parseKstatHeader(test.in)
+		if got != test.wanted || ok != test.ok {
+			t.Errorf("parseKstatHeader(%s) = %v, %v (wanted \"%v\")", test.in, got, ok, test.wanted)
+		}
+	}
+}
+
+func TestKstatAge(t *testing.T) {
+
+	var tests = []struct {
+		h      kstatHeader
+		uptime float64
+		wanted string
+	}{
+		{kstatHeader{10 * time.Second, 2 * time.Hour}, 0, "1:59"},
+		{kstatHeader{10 * time.Second, 2 * time.Hour}, 7205.5, "1:59 (data 5.5 s old)"},
+		{kstatHeader{time.Hour, 26 * time.Hour}, 93600, "1 day, 1:00 (started 1:00 after boot, data 0.0 s old)"},
+	}
+
+	for _, test := range tests {
+		if got := kstatAge(test.h, test.uptime); got != test.wanted {
+			t.Errorf("kstatAge(%v, %v) = %s (wanted \"%s\")", test.h, test.uptime, got, test.wanted)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+// Latency histograms for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// ZFS keeps some of its timings as histograms with buckets that double in
+// size: each pool has a dmu_tx_assign kstat with how long writes waited to
+// get into a transaction group, and "zpool iostat -w" has the latencies of
+// the vdev queues. Raw buckets are hard to compare against a service level
+// objective, so we also compute p50, p95 and p99. These are approximate:
+// each is the upper end of the bucket the percentile falls into, so the
+// real value is at most this. See arc_summary.go for the license
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// latencyPercentiles are the percentiles we show
+var latencyPercentiles = []float64{50, 95, 99}
+
+// iostatLatencies are the columns of "zpool iostat -w" in the order they are
+// printed. Older versions of ZFS don't have all of them
+var iostatLatencies = []string{
+	"Total wait read:", "Total wait write:",
+	"Disk wait read:", "Disk wait write:",
+	"Sync queue read:", "Sync queue write:",
+	"Async queue read:", "Async queue write:",
+	"Scrub queue:", "Trim queue:", "Rebuild queue:",
+}
+
+// histBucket is a bucket of a histogram: the number of values up to upper
+// nanoseconds and above the upper end of the bucket before
+type histBucket struct {
+	upper float64
+	count uint64
+}
+
+// histTotal returns the number of values in a histogram
+func histTotal(buckets []histBucket) uint64 {
+
+	var total uint64
+	for _, b := range buckets {
+		total += b.count
+	}
+
+	return total
+}
+
+// percentile returns the upper end of the bucket the pth percentile falls
+// into. The buckets must be sorted. Returns false for an empty histogram
+func percentile(buckets []histBucket, p float64) (float64, bool) {
+
+	total := histTotal(buckets)
+	if total == 0 {
+		return 0, false
+	}
+
+	want := p / 100 * float64(total)
+	var seen uint64
+
+	for _, b := range buckets {
+		seen += b.count
+		if float64(seen) >= want {
+			return b.upper, true
+		}
+	}
+
+	return buckets[len(buckets)-1].upper, true
+}
+
+// parseTxAssign parses a dmu_tx_assign kstat. Lines look like
+// "1024 ns   4   12": the bucket holds the waits of at least 1024 and less
+// than 2048 ns
+func parseTxAssign(data []byte) ([]histBucket, error) {
+
+	var buckets []histBucket
+
+	input := bufio.NewScanner(strings.NewReader(string(data)))
+
+	for input.Scan() {
+		fields := strings.Fields(input.Text())
+		if len(fields) != 4 || fields[1] != "ns" {
+			continue
+		}
+
+		lower, err1 := strconv.ParseUint(fields[0], 10, 64)
+		count, err2 := strconv.ParseUint(fields[3], 10, 64)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("bad bucket '%s'", input.Text())
+		}
+
+		buckets = append(buckets, histBucket{float64(2 * lower), count})
+	}
+
+	if len(buckets) == 0 {
+		return nil, fmt.Errorf("no buckets")
+	}
+
+	return buckets, input.Err()
+}
+
+// iostatHist is the latency histograms of a pool by column of
+// iostatLatencies
+type iostatHist struct {
+	pool    string
+	columns [][]histBucket
+}
+
+// parseIostatHist parses the output of "zpool iostat -w -H -p". Each pool
+// starts with a line with its name, followed by one line per bucket with
+// its upper end in ns and the counts of the columns
+func parseIostatHist(out string) []iostatHist {
+
+	var result []iostatHist
+
+	input := bufio.NewScanner(strings.NewReader(out))
+
+	for input.Scan() {
+		fields := strings.Fields(input.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		upper, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			result = append(result, iostatHist{pool: fields[0]})
+			continue
+		}
+
+		if len(result) == 0 {
+			continue
+		}
+
+		h := &result[len(result)-1]
+
+		for i, f := range fields[1:] {
+			if i >= len(iostatLatencies) {
+				break
+			}
+			count, err := strconv.ParseUint(f, 10, 64)
+			if err != nil {
+				continue
+			}
+			for len(h.columns) <= i {
+				h.columns = append(h.columns, nil)
+			}
+			h.columns[i] = append(h.columns[i], histBucket{float64(upper), count})
+		}
+	}
+
+	return result
+}
+
+// fPercentiles returns the percentiles of a histogram
+func fPercentiles(buckets []histBucket) string {
+
+	var parts []string
+
+	for _, p := range latencyPercentiles {
+		v, _ := percentile(buckets, p)
+		parts = append(parts, fNanos(v))
+	}
+
+	return strings.Join(parts, " / ")
+}
+
+// printHistogram prints the percentiles of a histogram and, with -detail
+// full, the buckets that aren't empty
+func printHistogram(label string, buckets []histBucket) {
+
+	if histTotal(buckets) == 0 {
+		return
+	}
+
+	prtL2(label, fPercentiles(buckets))
+
+	if detailLevels[*OptDetail] < detailLevels["full"] {
+		return
+	}
+
+	for _, b := range buckets {
+		if b.count > 0 {
+			prtL2("  up to "+fNanos(b.upper)+":", fHits(strconv.FormatUint(b.count, 10)))
+		}
+	}
+}
+
+// printLatency displays the latency histograms of every pool
+func printLatency() {
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	out, err := runCommand(ctx, "zpool", "list", "-H", "-o", "name")
+	if err != nil {
+		skipSection("latency", fmt.Errorf("couldn't list pools: %v", err))
+		return
+	}
+
+	iostat := make(map[string]iostatHist)
+	if out, err := runCommand(ctx, "zpool", "iostat", "-w", "-H", "-p"); err == nil {
+		for _, h := range parseIostatHist(string(out)) {
+			iostat[h.pool] = h
+		}
+	} else {
+		addWarning("latency", "couldn't run 'zpool iostat -w': %v", err)
+	}
+
+	for _, pool := range strings.Fields(string(out)) {
+		fmt.Fprintf(reportOut, "\nPool %s (p50 / p95 / p99):\n", redactName(pool))
+
+		if data, err := readFile(ctx, procPath+pool+"/dmu_tx_assign"); err == nil {
+			if buckets, err := parseTxAssign(data); err == nil {
+				printHistogram("Tx assign wait:", buckets)
+			} else {
+				addWarning("latency", "%s/dmu_tx_assign: %v", redactName(pool), err)
+			}
+		}
+
+		for i, buckets := range iostat[pool].columns {
+			printHistogram(iostatLatencies[i], buckets)
+		}
+	}
+}
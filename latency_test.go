@@ -0,0 +1,68 @@
+// Test file for latency.go
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPercentile(t *testing.T) {
+
+	buckets := []histBucket{{1000, 50}, {2000, 40}, {4000, 8}, {8000, 2}}
+
+	var tests = []struct {
+		p    float64
+		want float64
+	}{
+		{0, 1000},
+		{50, 1000},
+		{51, 2000},
+		{95, 4000},
+		{99, 8000},
+		{100, 8000},
+	}
+
+	for _, test := range tests {
+		if got, ok := percentile(buckets, test.p); !ok || got != test.want {
+			t.Errorf("percentile(%v) = %v, %v (wanted \"%v\")", test.p, got, ok, test.want)
+		}
+	}
+
+	if _, ok := percentile([]histBucket{{1000, 0}}, 50); ok {
+		t.Errorf("percentile(empty) succeeded (wanted false)")
+	}
+}
+
+func TestParseTxAssign(t *testing.T) {
+
+	data := []byte("19 4 0x01 32 1536 5214936013 15383924717486\nname type data\n1 ns 4 0\n512 ns 4 7\n1024 ns 4 3\n")
+
+	got, err := parseTxAssign(data)
+	wanted := []histBucket{{2, 0}, {1024, 7}, {2048, 3}}
+	if err != nil || !reflect.DeepEqual(got, wanted) {
+		t.Errorf("parseTxAssign() = %v, %v (wanted \"%v\")", got, err, wanted)
+	}
+
+	if _, err := parseTxAssign([]byte("name type data\n")); err == nil {
+		t.Errorf("parseTxAssign(empty) succeeded (wanted error)")
+	}
+}
+
+func TestParseIostatHist(t *testing.T) {
+
+	out := "tank\n1023\t1\t2\t3\n2047\t4\t5\t6\nbackup\n1023\t0\t0\t0\n"
+
+	got := parseIostatHist(out)
+	wanted := []iostatHist{
+		{"tank", [][]histBucket{
+			{{1023, 1}, {2047, 4}},
+			{{1023, 2}, {2047, 5}},
+			{{1023, 3}, {2047, 6}},
+		}},
+		{"backup", [][]histBucket{{{1023, 0}}, {{1023, 0}}, {{1023, 0}}}},
+	}
+
+	if !reflect.DeepEqual(got, wanted) {
+		t.Errorf("parseIostatHist() = %v (wanted \"%v\")", got, wanted)
+	}
+}
@@ -0,0 +1,429 @@
+// Layout of the report lines for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// The prtL* functions don't print their lines directly but add them as rows
+// of cells to a layout buffer. printLayout collects the output of a whole
+// section, works out how wide the label, percentage and value columns have to
+// be for the rows in it, and then prints them aligned to lineLen. Everything
+// else the section prints is passed through unchanged and in order. The
+// indent, width and style of the section titles can be set with flags or in
+// the "layout" object of the configuration file. See arc_summary.go for the
+// license
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// tabWidth is how wide a terminal shows a tab in the indent
+const tabWidth = 8
+
+// reportOut is where the report is printed. printLayout and captureOutput
+// point it somewhere else while they collect output
+var reportOut io.Writer = os.Stdout
+
+// layoutConfig is the "layout" object of the configuration file. Empty
+// fields keep the defaults
+type layoutConfig struct {
+	Indent    string `json:"indent"`
+	Width     int    `json:"width"`
+	Separator string `json:"separator"`
+}
+
+// minWidth is the narrowest report we allow. Anything smaller can't hold a
+// label and a value
+const minWidth = 40
+
+// separators are the styles of section titles known to -separator
+var separators = map[string]func(title string) string{
+	"dashes": func(t string) string { return "--- " + t + " ---" },
+	"rule": func(t string) string {
+		if n := lineLen - len(t) - 1; n > 0 {
+			return t + " " + strings.Repeat("-", n)
+		}
+		return t
+	},
+	"title": func(t string) string { return t },
+}
+
+// separator is the style of the section titles
+var separator = "dashes"
+
+// separatorNames returns the names of the title styles in alphabetical order
+func separatorNames() []string {
+
+	var names []string
+
+	for n := range separators {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// parseIndent returns the indent for "tab" or a number of spaces
+func parseIndent(s string) (string, error) {
+
+	if s == "tab" {
+		return "\t", nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > 16 {
+		return "", fmt.Errorf("bad indent '%s' (wanted 'tab' or 0 to 16 spaces)", s)
+	}
+
+	return strings.Repeat(" ", n), nil
+}
+
+// check returns an error if the layout can't be used
+func (lc layoutConfig) check() error {
+
+	if lc.Indent != "" {
+		if _, err := parseIndent(lc.Indent); err != nil {
+			return err
+		}
+	}
+
+	if lc.Width != 0 && lc.Width < minWidth {
+		return fmt.Errorf("width %d is less than %d", lc.Width, minWidth)
+	}
+
+	if _, ok := separators[lc.Separator]; lc.Separator != "" && !ok {
+		return fmt.Errorf("unknown separator '%s'", lc.Separator)
+	}
+
+	return nil
+}
+
+// applyLayout sets the indent, width and title style from the flags and the
+// configuration file. Flags given on the command line win
+func applyLayout(lc layoutConfig) {
+
+	if explicitFlags["indent"] || lc.Indent == "" {
+		lc.Indent = *OptIndent
+	}
+	if explicitFlags["width"] || lc.Width == 0 {
+		lc.Width = *OptWidth
+	}
+	if explicitFlags["separator"] || lc.Separator == "" {
+		lc.Separator = *OptSeparator
+	}
+
+	if err := lc.check(); err != nil {
+		log.Fatal(err)
+	}
+
+	indent, _ = parseIndent(lc.Indent)
+	lineLen = lc.Width
+	separator = lc.Separator
+
+	if *OptPlain {
+		indent = ""
+	}
+}
+
+// layoutRow is one line of the report. Level 1 rows start at the left
+// margin, level 2 rows are indented
+type layoutRow struct {
+	level int
+	label string
+	perc  string
+	value string
+}
+
+// layoutWidths are the widths of the columns
+type layoutWidths struct {
+	label int
+	perc  int
+	value int
+}
+
+// displayWidth returns how many columns a string takes in a terminal
+func displayWidth(s string) int {
+
+	width := 0
+
+	for _, r := range s {
+		if r == '\t' {
+			width += tabWidth - width%tabWidth
+			continue
+		}
+		width++
+	}
+
+	return width
+}
+
+// rowIndent returns the indent of a row
+func rowIndent(level int) string {
+	if level > 1 {
+		return indent
+	}
+	return ""
+}
+
+// measureRows returns the column widths for the rows. The label column is
+// made wider until the lines are width columns long, so short sections line
+// up with long ones
+func measureRows(rows []layoutRow, width int) layoutWidths {
+
+	var w layoutWidths
+
+	for _, r := range rows {
+		if n := displayWidth(rowIndent(r.level) + r.label); n > w.label {
+			w.label = n
+		}
+		if n := utf8.RuneCountInString(r.perc); n > w.perc {
+			w.perc = n
+		}
+		if n := utf8.RuneCountInString(r.value); n > w.value {
+			w.value = n
+		}
+	}
+
+	if total := w.label + 1 + w.perc + 1 + w.value; total < width {
+		w.label += width - total
+	}
+
+	return w
+}
+
+// format returns the row as a line with the given column widths
+func (r layoutRow) format(w layoutWidths) string {
+
+	label := rowIndent(r.level) + r.label
+	pad := w.label - displayWidth(label)
+	if pad < 0 {
+		pad = 0
+	}
+
+	return label + strings.Repeat(" ", pad) + " " +
+		fmt.Sprintf("%*s %*s", w.perc, r.perc, w.value, r.value)
+}
+
+// layoutPart is a row or a piece of text printed by a section
+type layoutPart struct {
+	row  *layoutRow
+	text []byte
+}
+
+// layoutBuffer collects the output of a section for printLayout in the
+// order it was printed
+type layoutBuffer struct {
+	parts []layoutPart
+}
+
+// Write adds text to the buffer
+func (b *layoutBuffer) Write(p []byte) (int, error) {
+
+	if n := len(b.parts); n > 0 && b.parts[n-1].row == nil {
+		b.parts[n-1].text = append(b.parts[n-1].text, p...)
+	} else {
+		b.parts = append(b.parts, layoutPart{text: append([]byte(nil), p...)})
+	}
+
+	return len(p), nil
+}
+
+// addRow adds a row to the buffer
+func (b *layoutBuffer) addRow(r layoutRow) {
+	b.parts = append(b.parts, layoutPart{row: &r})
+}
+
+// render returns the output in the buffer with the rows aligned
+func (b *layoutBuffer) render(width int) []byte {
+
+	var rows []layoutRow
+	for _, p := range b.parts {
+		if p.row != nil {
+			rows = append(rows, *p.row)
+		}
+	}
+
+	w := measureRows(rows, width)
+
+	var out bytes.Buffer
+	for _, p := range b.parts {
+		if p.row == nil {
+			out.Write(p.text)
+			continue
+		}
+		line := strings.TrimRight(p.row.format(w), " ")
+		fmt.Fprintln(&out, highlightRow(*p.row, colorRow(*p.row, line)))
+	}
+
+	return out.Bytes()
+}
+
+// layoutPending is the buffer printLayout is filling and layoutDest where it
+// goes when the section is done. Rows printed outside of printLayout are
+// aligned on their own
+var (
+	layoutPending *layoutBuffer
+	layoutDest    io.Writer
+)
+
+// printLayout runs f, which prints part of the report, and prints its output
+// with the rows aligned
+func printLayout(f func()) {
+
+	if layoutPending != nil || *OptPlain {
+		f()
+		return
+	}
+
+	layoutPending, layoutDest = &layoutBuffer{}, reportOut
+	reportOut = layoutPending
+
+	f()
+
+	flushLayout()
+}
+
+// flushLayout prints what printLayout has collected so far and stops
+// collecting
+func flushLayout() {
+
+	if layoutPending == nil {
+		return
+	}
+
+	b := layoutPending
+	reportOut, layoutPending, layoutDest = layoutDest, nil, nil
+
+	reportOut.Write(b.render(lineLen))
+}
+
+// layoutLog is the output of the log package. It first prints what
+// printLayout has collected, so the part of a section before a log.Fatal is
+// not lost
+type layoutLog struct{}
+
+func (layoutLog) Write(p []byte) (int, error) {
+	flushLayout()
+	return os.Stderr.Write(p)
+}
+
+// printRow prints a row of the report
+func printRow(r layoutRow) {
+
+	if layoutPending == nil {
+		line := strings.TrimRight(r.format(measureRows([]layoutRow{r}, lineLen)), " ")
+		fmt.Fprintln(reportOut, highlightRow(r, colorRow(r, line)))
+		return
+	}
+
+	layoutPending.addRow(r)
+}
+
+// tableGap is the space between the columns of a layoutTable
+const tableGap = 2
+
+// layoutTable is a table printed below a heading, with names in the first
+// column and values right aligned in the others. Each column is as wide as
+// its widest cell, and the first one is made wider until the lines are as
+// long as the report
+type layoutTable struct {
+	rows [][]string
+}
+
+// add adds a row. A row with fewer cells than the first one has its last
+// cell right aligned to the end of the table, eg for "(no statistics)"
+func (t *layoutTable) add(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+// padCell returns s padded with spaces to width columns, on the left if
+// right is true
+func padCell(s string, width int, right bool) string {
+
+	pad := width - utf8.RuneCountInString(s)
+	if pad < 0 {
+		pad = 0
+	}
+
+	if right {
+		return strings.Repeat(" ", pad) + s
+	}
+
+	return s + strings.Repeat(" ", pad)
+}
+
+// lines returns the lines of the table for a report of the given width,
+// each indented like a level 2 row
+func (t *layoutTable) lines(width int) []string {
+
+	if len(t.rows) == 0 {
+		return nil
+	}
+
+	cols := len(t.rows[0])
+	widths := make([]int, cols)
+
+	for _, r := range t.rows {
+		for i, c := range r {
+			if i >= cols || (len(r) < cols && i > 0) {
+				break
+			}
+			if n := utf8.RuneCountInString(c); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	rest := tableGap * (cols - 1)
+	for _, w := range widths[1:] {
+		rest += w
+	}
+
+	// The last cell of a short row may need more room than the columns
+	// it spans, which then goes to the last column
+	for _, r := range t.rows {
+		if len(r) > 1 && len(r) < cols {
+			if need := tableGap + utf8.RuneCountInString(r[len(r)-1]); need > rest {
+				widths[cols-1] += need - rest
+				rest = need
+			}
+		}
+	}
+
+	if total := displayWidth(indent) + widths[0] + rest; total < width {
+		widths[0] += width - total
+	}
+
+	var lines []string
+
+	for _, r := range t.rows {
+		line := indent + padCell(r[0], widths[0], false)
+
+		switch {
+		case len(r) >= cols:
+			for i := 1; i < cols; i++ {
+				line += strings.Repeat(" ", tableGap) + padCell(r[i], widths[i], true)
+			}
+		case len(r) > 1:
+			line += strings.Repeat(" ", tableGap) + padCell(r[len(r)-1], rest-tableGap, true)
+		}
+
+		lines = append(lines, strings.TrimRight(line, " "))
+	}
+
+	return lines
+}
+
+// print prints the table
+func (t *layoutTable) print() {
+	for _, l := range t.lines(lineLen) {
+		fmt.Fprintln(reportOut, l)
+	}
+}
@@ -0,0 +1,152 @@
+// Test file for layout.go
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLayoutBuffer(t *testing.T) {
+
+	rows := []layoutRow{
+		{1, "ARC size:", "46.9 %", "7.0 GiB"},
+		{2, "Target size:", "", "7.5 GiB (8,000,000,000)"},
+	}
+
+	var b layoutBuffer
+	b.Write([]byte("\nARC:\n"))
+	for _, r := range rows {
+		b.addRow(r)
+	}
+	b.Write([]byte("\tfree text "))
+	b.Write([]byte("stays\n"))
+
+	got := string(b.render(40))
+	want := strings.Join([]string{
+		"",
+		"ARC:",
+		"ARC size:            46.9 %                 7.0 GiB",
+		"\tTarget size:        7.5 GiB (8,000,000,000)",
+		"\tfree text stays",
+		"",
+	}, "\n")
+
+	if got != want {
+		t.Errorf("render(40) = %q (wanted %q)", got, want)
+	}
+
+	// Short rows are padded to the line width
+	b = layoutBuffer{}
+	b.addRow(rows[0])
+	got = strings.TrimSuffix(string(b.render(30)), "\n")
+	if displayWidth(got) != 30 {
+		t.Errorf("render(30) of %v is %d wide (wanted 30): %q", rows[0], displayWidth(got), got)
+	}
+}
+
+func TestPrintLayout(t *testing.T) {
+
+	got := string(captureOutput(func() {
+		printLayout(func() {
+			fmt.Fprintln(reportOut, "Title:")
+			prtL1("Short:", "1")
+			prtL2("A much longer label:", "2")
+		})
+	}))
+
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	if len(lines) != 4 || lines[0] != "Title:" || lines[1] != "" {
+		t.Fatalf("printLayout() = %q (wanted title, blank line and two rows)", got)
+	}
+	if displayWidth(lines[2]) != displayWidth(lines[3]) {
+		t.Errorf("printLayout() values not aligned: %q", got)
+	}
+	if layoutPending != nil || reportOut != os.Stdout {
+		t.Errorf("printLayout() left the output redirected")
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+
+	var tests = []struct {
+		have string
+		want int
+	}{
+		{"", 0},
+		{"abc", 3},
+		{"\tabc", 11},
+		{"ab\tc", 9},
+		{"45 %", 4},
+	}
+
+	for _, test := range tests {
+		if got := displayWidth(test.have); got != test.want {
+			t.Errorf("displayWidth(%q) = %v (wanted \"%v\")", test.have, got, test.want)
+		}
+	}
+}
+
+func TestLayoutTable(t *testing.T) {
+
+	savedIndent := indent
+	defer func() { indent = savedIndent }()
+
+	var tbl layoutTable
+	tbl.add("Device", "Reads", "Busy")
+	tbl.add("ata-WDC_WD40EFRX-68N32N0_WD-WCC7K1234567", "1.2k", "3.5 %")
+	tbl.add("sdb", "(no statistics)")
+
+	var tests = []struct {
+		indent string
+		width  int
+		wanted []string
+	}{
+		{"  ", 0, []string{
+			"  Device                                    Reads      Busy",
+			"  ata-WDC_WD40EFRX-68N32N0_WD-WCC7K1234567   1.2k     3.5 %",
+			"  sdb                                       (no statistics)",
+		}},
+		{"\t", 0, []string{
+			"\tDevice                                    Reads      Busy",
+			"\tata-WDC_WD40EFRX-68N32N0_WD-WCC7K1234567   1.2k     3.5 %",
+			"\tsdb                                       (no statistics)",
+		}},
+		{"  ", 72, []string{
+			"  Device                                                 Reads      Busy",
+			"  ata-WDC_WD40EFRX-68N32N0_WD-WCC7K1234567                1.2k     3.5 %",
+			"  sdb                                                    (no statistics)",
+		}},
+	}
+
+	for _, test := range tests {
+		indent = test.indent
+		got := tbl.lines(test.width)
+		if strings.Join(got, "\n") != strings.Join(test.wanted, "\n") {
+			t.Errorf("layoutTable.lines(%q, %d) = %q (wanted %q)", test.indent, test.width, got, test.wanted)
+		}
+	}
+}
+
+func TestLayoutConfig(t *testing.T) {
+
+	var tests = []struct {
+		lc    layoutConfig
+		fails bool
+	}{
+		{layoutConfig{}, false},
+		{layoutConfig{Indent: "tab", Width: 100, Separator: "rule"}, false},
+		{layoutConfig{Indent: "4"}, false},
+		{layoutConfig{Indent: "wide"}, true},
+		{layoutConfig{Indent: "-1"}, true},
+		{layoutConfig{Width: 20}, true},
+		{layoutConfig{Separator: "stars"}, true},
+	}
+
+	for _, test := range tests {
+		if err := test.lc.check(); (err != nil) != test.fails {
+			t.Errorf("check(%v) = %v (wanted fail %v)", test.lc, err, test.fails)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// PID and lock file for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// With -pid-file, watch and serve mode lock the file and write their PID to
+// it, so a second instance refuses to start instead of serving the same
+// metrics twice or writing to the same history file. The lock goes away
+// with the process, so a file left behind by a crash doesn't get in the
+// way. See arc_summary.go for the license
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+)
+
+// pidFile is kept open while we run, since closing it drops the lock
+var pidFile *os.File
+
+// acquirePidFile locks the file at path and writes our PID to it. If
+// another instance holds the lock, the error names its PID
+func acquirePidFile(path string) error {
+
+	f, err := openLocked(path)
+	if err != nil {
+		if err == errLocked {
+			data, _ := ioutil.ReadFile(path)
+			if pid := strings.TrimSpace(string(data)); pid != "" {
+				return fmt.Errorf("arc_summary is already running with pid %s (lock file %s)", pid, path)
+			}
+			return fmt.Errorf("arc_summary is already running (lock file %s)", path)
+		}
+		return err
+	}
+
+	if err := f.Truncate(0); err != nil {
+		f.Close()
+		return err
+	}
+
+	if _, err := fmt.Fprintf(f, "%d\n", os.Getpid()); err != nil {
+		f.Close()
+		return err
+	}
+
+	pidFile = f
+	return nil
+}
+
+// lockDaemon takes the lock of -pid-file if one was given
+func lockDaemon() {
+
+	if *OptPidFile == "" {
+		return
+	}
+
+	if err := acquirePidFile(*OptPidFile); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,46 @@
+// Test file for lock.go
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAcquirePidFile(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "arc_summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "arc_summary.pid")
+	defer func() {
+		if pidFile != nil {
+			pidFile.Close()
+			pidFile = nil
+		}
+	}()
+
+	if err := acquirePidFile(path); err != nil {
+		t.Fatalf("acquirePidFile() = %v (wanted nil)", err)
+	}
+
+	data, _ := ioutil.ReadFile(path)
+	if got := strings.TrimSpace(string(data)); got != strconv.Itoa(os.Getpid()) {
+		t.Errorf("pid file holds %q (wanted %d)", got, os.Getpid())
+	}
+
+	// The second lock is held by the same process here, but flock locks
+	// belong to the open file, so it fails all the same
+	first := pidFile
+	err = acquirePidFile(path)
+	if err == nil || !strings.Contains(err.Error(), "already running with pid") {
+		t.Errorf("second acquirePidFile() = %v (wanted already running)", err)
+	}
+	pidFile = first
+}
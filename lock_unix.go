@@ -0,0 +1,42 @@
+// Lock files on Unix systems
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// See arc_summary.go for the license
+
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// errLocked says another process holds the lock
+var errLocked = errors.New("locked by another process")
+
+// openLocked opens the file at path, creating it if needed, and takes an
+// exclusive lock on it without waiting
+func openLocked(path string) (*os.File, error) {
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, errLocked
+		}
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// releasePidFile does nothing, the lock goes away with the process. Removing
+// the file could let a new instance lock a file that is about to vanish
+func releasePidFile() {}
@@ -0,0 +1,42 @@
+// Lock files on Windows
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// Without flock, the lock is the file itself: it is created exclusively and
+// removed when we quit normally. A file left behind by a crash has to be
+// removed by hand. See arc_summary.go for the license
+
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// errLocked says another process holds the lock
+var errLocked = errors.New("locked by another process")
+
+// openLocked creates the file at path, which must not exist yet
+func openLocked(path string) (*os.File, error) {
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0644)
+	if os.IsExist(err) {
+		return nil, errLocked
+	}
+
+	return f, err
+}
+
+// releasePidFile removes the lock file
+func releasePidFile() {
+
+	if pidFile == nil {
+		return
+	}
+
+	pidFile.Close()
+	os.Remove(pidFile.Name())
+	pidFile = nil
+}
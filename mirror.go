@@ -0,0 +1,79 @@
+// Mirror read balancing section for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// A read from a mirror goes to one of its children. ZFS prefers the child
+// with the fewest pending I/Os, adjusted by how expensive the read would be:
+// on spinning disks a read next to the last one (linear) or close to it
+// (offset) is cheap and a seek is not, SSDs only know linear and seek. The
+// vdev_mirror_stats kstat counts which case each pick was, which shows if
+// reads on a mirror of SSDs and HDDs really end up on the SSDs. See
+// arc_summary.go for the license
+package main
+
+import (
+	"strconv"
+)
+
+// mirrorRotating and mirrorNonRotating are the counters of the picks of
+// rotating and non-rotating children with their labels
+var (
+	mirrorRotating = []struct{ stat, label string }{
+		{"rotating_linear", "Next to the last read:"},
+		{"rotating_offset", "Close to the last read:"},
+		{"rotating_seek", "Seek:"},
+	}
+
+	mirrorNonRotating = []struct{ stat, label string }{
+		{"non_rotating_linear", "Next to the last read:"},
+		{"non_rotating_seek", "Seek:"},
+	}
+)
+
+// sumStats returns the sum of the given stats as a string
+func sumStats(m map[string]string, names ...string) string {
+
+	var total uint64
+
+	for _, n := range names {
+		if v, ok := m[n]; ok {
+			total += stringToUint64(v)
+		}
+	}
+
+	return strconv.FormatUint(total, 10)
+}
+
+// printMirror displays how reads are spread over the children of mirrors
+func printMirror() {
+
+	var mirrorStats = make(map[string]string)
+	procSection("vdev_mirror_stats", mirrorStats)
+
+	rotating := sumStats(mirrorStats, "rotating_linear", "rotating_offset", "rotating_seek")
+	nonRotating := sumStats(mirrorStats, "non_rotating_linear", "non_rotating_seek")
+	all := strconv.FormatUint(stringToUint64(rotating)+stringToUint64(nonRotating), 10)
+
+	prtL1p("Reads to rotating children:", fPerc(rotating, all), fHits(rotating))
+	for _, c := range mirrorRotating {
+		if v, ok := mirrorStats[c.stat]; ok {
+			prtL2p(c.label, fPerc(v, rotating), fHits(v))
+		}
+	}
+
+	prtL1p("Reads to non-rotating children:", fPerc(nonRotating, all), fHits(nonRotating))
+	for _, c := range mirrorNonRotating {
+		if v, ok := mirrorStats[c.stat]; ok {
+			prtL2p(c.label, fPerc(v, nonRotating), fHits(v))
+		}
+	}
+
+	found, notFound := mirrorStats["preferred_found"], mirrorStats["preferred_not_found"]
+	if found != "" && notFound != "" {
+		picks := sumStats(mirrorStats, "preferred_found", "preferred_not_found")
+		prtL1("Preferred child:", "")
+		prtL2p("Found:", fPerc(found, picks), fHits(found))
+		prtL2p("Not found:", fPerc(notFound, picks), fHits(notFound))
+	}
+
+	explain(explainMirror(stringToUint64(rotating), stringToUint64(nonRotating)))
+}
@@ -0,0 +1,40 @@
+// Test file for mirror.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSumStats(t *testing.T) {
+
+	m := map[string]string{"rotating_linear": "100", "rotating_seek": "850"}
+
+	if got := sumStats(m, "rotating_linear", "rotating_offset", "rotating_seek"); got != "950" {
+		t.Errorf("sumStats() = %s (wanted \"950\")", got)
+	}
+
+	if got := sumStats(m); got != "0" {
+		t.Errorf("sumStats(nothing) = %s (wanted \"0\")", got)
+	}
+}
+
+func TestExplainMirror(t *testing.T) {
+
+	var tests = []struct {
+		rotating, nonRotating uint64
+		hint                  bool
+	}{
+		{1000, 0, false},
+		{0, 1000, false},
+		{100, 1000, false},
+		{1000, 100, true},
+	}
+
+	for _, test := range tests {
+		got := strings.Contains(explainMirror(test.rotating, test.nonRotating), "zfs_vdev_mirror")
+		if got != test.hint {
+			t.Errorf("explainMirror(%d, %d) has hint %v (wanted \"%v\")", test.rotating, test.nonRotating, got, test.hint)
+		}
+	}
+}
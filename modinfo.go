@@ -0,0 +1,266 @@
+// Descriptions of the tunables without modinfo for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// The descriptions and types of the tunables come from modinfo. It isn't
+// always in /sbin or in the PATH, so without it we read the .modinfo section
+// of the zfs.ko module ourselves, and without that we fall back on a short
+// table of the most common tunables. Anything else gets "(no description)"
+// instead of stopping the report. modinfo can be slow, so its output is
+// kept in -cache-dir for each version of the module. See arc_summary.go for
+// the license
+package main
+
+import (
+	"bytes"
+	"context"
+	"debug/elf"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	osReleaseKernelPath = "/proc/sys/kernel/osrelease"
+	modulesDir          = "/lib/modules"
+	noDescription       = "(no description)"
+)
+
+// builtinTunables are the descriptions and types of the common tunables for
+// systems where neither modinfo nor the module can be read
+var builtinTunables = map[string]struct{ typ, desc string }{
+	"l2arc_noprefetch":                {"int", "Skip caching prefetched buffers"},
+	"l2arc_write_max":                 {"ulong", "Max write bytes per interval"},
+	"zfs_arc_dnode_limit":             {"ulong", "Minimum bytes of dnodes in ARC"},
+	"zfs_arc_max":                     {"ulong", "Max arc size"},
+	"zfs_arc_meta_limit":              {"ulong", "Meta limit for arc size"},
+	"zfs_arc_min":                     {"ulong", "Min arc size"},
+	"zfs_arc_sys_free":                {"ulong", "System free memory target size in bytes"},
+	"zfs_compressed_arc_enabled":      {"int", "Disable compressed arc buffers"},
+	"zfs_dirty_data_max":              {"ulong", "Determines the dirty space limit"},
+	"zfs_dirty_data_max_percent":      {"int", "Percent of RAM to limit dirty data"},
+	"zfs_prefetch_disable":            {"int", "Disable all ZFS prefetching"},
+	"zfs_txg_timeout":                 {"int", "Max seconds worth of delta per txg"},
+	"zfs_vdev_async_read_max_active":  {"int", "Max active async read I/Os per vdev"},
+	"zfs_vdev_async_write_max_active": {"int", "Max active async write I/Os per vdev"},
+	"zfs_vdev_sync_read_max_active":   {"int", "Max active sync read I/Os per vdev"},
+	"zfs_vdev_sync_write_max_active":  {"int", "Max active sync write I/Os per vdev"},
+}
+
+// modinfoCommand returns the modinfo to run. Cron and systemd often don't
+// have /sbin in the PATH, so that is where we look if it isn't found
+func modinfoCommand() string {
+
+	if p, err := exec.LookPath("modinfo"); err == nil {
+		return p
+	}
+
+	return "/sbin/modinfo"
+}
+
+// modinfoFromELF turns the .modinfo section of a module into the output of
+// "modinfo -0", so parseModinfo can read both. The section holds
+// "parm=name:description" and "parmtype=name:type" entries separated by
+// NUL bytes
+func modinfoFromELF(section []byte) string {
+
+	var names []string
+	descs := make(map[string]string)
+	types := make(map[string]string)
+
+	for _, e := range bytes.Split(section, []byte{0}) {
+		kv := strings.SplitN(string(e), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		nv := strings.SplitN(kv[1], ":", 2)
+		if len(nv) != 2 {
+			continue
+		}
+
+		if _, ok := descs[nv[0]]; !ok {
+			if _, ok := types[nv[0]]; !ok {
+				names = append(names, nv[0])
+			}
+		}
+
+		switch kv[0] {
+		case "parm":
+			descs[nv[0]] = nv[1]
+		case "parmtype":
+			types[nv[0]] = nv[1]
+		}
+	}
+
+	var b strings.Builder
+
+	for _, n := range names {
+		fmt.Fprintf(&b, "parm:           %s:%s", n, descs[n])
+		if t, ok := types[n]; ok {
+			fmt.Fprintf(&b, " (%s)", t)
+		}
+		b.WriteByte(0)
+	}
+
+	return b.String()
+}
+
+// errFound stops the walk for the module once it was found
+var errFound = errors.New("found")
+
+// findModule returns the path of the zfs.ko of the running kernel.
+// Compressed modules can't be read with the standard library and are
+// ignored
+func findModule(ctx context.Context) (string, error) {
+
+	release, err := readFile(ctx, osReleaseKernelPath)
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(modulesDir, strings.TrimSpace(string(release)))
+	found := ""
+
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && info.Name() == "zfs.ko" {
+			found = path
+			return errFound
+		}
+		return nil
+	})
+
+	if found == "" {
+		return "", fmt.Errorf("no zfs.ko in %s", dir)
+	}
+
+	return found, nil
+}
+
+// readModuleInfo returns the .modinfo section of the zfs module in the
+// format of "modinfo -0"
+func readModuleInfo(ctx context.Context) (string, error) {
+
+	if bundleFiles != nil {
+		return "", errors.New("the module isn't in the bundle")
+	}
+
+	path, err := findModule(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := elf.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	s := f.Section(".modinfo")
+	if s == nil {
+		return "", fmt.Errorf("%s has no .modinfo section", path)
+	}
+
+	data, err := s.Data()
+	if err != nil {
+		return "", err
+	}
+
+	return modinfoFromELF(data), nil
+}
+
+// builtinModinfo returns the table of common tunables in the format of
+// "modinfo -0"
+func builtinModinfo() string {
+
+	var b strings.Builder
+
+	for n, t := range builtinTunables {
+		fmt.Fprintf(&b, "parm:           %s:%s (%s)", n, t.desc, t.typ)
+		b.WriteByte(0)
+	}
+
+	return b.String()
+}
+
+// modinfoMemo is the output of modinfo for the module version it was taken
+// from, so watch mode runs it only once
+var modinfoMemo struct {
+	version string
+	out     string
+}
+
+// defaultCacheDir returns the directory for the cache of the modinfo output,
+// or "" if the user has none
+func defaultCacheDir() string {
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "arc_summary")
+}
+
+// modinfoCachePath returns the cache file for a version of the module. The
+// version is part of the name, so an update of ZFS starts a new cache
+func modinfoCachePath(dir, version string) string {
+	return filepath.Join(dir, "modinfo-"+strings.Replace(version, "/", "_", -1))
+}
+
+// cachedModinfo returns the output of modinfo for the version of the module
+// from memory or the cache on disk
+func cachedModinfo(version string) (string, bool) {
+
+	if version == "" || bundleFiles != nil {
+		return "", false
+	}
+
+	if modinfoMemo.version == version {
+		return modinfoMemo.out, true
+	}
+
+	if *OptCacheDir == "" {
+		return "", false
+	}
+
+	data, err := ioutil.ReadFile(modinfoCachePath(*OptCacheDir, version))
+	if err != nil || len(data) == 0 {
+		return "", false
+	}
+
+	modinfoMemo.version, modinfoMemo.out = version, string(data)
+	return modinfoMemo.out, true
+}
+
+// storeModinfo keeps the output of modinfo for the next call and the next
+// run. A cache that can't be written is no reason to fail
+func storeModinfo(version, out string) {
+
+	if version == "" || bundleFiles != nil {
+		return
+	}
+
+	modinfoMemo.version, modinfoMemo.out = version, out
+
+	if *OptCacheDir == "" {
+		return
+	}
+
+	if err := os.MkdirAll(*OptCacheDir, 0755); err != nil {
+		return
+	}
+
+	path := modinfoCachePath(*OptCacheDir, version)
+	tmp := path + ".tmp"
+
+	if err := ioutil.WriteFile(tmp, []byte(out), 0644); err != nil {
+		os.Remove(tmp)
+		return
+	}
+
+	os.Rename(tmp, path)
+}
@@ -0,0 +1,97 @@
+// Test file for modinfo.go
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestModinfoFromELF(t *testing.T) {
+
+	section := []byte("license=CDDL\000" +
+		"parmtype=zfs_arc_max:ulong\000" +
+		"parm=zfs_arc_max:Max arc size\000" +
+		"parm=zfs_vdev_raidz_impl:Select raidz implementation: fastest (default)\000" +
+		"parmtype=zfs_vdev_raidz_impl:charp\000" +
+		"parmtype=zfs_nodesc:int\000")
+
+	descs := make(map[string]string)
+	types := make(map[string]string)
+	parseModinfo(modinfoFromELF(section), descs, types)
+
+	var tests = []struct {
+		name string
+		desc string
+		typ  string
+	}{
+		{"zfs_arc_max", "Max arc size", "ulong"},
+		{"zfs_vdev_raidz_impl", "Select raidz implementation: fastest (default)", "charp"},
+		{"zfs_nodesc", "", "int"},
+	}
+
+	for _, test := range tests {
+		if descs[test.name] != test.desc || types[test.name] != test.typ {
+			t.Errorf("modinfoFromELF() %s = \"%v\", \"%v\" (wanted \"%v\", \"%v\")",
+				test.name, descs[test.name], types[test.name], test.desc, test.typ)
+		}
+	}
+
+	if _, ok := descs["license"]; ok {
+		t.Errorf("modinfoFromELF() took license for a parameter")
+	}
+}
+
+func TestBuiltinModinfo(t *testing.T) {
+
+	descs := make(map[string]string)
+	types := make(map[string]string)
+	parseModinfo(builtinModinfo(), descs, types)
+
+	if descs["zfs_arc_max"] != "Max arc size" || types["zfs_arc_max"] != "ulong" {
+		t.Errorf("builtinModinfo() zfs_arc_max = \"%v\", \"%v\" (wanted \"Max arc size\", \"ulong\")",
+			descs["zfs_arc_max"], types["zfs_arc_max"])
+	}
+
+	if len(descs) != len(builtinTunables) {
+		t.Errorf("builtinModinfo() has %d tunables (wanted %d)", len(descs), len(builtinTunables))
+	}
+}
+
+func TestModinfoCache(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "arc_summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	saved := *OptCacheDir
+	*OptCacheDir = filepath.Join(dir, "cache")
+	defer func() {
+		*OptCacheDir = saved
+		modinfoMemo.version, modinfoMemo.out = "", ""
+	}()
+
+	if _, ok := cachedModinfo("2.1.5-1"); ok {
+		t.Errorf("cachedModinfo() of empty cache succeeded (wanted false)")
+	}
+
+	storeModinfo("2.1.5-1", "parm: zfs_arc_max:Max arc size (ulong)")
+
+	// A new run only has the cache on disk
+	modinfoMemo.version, modinfoMemo.out = "", ""
+
+	if out, ok := cachedModinfo("2.1.5-1"); !ok || out != "parm: zfs_arc_max:Max arc size (ulong)" {
+		t.Errorf("cachedModinfo(2.1.5-1) = %q, %v (wanted the stored output)", out, ok)
+	}
+
+	if _, ok := cachedModinfo("2.2.0-1"); ok {
+		t.Errorf("cachedModinfo(2.2.0-1) succeeded for another version (wanted false)")
+	}
+
+	if _, ok := cachedModinfo(""); ok {
+		t.Errorf("cachedModinfo() without a version succeeded (wanted false)")
+	}
+}
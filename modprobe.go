@@ -0,0 +1,146 @@
+// Parsing of modprobe.d configuration for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// ZFS module parameters set at boot live in "options zfs ..." lines in
+// /etc/modprobe.d. See arc_summary.go for the license
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const modprobePath = "/etc/modprobe.d"
+
+// modprobeOption is a single parameter from an "options zfs" line
+type modprobeOption struct {
+	file  string
+	line  int
+	name  string
+	value string
+}
+
+// logicalLine is a line of a modprobe.d file after joining continued lines,
+// with the number of the line it started on
+type logicalLine struct {
+	text string
+	line int
+}
+
+// logicalLines reads a modprobe.d file and joins lines that are continued
+// with a backslash
+func logicalLines(r io.Reader) ([]logicalLine, error) {
+
+	var result []logicalLine
+	var logical string
+	lineNo, startLine := 0, 0
+
+	input := bufio.NewScanner(r)
+
+	for input.Scan() {
+		lineNo++
+		l := input.Text()
+
+		if logical == "" {
+			startLine = lineNo
+		}
+
+		if strings.HasSuffix(l, "\\") {
+			logical += strings.TrimSuffix(l, "\\") + " "
+			continue
+		}
+
+		result = append(result, logicalLine{logical + l, startLine})
+		logical = ""
+	}
+
+	if logical != "" {
+		result = append(result, logicalLine{logical, startLine})
+	}
+
+	return result, input.Err()
+}
+
+// parseModprobe returns the zfs options in a modprobe.d file. Comments start
+// with '#'
+func parseModprobe(r io.Reader, file string) ([]modprobeOption, error) {
+
+	lines, err := logicalLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []modprobeOption
+
+	for _, l := range lines {
+		result = append(result, parseModprobeLine(l.text, file, l.line)...)
+	}
+
+	return result, nil
+}
+
+// parseModprobeLine returns the zfs options of a single logical line
+func parseModprobeLine(l, file string, line int) []modprobeOption {
+
+	var result []modprobeOption
+
+	if idx := strings.Index(l, "#"); idx != -1 {
+		l = l[:idx]
+	}
+
+	fields := strings.Fields(l)
+	if len(fields) < 3 || fields[0] != "options" || fields[1] != "zfs" {
+		return nil
+	}
+
+	for _, f := range fields[2:] {
+		name, value := f, ""
+		if idx := strings.Index(f, "="); idx != -1 {
+			name, value = f[:idx], f[idx+1:]
+		}
+		result = append(result, modprobeOption{file: file, line: line, name: name, value: value})
+	}
+
+	return result
+}
+
+// readModprobeDir returns the zfs options of all .conf files in dir in the
+// order modprobe reads them. Later settings override earlier ones
+func readModprobeDir(ctx context.Context, dir string) ([]modprobeOption, error) {
+
+	names, err := readDirNames(ctx, dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var result []modprobeOption
+
+	for _, n := range names {
+		if !strings.HasSuffix(n, ".conf") {
+			continue
+		}
+
+		fn := filepath.Join(dir, n)
+		data, err := readFile(ctx, fn)
+		if err != nil {
+			return nil, err
+		}
+
+		opts, err := parseModprobe(bytes.NewReader(data), fn)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, opts...)
+	}
+
+	return result, nil
+}
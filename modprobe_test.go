@@ -0,0 +1,59 @@
+// Test file for modprobe.go and obsolete.go
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseModprobe(t *testing.T) {
+	conf := `# ZFS tuning
+options zfs zfs_arc_max=8589934592 zfs_arc_min=1073741824
+options zfs zfs_prefetch_disable=1 \
+	zfs_txg_timeout=10 # longer txgs
+options spl spl_kmem_cache_slab_limit=16384
+blacklist zfs_foo
+`
+	want := []modprobeOption{
+		{"zfs.conf", 2, "zfs_arc_max", "8589934592"},
+		{"zfs.conf", 2, "zfs_arc_min", "1073741824"},
+		{"zfs.conf", 3, "zfs_prefetch_disable", "1"},
+		{"zfs.conf", 3, "zfs_txg_timeout", "10"},
+	}
+
+	got, err := parseModprobe(strings.NewReader(conf), "zfs.conf")
+	if err != nil {
+		t.Fatalf("parseModprobe() returned error: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseModprobe() = %v (wanted %v)", got, want)
+	}
+}
+
+func TestCheckTunableNames(t *testing.T) {
+	live := map[string]string{
+		"zfs_arc_max":        "0",
+		"zfs_arc_meta_limit": "0",
+	}
+
+	opts := []modprobeOption{
+		{"zfs.conf", 1, "zfs_arc_max", "1"},
+		{"zfs.conf", 2, "zil_slog_limit", "1"},
+		{"zfs.conf", 3, "zfs_arc_maxx", "1"},
+		{"zfs.conf", 4, "zfs_arc_meta_limit", "1"},
+		{"zfs.conf", 5, "zfs_arc_meta_min", "1"},
+	}
+
+	got := checkTunableNames(live, opts)
+	want := []string{
+		"zfs.conf:2: zil_slog_limit was replaced by zil_slog_bulk in OpenZFS 0.7",
+		"zfs.conf:3: zfs_arc_maxx is not a parameter of the running module",
+		"zfs.conf:5: zfs_arc_meta_min was replaced by zfs_arc_meta_balance in OpenZFS 2.2",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("checkTunableNames() = %v (wanted %v)", got, want)
+	}
+}
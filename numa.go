@@ -0,0 +1,207 @@
+// NUMA node memory for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// On NUMA machines, memory is split between nodes, and the ARC takes its
+// memory from whichever node the allocating thread happens to run on. When
+// one node fills up while others have memory to spare, processes on it get
+// slow remote memory or reclaim even though the machine as a whole isn't
+// short. The ARC doesn't report which node its memory is on, so we show the
+// free memory of each node from /sys/devices/system/node and leave the
+// conclusion to the reader. Linux only. See arc_summary.go for the license
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const numaPath = "/sys/devices/system/node"
+
+const (
+	// A node with less free memory than this fraction is nearly full
+	numaFullFraction = 0.05
+
+	// A node with more free memory than this fraction has room to spare
+	numaSpareFraction = 0.25
+)
+
+// numaNode is the memory of a NUMA node in bytes
+type numaNode struct {
+	id    int
+	total uint64
+	free  uint64
+}
+
+// parseNodeList parses a list of nodes such as "0-3,6" as found in the
+// online file
+func parseNodeList(s string) ([]int, error) {
+
+	var result []int
+
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		first, last := part, part
+		if idx := strings.Index(part, "-"); idx != -1 {
+			first, last = part[:idx], part[idx+1:]
+		}
+
+		from, err := strconv.Atoi(first)
+		if err != nil {
+			return nil, fmt.Errorf("bad node list '%s'", s)
+		}
+		to, err := strconv.Atoi(last)
+		if err != nil || to < from {
+			return nil, fmt.Errorf("bad node list '%s'", s)
+		}
+
+		for n := from; n <= to; n++ {
+			result = append(result, n)
+		}
+	}
+
+	return result, nil
+}
+
+// parseNodeMeminfo returns the values of the meminfo file of a node in
+// bytes. Lines look like "Node 0 MemFree:   3337612 kB"
+func parseNodeMeminfo(data []byte) map[string]uint64 {
+
+	m := make(map[string]uint64)
+	input := bufio.NewScanner(bytes.NewReader(data))
+
+	for input.Scan() {
+		fields := strings.Fields(input.Text())
+		if len(fields) < 4 || fields[0] != "Node" {
+			continue
+		}
+
+		value, err := strconv.ParseUint(fields[3], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		if len(fields) == 5 && fields[4] == "kB" {
+			value *= 1024
+		}
+
+		m[strings.TrimSuffix(fields[2], ":")] = value
+	}
+
+	return m
+}
+
+// numaNodeFile returns the path of a file of a node
+func numaNodeFile(id int, name string) string {
+	return fmt.Sprintf("%s/node%d/%s", numaPath, id, name)
+}
+
+// numaFiles returns the files we read for the NUMA nodes, for the bundle
+func numaFiles(ctx context.Context) []string {
+
+	data, err := readFile(ctx, numaPath+"/online")
+	if err != nil {
+		return nil
+	}
+
+	ids, err := parseNodeList(string(data))
+	if err != nil {
+		return nil
+	}
+
+	files := []string{numaPath + "/online"}
+	for _, id := range ids {
+		files = append(files, numaNodeFile(id, "meminfo"))
+	}
+
+	return files
+}
+
+// readNUMANodes returns the memory of the online NUMA nodes. Nodes without
+// memory are left out
+func readNUMANodes(ctx context.Context) ([]numaNode, error) {
+
+	data, err := readFile(ctx, numaPath+"/online")
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := parseNodeList(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []numaNode
+
+	for _, id := range ids {
+		data, err := readFile(ctx, numaNodeFile(id, "meminfo"))
+		if err != nil {
+			return nil, err
+		}
+
+		m := parseNodeMeminfo(data)
+		if m["MemTotal"] == 0 {
+			continue
+		}
+
+		nodes = append(nodes, numaNode{id: id, total: m["MemTotal"], free: m["MemFree"]})
+	}
+
+	return nodes, nil
+}
+
+// numaImbalance returns a warning if one node is nearly full while another
+// has plenty of memory left
+func numaImbalance(nodes []numaNode) string {
+
+	var full, spare *numaNode
+
+	for i, n := range nodes {
+		fraction := float64(n.free) / float64(n.total)
+		if fraction < numaFullFraction && full == nil {
+			full = &nodes[i]
+		}
+		if fraction > numaSpareFraction && spare == nil {
+			spare = &nodes[i]
+		}
+	}
+
+	if full == nil || spare == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("NUMA node %d is nearly full while node %d has %s free, allocations (including the ARC) are piling up on one node",
+		full.id, spare.id, fBytes(strconv.FormatUint(spare.free, 10)))
+}
+
+// printNUMA prints the memory of each NUMA node. Machines with a single
+// node have nothing to break down
+func printNUMA() {
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	nodes, err := readNUMANodes(ctx)
+	if err != nil || len(nodes) < 2 {
+		return
+	}
+
+	fmt.Fprintln(reportOut, "\nMemory by NUMA node:")
+
+	for _, n := range nodes {
+		total := strconv.FormatUint(n.total, 10)
+		used := strconv.FormatUint(n.total-n.free, 10)
+		prtL2p(fmt.Sprintf("Node %d used (of %s):", n.id, strings.TrimSpace(fBytes(total))), fPerc(used, total), fBytes(used))
+	}
+
+	if w := numaImbalance(nodes); w != "" {
+		addWarning("numa", "%s", w)
+	}
+}
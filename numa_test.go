@@ -0,0 +1,58 @@
+// Test file for numa.go
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseNodeList(t *testing.T) {
+
+	var tests = []struct {
+		input string
+		want  []int
+		ok    bool
+	}{
+		{"0\n", []int{0}, true},
+		{"0-3\n", []int{0, 1, 2, 3}, true},
+		{"0-1,4", []int{0, 1, 4}, true},
+		{"", nil, true},
+		{"3-1", nil, false},
+		{"a", nil, false},
+	}
+
+	for _, test := range tests {
+		got, err := parseNodeList(test.input)
+		if (err == nil) != test.ok || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parseNodeList(%q) = %v, %v (wanted \"%v\")", test.input, got, err, test.want)
+		}
+	}
+}
+
+func TestParseNodeMeminfo(t *testing.T) {
+
+	m := parseNodeMeminfo([]byte("Node 1 MemTotal:  1024 kB\nNode 1 MemFree:  512 kB\nNode 1 HugePages_Total:  0\n"))
+
+	wanted := map[string]uint64{"MemTotal": 1 << 20, "MemFree": 1 << 19, "HugePages_Total": 0}
+	if !reflect.DeepEqual(m, wanted) {
+		t.Errorf("parseNodeMeminfo() = %v (wanted \"%v\")", m, wanted)
+	}
+}
+
+func TestNUMAImbalance(t *testing.T) {
+
+	var tests = []struct {
+		nodes []numaNode
+		warn  bool
+	}{
+		{[]numaNode{{0, 16 * gib, 8 * gib}, {1, 16 * gib, 7 * gib}}, false},
+		{[]numaNode{{0, 16 * gib, gib / 4}, {1, 16 * gib, 8 * gib}}, true},
+		{[]numaNode{{0, 16 * gib, gib / 4}, {1, 16 * gib, gib / 2}}, false},
+	}
+
+	for i, test := range tests {
+		if got := numaImbalance(test.nodes) != ""; got != test.warn {
+			t.Errorf("numaImbalance(test %d) warns = %v (wanted \"%v\")", i, got, test.warn)
+		}
+	}
+}
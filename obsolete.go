@@ -0,0 +1,98 @@
+// Renamed and removed ZFS tunables for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// Setting a tunable that no longer exists does nothing, and modprobe doesn't
+// complain about it, so old configuration files silently stop working after
+// an upgrade. See arc_summary.go for the license
+package main
+
+import "fmt"
+
+// obsoleteTunable describes a tunable that was renamed or removed. An empty
+// replacement means there is none
+type obsoleteTunable struct {
+	replacement string
+	version     string
+}
+
+var obsoleteTunables = map[string]obsoleteTunable{
+	"zfs_arc_meta_limit":             {"zfs_arc_meta_balance", "2.2"},
+	"zfs_arc_meta_limit_percent":     {"zfs_arc_meta_balance", "2.2"},
+	"zfs_arc_meta_min":               {"zfs_arc_meta_balance", "2.2"},
+	"zfs_arc_meta_prune":             {"", "2.2"},
+	"zfs_arc_meta_adjust_restarts":   {"", "2.2"},
+	"zfs_arc_meta_strategy":          {"", "2.2"},
+	"zfs_arc_p_min_shift":            {"", "2.2"},
+	"zfs_arc_p_dampener_disable":     {"", "2.2"},
+	"zfs_vdev_cache_size":            {"", "2.2"},
+	"zfs_vdev_cache_max":             {"", "2.2"},
+	"zfs_vdev_cache_bshift":          {"", "2.2"},
+	"zfs_vdev_scheduler":             {"", "2.0"},
+	"zfs_arc_num_sublists_per_state": {"zfs_multilist_num_sublists", "0.8"},
+	"zfs_dirty_data_sync":            {"zfs_dirty_data_sync_percent", "0.8"},
+	"zfs_top_maxinflight":            {"", "0.8"},
+	"zfs_scan_idle":                  {"", "0.8"},
+	"zfs_scrub_delay":                {"", "0.8"},
+	"zfs_resilver_delay":             {"", "0.8"},
+	"zfs_mdcomp_disable":             {"", "0.8"},
+	"zil_slog_limit":                 {"zil_slog_bulk", "0.7"},
+}
+
+// obsoleteWarning describes an obsolete tunable in words
+func obsoleteWarning(name string, o obsoleteTunable) string {
+
+	if o.replacement == "" {
+		return fmt.Sprintf("%s was removed in OpenZFS %s", name, o.version)
+	}
+
+	return fmt.Sprintf("%s was replaced by %s in OpenZFS %s", name, o.replacement, o.version)
+}
+
+// checkTunableNames returns warnings for obsolete or unknown tunables set in
+// modprobe.d. A tunable the running module still has is not obsolete yet,
+// whatever later versions did with it
+func checkTunableNames(live map[string]string, opts []modprobeOption) []string {
+
+	var warnings []string
+
+	for _, opt := range opts {
+
+		if _, ok := live[opt.name]; ok {
+			continue
+		}
+
+		where := fmt.Sprintf("%s:%d: ", opt.file, opt.line)
+
+		if o, ok := obsoleteTunables[opt.name]; ok {
+			warnings = append(warnings, where+obsoleteWarning(opt.name, o))
+			continue
+		}
+
+		warnings = append(warnings, where+opt.name+" is not a parameter of the running module")
+	}
+
+	return warnings
+}
+
+// printTunableWarnings prints warnings about tunables that do nothing on the
+// running version of ZFS
+func printTunableWarnings() {
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	opts, err := readModprobeDir(ctx, modprobePath)
+	if err != nil {
+		fmt.Fprintln(reportOut, "\nCouldn't read", modprobePath+":", err)
+	}
+
+	warnings := checkTunableNames(tunables, opts)
+	if len(warnings) == 0 {
+		return
+	}
+
+	fmt.Fprintln(reportOut, "\nObsolete or unknown tunables:")
+	for _, w := range warnings {
+		fmt.Fprintln(reportOut, indent+w)
+	}
+}
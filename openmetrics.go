@@ -0,0 +1,190 @@
+// OpenMetrics output for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// Some ingestion systems only accept strict OpenMetrics rather than the
+// looser Prometheus text format. The difference is that every metric family
+// needs a type, counters end in _total, units are declared and the output
+// ends with "# EOF". Samples carry the time of the collection. There are no
+// exemplars, since we have no traces to point to. See arc_summary.go for the
+// license
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// arcGauges are the stats in arcstats that are current values rather than
+// counters and that don't end in _size. The byte sizes are marked with a
+// unit
+var arcGauges = map[string]string{
+	"anon_data":                    "bytes",
+	"anon_evictable_data":          "bytes",
+	"anon_evictable_metadata":      "bytes",
+	"anon_metadata":                "bytes",
+	"arc_dnode_limit":              "bytes",
+	"arc_loaned_bytes":             "bytes",
+	"arc_meta_limit":               "bytes",
+	"arc_meta_max":                 "bytes",
+	"arc_meta_min":                 "bytes",
+	"arc_meta_used":                "bytes",
+	"arc_need_free":                "bytes",
+	"arc_no_grow":                  "",
+	"arc_sys_free":                 "bytes",
+	"arc_tempreserve":              "bytes",
+	"c":                            "bytes",
+	"c_max":                        "bytes",
+	"c_min":                        "bytes",
+	"memory_all_bytes":             "bytes",
+	"memory_available_bytes":       "bytes",
+	"memory_free_bytes":            "bytes",
+	"mfu_data":                     "bytes",
+	"mfu_evictable_data":           "bytes",
+	"mfu_evictable_metadata":       "bytes",
+	"mfu_ghost_data":               "bytes",
+	"mfu_ghost_evictable_data":     "bytes",
+	"mfu_ghost_evictable_metadata": "bytes",
+	"mfu_ghost_metadata":           "bytes",
+	"mfu_metadata":                 "bytes",
+	"mru_data":                     "bytes",
+	"mru_evictable_data":           "bytes",
+	"mru_evictable_metadata":       "bytes",
+	"mru_ghost_data":               "bytes",
+	"mru_ghost_evictable_data":     "bytes",
+	"mru_ghost_evictable_metadata": "bytes",
+	"mru_ghost_metadata":           "bytes",
+	"mru_metadata":                 "bytes",
+	"p":                            "bytes",
+	"size":                         "bytes",
+	"uncached_data":                "bytes",
+	"uncached_evictable_data":      "bytes",
+	"uncached_evictable_metadata":  "bytes",
+	"uncached_metadata":            "bytes",
+}
+
+// omFamily is one metric family with its samples, which are complete lines
+// without the timestamp
+type omFamily struct {
+	name    string
+	typ     string
+	unit    string
+	samples []string
+}
+
+// kstatType returns if a kstat is a counter or a gauge and its unit, which
+// is "bytes" or empty
+func kstatType(file, stat string) (string, string) {
+
+	if u, ok := arcGauges[stat]; ok && file == "arcstats" {
+		return "gauge", u
+	}
+
+	if strings.HasSuffix(stat, "_size") || strings.HasSuffix(stat, "_asize") {
+		return "gauge", "bytes"
+	}
+
+	if strings.HasSuffix(stat, "_bytes") {
+		return "counter", "bytes"
+	}
+
+	return "counter", ""
+}
+
+// omKstatFamily returns the family for a kstat
+func omKstatFamily(file, stat string, v uint64) omFamily {
+
+	name := promName("zfs", promSection(file), stat)
+	typ, unit := kstatType(file, stat)
+
+	if unit != "" && !strings.HasSuffix(name, "_"+unit) {
+		name += "_" + unit
+	}
+
+	sample := name
+	if typ == "counter" {
+		sample += "_total"
+	}
+
+	return omFamily{name, typ, unit, []string{fmt.Sprintf("%s %d", sample, v)}}
+}
+
+// openMetricsRenderer writes the StatsSet in OpenMetrics format
+type openMetricsRenderer struct{}
+
+func (openMetricsRenderer) Render(w io.Writer, s *StatsSet) error {
+
+	var families []omFamily
+
+	for file, m := range s.Kstats {
+		for stat, v := range m {
+			families = append(families, omKstatFamily(file, stat, v))
+		}
+	}
+
+	for name, v := range s.Tunables {
+		if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+			n := promName("zfs_tunable", name)
+			families = append(families, omFamily{n, "gauge", "", []string{n + " " + v}})
+		}
+	}
+
+	if len(s.Derived) > 0 {
+		f := omFamily{name: "arc_summary_derived", typ: "gauge"}
+		for n, v := range s.Derived {
+			f.samples = append(f.samples, fmt.Sprintf("arc_summary_derived{name=%q} %s", n,
+				strconv.FormatFloat(v, 'g', -1, 64)))
+		}
+		families = append(families, f)
+	}
+
+	if len(s.Averages) > 0 {
+		families = append(families, omFamily{"arc_summary_rate_average", "gauge", "", averageLines(s.Averages)})
+	}
+
+	if len(s.Self.Durations) > 0 {
+		f := omFamily{name: "arc_summary_collect_duration_seconds", typ: "gauge", unit: "seconds"}
+		for n, v := range s.Self.Durations {
+			f.samples = append(f.samples, fmt.Sprintf("arc_summary_collect_duration_seconds{file=%q} %s", n,
+				strconv.FormatFloat(v, 'g', -1, 64)))
+		}
+		families = append(families, f)
+	}
+
+	families = append(families,
+		omFamily{"arc_summary_read_errors", "gauge", "", []string{fmt.Sprintf("arc_summary_read_errors %d", s.Self.ReadErrors)}},
+		omFamily{"arc_summary_parse_errors", "gauge", "", []string{fmt.Sprintf("arc_summary_parse_errors %d", s.Self.ParseErrors)}})
+
+	if s.Self.LastSuccess != 0 {
+		families = append(families, omFamily{"arc_summary_last_success_timestamp_seconds", "gauge", "seconds",
+			[]string{fmt.Sprintf("arc_summary_last_success_timestamp_seconds %d", s.Self.LastSuccess)}})
+	}
+
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	ts := ""
+	if !s.Time.IsZero() {
+		ts = " " + strconv.FormatFloat(float64(s.Time.UnixNano())/1e9, 'f', 3, 64)
+	}
+
+	var b strings.Builder
+
+	for _, f := range families {
+		fmt.Fprintf(&b, "# TYPE %s %s\n", f.name, f.typ)
+		if f.unit != "" {
+			fmt.Fprintf(&b, "# UNIT %s %s\n", f.name, f.unit)
+		}
+
+		sort.Strings(f.samples)
+		for _, l := range f.samples {
+			b.WriteString(l + ts + "\n")
+		}
+	}
+
+	b.WriteString("# EOF\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,60 @@
+// Test file for openmetrics.go
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOmKstatFamily(t *testing.T) {
+
+	var tests = []struct {
+		file, stat string
+		name, typ  string
+		unit       string
+		sample     string
+	}{
+		{"arcstats", "hits", "zfs_arc_hits", "counter", "", "zfs_arc_hits_total 5"},
+		{"arcstats", "size", "zfs_arc_size_bytes", "gauge", "bytes", "zfs_arc_size_bytes 5"},
+		{"arcstats", "c_max", "zfs_arc_c_max_bytes", "gauge", "bytes", "zfs_arc_c_max_bytes 5"},
+		{"arcstats", "arc_no_grow", "zfs_arc_arc_no_grow", "gauge", "", "zfs_arc_arc_no_grow 5"},
+		{"arcstats", "memory_free_bytes", "zfs_arc_memory_free_bytes", "gauge", "bytes", "zfs_arc_memory_free_bytes 5"},
+		{"arcstats", "l2_read_bytes", "zfs_arc_l2_read_bytes", "counter", "bytes", "zfs_arc_l2_read_bytes_total 5"},
+		{"zil", "c", "zfs_zil_c", "counter", "", "zfs_zil_c_total 5"},
+	}
+
+	for _, test := range tests {
+		f := omKstatFamily(test.file, test.stat, 5)
+		if f.name != test.name || f.typ != test.typ || f.unit != test.unit || f.samples[0] != test.sample {
+			t.Errorf("omKstatFamily(%s, %s) = %+v (wanted \"%v %v %v %v\")", test.file, test.stat, f,
+				test.name, test.typ, test.unit, test.sample)
+		}
+	}
+}
+
+func TestOpenMetricsRenderer(t *testing.T) {
+
+	s := &StatsSet{
+		Time:   time.Unix(1500000000, 0),
+		Kstats: map[string]map[string]uint64{"arcstats": {"hits": 10}},
+	}
+
+	var buf bytes.Buffer
+	if err := (openMetricsRenderer{}).Render(&buf, s); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+
+	for _, w := range []string{"# TYPE zfs_arc_hits counter\nzfs_arc_hits_total 10 1500000000.000\n"} {
+		if !strings.Contains(out, w) {
+			t.Errorf("openMetricsRenderer.Render() = %v (wanted \"%v\")", out, w)
+		}
+	}
+
+	if !strings.HasSuffix(out, "\n# EOF\n") {
+		t.Errorf("openMetricsRenderer.Render() doesn't end with # EOF")
+	}
+}
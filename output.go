@@ -0,0 +1,423 @@
+// Collectors, formatters and renderers for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// A Collector gathers the stats into a StatsSet, a Formatter turns a single
+// value into a string for humans and a Renderer writes a whole StatsSet in
+// some output format. New output formats only need a Renderer added to the
+// renderers map. See arc_summary.go for the license
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StatsSet is one collection of all stats. Kstats are keyed by the short
+// name of their file (eg "arcstats") and then by the name of the stat
+type StatsSet struct {
+	Time     time.Time                    `json:"time"`
+	Seq      int64                        `json:"seq,omitempty"`
+	Kstats   map[string]map[string]uint64 `json:"kstats"`
+	Tunables map[string]string            `json:"tunables"`
+	Derived  map[string]float64           `json:"derived,omitempty"`
+	Averages map[string]float64           `json:"averages,omitempty"`
+	Self     SelfStats                    `json:"self"`
+}
+
+// SelfStats are about the collection itself rather than ZFS, so monitoring
+// can tell when the tool is the problem. LastSuccess is the Unix time of the
+// last collection that could read every kstat file, which can be older than
+// the StatsSet in watch mode
+type SelfStats struct {
+	Durations   map[string]float64 `json:"collect_seconds"`
+	ReadErrors  int                `json:"read_errors"`
+	ParseErrors int                `json:"parse_errors"`
+	LastSuccess int64              `json:"last_success"`
+}
+
+// lastSuccess is the time of the last collection without read errors
+var lastSuccess time.Time
+
+// Collector gathers stats from some source
+type Collector interface {
+	Collect(ctx context.Context) (*StatsSet, error)
+}
+
+// Formatter turns a value into a string for humans
+type Formatter interface {
+	Format(value float64) string
+}
+
+// Renderer writes a StatsSet in an output format
+type Renderer interface {
+	Render(w io.Writer, s *StatsSet) error
+}
+
+// statusRenderer is a Renderer whose output format also sets the exit code,
+// such as the check formats
+type statusRenderer interface {
+	Renderer
+	Status(s *StatsSet) int
+}
+
+// textfileName is the file written to -textfile-dir
+const textfileName = "arc_summary.prom"
+
+// renderers are the output formats known to -o
+var renderers = map[string]Renderer{
+	"checkmk":     checkmkRenderer{},
+	"json":        jsonRenderer{},
+	"openmetrics": openMetricsRenderer{},
+	"prometheus":  prometheusRenderer{},
+	"prtg":        prtgRenderer{},
+	"prtg-xml":    prtgXMLRenderer{},
+	"sensu":       sensuRenderer{},
+	"text":        textRenderer{},
+}
+
+// rendererNames returns the names of the output formats in alphabetical order
+func rendererNames() []string {
+
+	var names []string
+
+	for n := range renderers {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// kstatCollector reads the kstats and tunables of the running system, or of
+// the bundle given with -bundle
+type kstatCollector struct{}
+
+func (kstatCollector) Collect(ctx context.Context) (*StatsSet, error) {
+
+	getKstats(ctx, kstats)
+	getTunables(ctx, tunables)
+
+	if len(kstats) == 0 {
+		return nil, fmt.Errorf("couldn't read any kstats from %s", procPath)
+	}
+
+	return newStatsSet(), nil
+}
+
+// newStatsSet builds a StatsSet from the stats that were last read into the
+// globals
+func newStatsSet() *StatsSet {
+
+	s := &StatsSet{
+		Time:     time.Now(),
+		Seq:      sampleSeq,
+		Kstats:   make(map[string]map[string]uint64),
+		Tunables: make(map[string]string),
+		Averages: averages.values(),
+	}
+
+	s.Self.Durations = make(map[string]float64)
+	for k, d := range kstatDurations {
+		s.Self.Durations[k] = d.Seconds()
+	}
+
+	s.Self.ReadErrors = kstatReadErrors() + len(unreadableTunables)
+	if kstatReadErrors() == 0 {
+		lastSuccess = s.Time
+	}
+	if !lastSuccess.IsZero() {
+		s.Self.LastSuccess = lastSuccess.Unix()
+	}
+
+	for section, lines := range kstats {
+		m := make(map[string]uint64)
+		for _, l := range lines {
+			if name, value, ok := parseKstatLine(l); ok {
+				m[name] = value
+			} else {
+				s.Self.ParseErrors++
+			}
+		}
+		s.Kstats[section] = m
+	}
+
+	for k, v := range tunables {
+		s.Tunables[k] = v
+	}
+
+	for _, d := range cfg.Derived {
+		if result, err := evalExpr(d.Expr, lookupStat); err == nil {
+			if s.Derived == nil {
+				s.Derived = make(map[string]float64)
+			}
+			s.Derived[d.Name] = result
+		}
+	}
+
+	return s
+}
+
+// parseKstatLine returns the name and value of a line of kstat data, eg
+// "hits   4   12345". Lines that aren't numbers are skipped and counted as
+// parse errors
+func parseKstatLine(l string) (string, uint64, bool) {
+
+	fields := strings.Fields(l)
+	if len(fields) != 3 {
+		return "", 0, false
+	}
+
+	v, ok := parseKstatValue(fields[2])
+	if !ok {
+		return "", 0, false
+	}
+
+	return fields[0], v, true
+}
+
+// parseKstatValue reads the value of a kstat. Counters are unsigned, but a
+// few gauges such as memory_available_bytes are signed and can drop below
+// zero, which we show as zero
+func parseKstatValue(s string) (uint64, bool) {
+
+	if v, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return v, true
+	}
+
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil && v < 0 {
+		return 0, true
+	}
+
+	return 0, false
+}
+
+type bytesFormatter struct{}
+
+func (bytesFormatter) Format(v float64) string {
+	return fBytes(strconv.FormatUint(uint64(clampZero(v)), 10))
+}
+
+type hitsFormatter struct{}
+
+func (hitsFormatter) Format(v float64) string {
+	return fHits(strconv.FormatUint(uint64(clampZero(v)), 10))
+}
+
+type percentFormatter struct{}
+
+func (percentFormatter) Format(v float64) string {
+	return fmt.Sprintf("%0.1f %%", v)
+}
+
+// unitFormatter prints the value with the unit appended, if there is one
+type unitFormatter string
+
+func (u unitFormatter) Format(v float64) string {
+
+	result := strconv.FormatFloat(v, 'f', 2, 64)
+
+	if u != "" {
+		result += " " + string(u)
+	}
+
+	return result
+}
+
+// formatterFor returns the formatter for a unit as used in the configuration
+// file
+func formatterFor(unit string) Formatter {
+
+	switch unit {
+	case "bytes":
+		return bytesFormatter{}
+	case "hits":
+		return hitsFormatter{}
+	case "percent":
+		return percentFormatter{}
+	}
+
+	return unitFormatter(unit)
+}
+
+// textRenderer writes the normal report from the StatsSet. The print
+// functions work on the global stats, so these are replaced by the set first
+type textRenderer struct{}
+
+func (textRenderer) Render(w io.Writer, s *StatsSet) error {
+	useStatsSet(s)
+	return printTo(w, printReport)
+}
+
+// useStatsSet replaces the global kstats and tunables by those of a
+// StatsSet. Kstat files the set doesn't have are treated as missing
+func useStatsSet(s *StatsSet) {
+
+	for _, file := range sectionPaths {
+		m, ok := s.Kstats[file]
+		if !ok {
+			delete(kstats, file)
+			if _, failed := kstatErrors[file]; !failed {
+				kstatErrors[file] = os.ErrNotExist
+			}
+			continue
+		}
+
+		var lines []string
+		for name, v := range m {
+			lines = append(lines, fmt.Sprintf("%s 4 %d", name, v))
+		}
+		sort.Strings(lines)
+
+		kstats[file] = lines
+		delete(kstatErrors, file)
+	}
+
+	for k := range tunables {
+		delete(tunables, k)
+	}
+	for k, v := range s.Tunables {
+		tunables[k] = v
+	}
+}
+
+// jsonRenderer writes the StatsSet as a JSON object
+type jsonRenderer struct{}
+
+func (jsonRenderer) Render(w io.Writer, s *StatsSet) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// prometheusRenderer writes the StatsSet in the Prometheus text exposition
+// format. Kstats become zfs_<prefix>_<name> with the prefix from
+// promPrefixes, numeric tunables zfs_tunable_<name>
+type prometheusRenderer struct{}
+
+var promNameRE = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// promPrefixes are the metric name prefixes for the kstat files, following
+// the names node_exporter uses. Files not listed keep their name
+var promPrefixes = map[string]string{
+	"arcstats":         "arc",
+	"vdev_cache_stats": "vdev_cache",
+	"xuio_stats":       "xuio",
+	"zfetchstats":      "zfetch",
+}
+
+// promSection returns the metric name prefix for a kstat file
+func promSection(file string) string {
+	if p, ok := promPrefixes[file]; ok {
+		return p
+	}
+	return file
+}
+
+// promName turns a name into a legal Prometheus metric name
+func promName(parts ...string) string {
+	return promNameRE.ReplaceAllString(strings.Join(parts, "_"), "_")
+}
+
+func (prometheusRenderer) Render(w io.Writer, s *StatsSet) error {
+
+	var lines []string
+
+	for section, m := range s.Kstats {
+		for name, v := range m {
+			lines = append(lines, fmt.Sprintf("%s %d", promName("zfs", promSection(section), name), v))
+		}
+	}
+
+	for name, v := range s.Tunables {
+		if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+			lines = append(lines, fmt.Sprintf("%s %s", promName("zfs_tunable", name), v))
+		}
+	}
+
+	sort.Strings(lines)
+
+	var names []string
+	for n := range s.Derived {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	for _, n := range names {
+		lines = append(lines, fmt.Sprintf("arc_summary_derived{name=%q} %s", n,
+			strconv.FormatFloat(s.Derived[n], 'g', -1, 64)))
+	}
+
+	lines = append(lines, averageLines(s.Averages)...)
+	lines = append(lines, renderSelfProm(s.Self)...)
+
+	for _, l := range lines {
+		if _, err := fmt.Fprintln(w, l); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// renderSelfProm returns the lines for the stats about the collection itself
+func renderSelfProm(self SelfStats) []string {
+
+	var names []string
+	for n := range self.Durations {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	var lines []string
+
+	for _, n := range names {
+		lines = append(lines, fmt.Sprintf("arc_summary_collect_duration_seconds{file=%q} %s", n,
+			strconv.FormatFloat(self.Durations[n], 'g', -1, 64)))
+	}
+
+	lines = append(lines,
+		fmt.Sprintf("arc_summary_read_errors %d", self.ReadErrors),
+		fmt.Sprintf("arc_summary_parse_errors %d", self.ParseErrors))
+
+	if self.LastSuccess != 0 {
+		lines = append(lines, fmt.Sprintf("arc_summary_last_success_timestamp_seconds %d", self.LastSuccess))
+	}
+
+	return lines
+}
+
+// writeTextfile writes the StatsSet in Prometheus format to arc_summary.prom
+// in dir for the textfile collector of node_exporter. The file is replaced
+// atomically, so node_exporter never sees half of it
+func writeTextfile(dir string, s *StatsSet) error {
+
+	path := filepath.Join(dir, textfileName)
+	tmp := path + ".tmp"
+
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	if err := (prometheusRenderer{}).Render(f, s); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
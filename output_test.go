@@ -0,0 +1,132 @@
+// Test file for output.go
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseKstatLine(t *testing.T) {
+
+	var tests = []struct {
+		input string
+		name  string
+		value uint64
+		ok    bool
+	}{
+		{"hits                            4    12345", "hits", 12345, true},
+		{"memory_available_bytes          3    -1024", "memory_available_bytes", 0, true},
+		{"huge                            4    18446744073709551615", "huge", 18446744073709551615, true},
+		{"name                            type data", "", 0, false},
+		{"broken", "", 0, false},
+	}
+
+	for _, test := range tests {
+		name, value, ok := parseKstatLine(test.input)
+		if name != test.name || value != test.value || ok != test.ok {
+			t.Errorf("parseKstatLine(%s) = %v, %v, %v (wanted \"%v, %v, %v\")",
+				test.input, name, value, ok, test.name, test.value, test.ok)
+		}
+	}
+}
+
+func TestFormatterFor(t *testing.T) {
+
+	var tests = []struct {
+		unit   string
+		value  float64
+		wanted string
+	}{
+		{"bytes", 2048, "2.0 KiB"},
+		{"percent", 12.345, "12.3 %"},
+		{"", 1.5, "1.50"},
+		{"ops/s", 1.5, "1.50 ops/s"},
+	}
+
+	for _, test := range tests {
+		if got := formatterFor(test.unit).Format(test.value); got != test.wanted {
+			t.Errorf("formatterFor(%s).Format(%v) = %v (wanted \"%v\")", test.unit, test.value, got, test.wanted)
+		}
+	}
+}
+
+func TestPrometheusRenderer(t *testing.T) {
+
+	s := &StatsSet{
+		Kstats:   map[string]map[string]uint64{"arcstats": {"hits": 10, "misses": 2}},
+		Tunables: map[string]string{"zfs_arc_max": "0", "spa_config_path": "/etc/zfs/zpool.cache"},
+		Derived:  map[string]float64{"hit ratio": 83.5},
+	}
+
+	var buf bytes.Buffer
+	if err := (prometheusRenderer{}).Render(&buf, s); err != nil {
+		t.Fatal(err)
+	}
+
+	wanted := "zfs_arc_hits 10\nzfs_arc_misses 2\nzfs_tunable_zfs_arc_max 0\n" +
+		"arc_summary_derived{name=\"hit ratio\"} 83.5\n" +
+		"arc_summary_read_errors 0\narc_summary_parse_errors 0\n"
+
+	if got := buf.String(); got != wanted {
+		t.Errorf("prometheusRenderer.Render() = %v (wanted \"%v\")", got, wanted)
+	}
+}
+
+func TestWriteTextfile(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "arc_summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := &StatsSet{Kstats: map[string]map[string]uint64{"arcstats": {"hits": 10}}}
+
+	if err := writeTextfile(dir, s); err != nil {
+		t.Fatal(err)
+	}
+
+	names, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(names) != 1 || filepath.Base(names[0]) != textfileName {
+		t.Errorf("writeTextfile() left %v (wanted only \"%v\")", names, textfileName)
+	}
+
+	data, _ := ioutil.ReadFile(filepath.Join(dir, textfileName))
+	if !bytes.Contains(data, []byte("zfs_arc_hits 10\n")) {
+		t.Errorf("writeTextfile() wrote %s (wanted \"zfs_arc_hits 10\")", data)
+	}
+}
+
+func TestUseStatsSet(t *testing.T) {
+
+	defer func() {
+		kstats = make(map[string][]string)
+		kstatErrors = make(map[string]error)
+		tunables = make(map[string]string)
+	}()
+
+	kstats["zil"] = []string{"zil_commit_count 4 1"}
+	tunables["zfs_arc_min"] = "0"
+
+	useStatsSet(&StatsSet{
+		Kstats:   map[string]map[string]uint64{"arcstats": {"size": 18446744073709551615, "hits": 10}},
+		Tunables: map[string]string{"zfs_arc_max": "0"},
+	})
+
+	wanted := []string{"hits 4 10", "size 4 18446744073709551615"}
+	if got := kstats["arcstats"]; !reflect.DeepEqual(got, wanted) {
+		t.Errorf("useStatsSet() arcstats = %v (wanted \"%v\")", got, wanted)
+	}
+
+	if _, ok := kstats["zil"]; ok || !os.IsNotExist(kstatErrors["zil"]) {
+		t.Errorf("useStatsSet() zil = %v, %v (wanted missing)", kstats["zil"], kstatErrors["zil"])
+	}
+
+	if len(tunables) != 1 || tunables["zfs_arc_max"] != "0" {
+		t.Errorf("useStatsSet() tunables = %v (wanted only zfs_arc_max)", tunables)
+	}
+}
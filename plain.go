@@ -0,0 +1,53 @@
+// Plain output for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// With -plain, the report is printed as simple "label: value" lines without
+// rulers, graphics, indentation or padding. This works better with screen
+// readers and is trivial to parse. See arc_summary.go for the license
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// plainLine returns a line of plain output. Empty parts are left out, and
+// the percentage goes after the value
+func plainLine(msg, perc, value string) string {
+
+	label := strings.TrimSuffix(strings.TrimSpace(msg), ":")
+	value = strings.TrimSpace(value)
+	perc = strings.TrimSpace(perc)
+
+	if perc != "" {
+		if value != "" {
+			value += " (" + perc + ")"
+		} else {
+			value = perc
+		}
+	}
+
+	if value == "" {
+		return label + ":"
+	}
+
+	return label + ": " + value
+}
+
+// plainList joins label/value pairs into one plain line, eg "reads: 10,
+// writes: 20"
+func plainList(pairs ...string) string {
+
+	var parts []string
+
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, plainLine(pairs[i], "", pairs[i+1]))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// printPlain prints a line of plain output
+func printPlain(msg, perc, value string) {
+	fmt.Fprintln(reportOut, plainLine(msg, perc, value))
+}
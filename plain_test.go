@@ -0,0 +1,34 @@
+// Test file for plain.go
+package main
+
+import "testing"
+
+func TestPlainLine(t *testing.T) {
+
+	var tests = []struct {
+		msg, perc, value string
+		want             string
+	}{
+		{"ARC summary:", "", "HEALTHY", "ARC summary: HEALTHY"},
+		{"ARC size:", "46.9 %", "7.0 GiB", "ARC size: 7.0 GiB (46.9 %)"},
+		{"Pool tank:", "", " ", "Pool tank:"},
+		{"Hit ratio", "90.0 %", "", "Hit ratio: 90.0 %"},
+		{"  zfs_arc_max  ", "", "  0  ", "zfs_arc_max: 0"},
+	}
+
+	for _, test := range tests {
+		if got := plainLine(test.msg, test.perc, test.value); got != test.want {
+			t.Errorf("plainLine(%s, %s, %s) = %v (wanted \"%v\")", test.msg, test.perc, test.value, got, test.want)
+		}
+	}
+}
+
+func TestPlainList(t *testing.T) {
+
+	got := plainList("reads", "10", "writes", "20")
+	want := "reads: 10, writes: 20"
+
+	if got != want {
+		t.Errorf("plainList(reads, 10, writes, 20) = %v (wanted \"%v\")", got, want)
+	}
+}
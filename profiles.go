@@ -0,0 +1,217 @@
+// Workload tuning profiles for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// Each profile describes the stat ranges we expect to see for a type of
+// workload and the tunables usually recommended for it. With -profile, the
+// live system is compared against the profile. See arc_summary.go for the
+// license
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// profileCheck is a stat that should lie between min and max. Expr uses the
+// same syntax as -expr
+type profileCheck struct {
+	desc string
+	expr string
+	min  float64
+	max  float64
+}
+
+// profileTunable is a recommended value for a tunable. The value "nonzero"
+// means that the tunable should be set at all, since the right value depends
+// on the machine
+type profileTunable struct {
+	name   string
+	value  string
+	reason string
+}
+
+type workloadProfile struct {
+	desc     string
+	checks   []profileCheck
+	tunables []profileTunable
+}
+
+const (
+	exprHitRatio         = "hits/(hits+misses)*100"
+	exprDemandDataRatio  = "demand_data_hits/(demand_data_hits+demand_data_misses)*100"
+	exprDemandMetaRatio  = "demand_metadata_hits/(demand_metadata_hits+demand_metadata_misses)*100"
+	exprPrefetchRatio    = "prefetch_data_hits/(prefetch_data_hits+prefetch_data_misses)*100"
+	exprPrefetchHitShare = "(prefetch_data_hits+prefetch_metadata_hits)/hits*100"
+)
+
+var profiles = map[string]workloadProfile{
+	"fileserver": {
+		desc: "NFS/SMB file serving with many clients and mostly cached metadata",
+		checks: []profileCheck{
+			{"ARC hit ratio (%)", exprHitRatio, 85, 100},
+			{"Demand metadata hit ratio (%)", exprDemandMetaRatio, 95, 100},
+			{"Memory throttle count", "memory_throttle_count", 0, 0},
+		},
+		tunables: []profileTunable{
+			{"zfs_prefetch_disable", "0", "sequential file reads profit from prefetch"},
+		},
+	},
+	"database": {
+		desc: "Databases with small random reads and synchronous writes",
+		checks: []profileCheck{
+			{"Demand data hit ratio (%)", exprDemandDataRatio, 90, 100},
+			{"Prefetch share of hits (%)", exprPrefetchHitShare, 0, 20},
+			{"Memory throttle count", "memory_throttle_count", 0, 0},
+		},
+		tunables: []profileTunable{
+			{"zfs_prefetch_disable", "1", "random I/O gains little from prefetch"},
+			{"zfs_arc_max", "nonzero", "leave room for the database's own cache"},
+		},
+	},
+	"vmhost": {
+		desc: "Hypervisor storing virtual machine images",
+		checks: []profileCheck{
+			{"ARC hit ratio (%)", exprHitRatio, 80, 100},
+			{"Memory throttle count", "memory_throttle_count", 0, 0},
+			{"Memory direct reclaims", "memory_direct_count", 0, 1000},
+		},
+		tunables: []profileTunable{
+			{"zfs_arc_max", "nonzero", "guests need the memory more than the ARC"},
+		},
+	},
+	"backup": {
+		desc: "Backup target with large sequential writes and few reads",
+		checks: []profileCheck{
+			{"Prefetch data hit ratio (%)", exprPrefetchRatio, 50, 100},
+			{"Memory throttle count", "memory_throttle_count", 0, 0},
+		},
+		tunables: []profileTunable{
+			{"zfs_prefetch_disable", "0", "restores read large files sequentially"},
+		},
+	},
+}
+
+// status returns "ok" if the value is in the range of the check, else "LOW"
+// or "HIGH"
+func (c profileCheck) status(value float64) string {
+
+	switch {
+	case value < c.min:
+		return "LOW"
+	case value > c.max:
+		return "HIGH"
+	}
+
+	return "ok"
+}
+
+// deviation describes how the current value differs from the recommended
+// one, or returns "" if it doesn't
+func (t profileTunable) deviation(current string) string {
+
+	switch {
+	case t.value == "nonzero" && current == "0":
+		return fmt.Sprintf("%s is not set (%s)", t.name, t.reason)
+	case t.value != "nonzero" && current != t.value:
+		return fmt.Sprintf("%s is %s, recommended %s (%s)", t.name, current, t.value, t.reason)
+	}
+
+	return ""
+}
+
+// profileNames returns the names of all built-in profiles in alphabetical
+// order
+func profileNames() []string {
+
+	var names []string
+
+	for n := range profiles {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// printProfile compares the live system against the named profile and prints
+// the result, listing any deviations
+func printProfile(name string) {
+
+	p, ok := profiles[name]
+	if !ok {
+		log.Fatal("Unknown profile '", name, "' (available: ", strings.Join(profileNames(), ", "), ")")
+	}
+
+	var deviations []string
+
+	printTitle("profile: " + name)
+	fmt.Fprintf(reportOut, "\n%s\n", p.desc)
+	fmt.Fprintln(reportOut, "\nStat checks:")
+
+	for _, c := range p.checks {
+
+		value, err := evalExpr(c.expr, lookupStat)
+		if err != nil {
+			prtL2(c.desc+":", "n/a")
+			continue
+		}
+
+		status := c.status(value)
+		prtL2(c.desc+":", fmt.Sprintf("%s %4s", strconv.FormatFloat(value, 'f', 1, 64), status))
+
+		if status != "ok" {
+			deviations = append(deviations, fmt.Sprintf("%s is %.1f, expected %s",
+				c.desc, value, fRange(c.min, c.max)))
+		}
+	}
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	getTunables(ctx, tunables)
+	fmt.Fprintln(reportOut, "\nRecommended tunables:")
+
+	for _, t := range p.tunables {
+
+		current, ok := tunables[t.name]
+		if !ok {
+			prtL2(t.name+":", "n/a")
+			continue
+		}
+
+		prtL2(t.name+":", current)
+
+		if d := t.deviation(current); d != "" {
+			deviations = append(deviations, d)
+		}
+	}
+
+	fmt.Fprintln(reportOut, "\nDeviations:")
+
+	if len(deviations) == 0 {
+		fmt.Fprintln(reportOut, indent+"None, system matches the profile")
+		return
+	}
+
+	for _, d := range deviations {
+		fmt.Fprintln(reportOut, indent+d)
+	}
+}
+
+// fRange describes the range of a check in words
+func fRange(min, max float64) string {
+
+	var result string
+
+	switch {
+	case min == max:
+		result = strconv.FormatFloat(min, 'f', -1, 64)
+	default:
+		result = strconv.FormatFloat(min, 'f', -1, 64) + " to " + strconv.FormatFloat(max, 'f', -1, 64)
+	}
+
+	return result
+}
@@ -0,0 +1,83 @@
+// Test file for profiles.go
+package main
+
+import (
+	"testing"
+)
+
+func TestProfileCheckStatus(t *testing.T) {
+
+	var tests = []struct {
+		c      profileCheck
+		value  float64
+		wanted string
+	}{
+		{profileCheck{min: 85, max: 100}, 84.9, "LOW"},
+		{profileCheck{min: 85, max: 100}, 85, "ok"},
+		{profileCheck{min: 85, max: 100}, 100, "ok"},
+		{profileCheck{min: 0, max: 20}, 20.1, "HIGH"},
+		{profileCheck{min: 0, max: 0}, 0, "ok"},
+		{profileCheck{min: 0, max: 0}, 1, "HIGH"},
+	}
+
+	for _, test := range tests {
+		if got := test.c.status(test.value); got != test.wanted {
+			t.Errorf("status(%v) in %v to %v = %v (wanted \"%v\")", test.value, test.c.min, test.c.max, got, test.wanted)
+		}
+	}
+}
+
+func TestProfileTunableDeviation(t *testing.T) {
+
+	var tests = []struct {
+		t       profileTunable
+		current string
+		wanted  string
+	}{
+		{profileTunable{"zfs_prefetch_disable", "1", "random I/O"}, "1", ""},
+		{profileTunable{"zfs_prefetch_disable", "1", "random I/O"}, "0",
+			"zfs_prefetch_disable is 0, recommended 1 (random I/O)"},
+		{profileTunable{"zfs_arc_max", "nonzero", "room"}, "0", "zfs_arc_max is not set (room)"},
+		{profileTunable{"zfs_arc_max", "nonzero", "room"}, "8589934592", ""},
+	}
+
+	for _, test := range tests {
+		if got := test.t.deviation(test.current); got != test.wanted {
+			t.Errorf("deviation(%s = %s) = %q (wanted %q)", test.t.name, test.current, got, test.wanted)
+		}
+	}
+}
+
+func TestFRange(t *testing.T) {
+
+	var tests = []struct {
+		min, max float64
+		wanted   string
+	}{
+		{0, 0, "0"},
+		{85, 100, "85 to 100"},
+		{0.5, 20, "0.5 to 20"},
+	}
+
+	for _, test := range tests {
+		if got := fRange(test.min, test.max); got != test.wanted {
+			t.Errorf("fRange(%v, %v) = %v (wanted \"%v\")", test.min, test.max, got, test.wanted)
+		}
+	}
+}
+
+func TestProfileExpressions(t *testing.T) {
+
+	dummy := func(string) (float64, error) { return 1, nil }
+
+	for _, name := range profileNames() {
+		for _, c := range profiles[name].checks {
+			if _, err := evalExpr(c.expr, dummy); err != nil {
+				t.Errorf("profile %s: check '%s' has bad expression: %v", name, c.desc, err)
+			}
+			if c.min > c.max {
+				t.Errorf("profile %s: check '%s' has min %v above max %v", name, c.desc, c.min, c.max)
+			}
+		}
+	}
+}
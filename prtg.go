@@ -0,0 +1,124 @@
+// PRTG sensor output for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// "-o prtg" and "-o prtg-xml" print the result for a PRTG "EXE/Script
+// Advanced" sensor, with channels for the ARC size, the ARC and L2ARC hit
+// ratios and the memory throttle count. With -state, a channel for the
+// throttles since the last run has a warning limit of zero, so PRTG warns
+// when the ARC was throttled and clears the warning once it no longer is.
+// See arc_summary.go for the license
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strconv"
+)
+
+// prtgChannel is one channel of a PRTG sensor. PRTG wants numbers and flags
+// as strings in XML, but accepts them in JSON as well
+type prtgChannel struct {
+	Channel         string `json:"channel" xml:"channel"`
+	Value           string `json:"value" xml:"value"`
+	Unit            string `json:"unit" xml:"unit"`
+	Float           int    `json:"float,omitempty" xml:"float,omitempty"`
+	LimitMode       int    `json:"limitmode,omitempty" xml:"limitmode,omitempty"`
+	LimitMaxWarning string `json:"limitmaxwarning,omitempty" xml:"limitmaxwarning,omitempty"`
+}
+
+// prtgResult is the whole output of a sensor
+type prtgResult struct {
+	XMLName  xml.Name      `json:"-" xml:"prtg"`
+	Channels []prtgChannel `json:"result" xml:"result"`
+	Text     string        `json:"text" xml:"text"`
+}
+
+// prtgChannels are the check metrics that become channels, with the name
+// PRTG shows
+var prtgChannels = []struct {
+	service, name string
+	channel       string
+}{
+	{"arc", "size", "ARC size"},
+	{"arc", "hit_ratio", "ARC hit ratio"},
+	{"l2arc", "hit_ratio", "L2ARC hit ratio"},
+	{"arc", "memory_throttle_count", "Memory throttle count"},
+	{"arc", "memory_throttle_delta", "Memory throttles since last run"},
+}
+
+// prtgUnits are the PRTG units of the units of the check metrics
+var prtgUnits = map[string]string{
+	"bytes":   "BytesMemory",
+	"percent": "Percent",
+	"count":   "Count",
+}
+
+// newPrtgResult builds the sensor result from a StatsSet
+func newPrtgResult(s *StatsSet) prtgResult {
+
+	metrics := make(map[string]checkMetric)
+	for _, m := range checkMetrics(s) {
+		metrics[m.service+"."+m.name] = m
+	}
+
+	_, summary := checkState(s)
+	result := prtgResult{Text: summary}
+
+	for _, pc := range prtgChannels {
+		m, ok := metrics[pc.service+"."+pc.name]
+		if !ok {
+			continue
+		}
+
+		c := prtgChannel{
+			Channel: pc.channel,
+			Value:   strconv.FormatFloat(m.value, 'f', -1, 64),
+			Unit:    prtgUnits[m.unit],
+		}
+
+		if m.unit == "percent" {
+			c.Float = 1
+			c.Value = strconv.FormatFloat(m.value, 'f', 2, 64)
+		}
+
+		if pc.name == "memory_throttle_delta" {
+			c.LimitMode = 1
+			c.LimitMaxWarning = "0"
+		}
+
+		result.Channels = append(result.Channels, c)
+	}
+
+	return result
+}
+
+// prtgRenderer writes the sensor result as JSON
+type prtgRenderer struct{}
+
+func (prtgRenderer) Render(w io.Writer, s *StatsSet) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Prtg prtgResult `json:"prtg"`
+	}{newPrtgResult(s)})
+}
+
+// prtgXMLRenderer writes the sensor result as XML
+type prtgXMLRenderer struct{}
+
+func (prtgXMLRenderer) Render(w io.Writer, s *StatsSet) error {
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(newPrtgResult(s)); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,51 @@
+// Test file for prtg.go
+package main
+
+import "testing"
+
+func TestPrtgResult(t *testing.T) {
+
+	defer func() { previousRun = nil }()
+
+	s := &StatsSet{Kstats: map[string]map[string]uint64{
+		"arcstats": {"size": 1024, "hits": 3, "misses": 1, "memory_throttle_count": 2},
+	}}
+
+	r := newPrtgResult(s)
+
+	if len(r.Channels) != len(prtgChannels)-1 {
+		t.Fatalf("newPrtgResult without -state returned %d channels (wanted %d)", len(r.Channels), len(prtgChannels)-1)
+	}
+
+	if r.Channels[3].LimitMaxWarning != "" {
+		t.Errorf("throttle count since boot has warning limit %q (wanted none)", r.Channels[3].LimitMaxWarning)
+	}
+
+	previousRun = &historyRecord{Stats: map[string]string{"arcstats.memory_throttle_count": "1"}}
+	r = newPrtgResult(s)
+
+	if len(r.Channels) != len(prtgChannels) {
+		t.Fatalf("newPrtgResult returned %d channels (wanted %d)", len(r.Channels), len(prtgChannels))
+	}
+
+	var tests = []struct {
+		channel, value, unit string
+	}{
+		{"ARC size", "1024", "BytesMemory"},
+		{"ARC hit ratio", "75.00", "Percent"},
+		{"L2ARC hit ratio", "0.00", "Percent"},
+		{"Memory throttle count", "2", "Count"},
+		{"Memory throttles since last run", "1", "Count"},
+	}
+
+	for i, test := range tests {
+		c := r.Channels[i]
+		if c.Channel != test.channel || c.Value != test.value || c.Unit != test.unit {
+			t.Errorf("channel %d = %v (wanted \"%v %v %v\")", i, c, test.channel, test.value, test.unit)
+		}
+	}
+
+	if r.Channels[4].LimitMaxWarning != "0" {
+		t.Errorf("throttles since last run have no warning limit")
+	}
+}
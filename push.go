@@ -0,0 +1,60 @@
+// Prometheus Pushgateway support for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// Where no exporter may run on the host, "arc_summary -push
+// http://pushgateway:9091" from cron pushes the metrics of every run to a
+// Pushgateway instead. The metrics are grouped by job and instance, and each
+// push replaces the ones before it. See arc_summary.go for the license
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// pushSink pushes every sample to a Pushgateway
+type pushSink struct {
+	url string
+	job string
+}
+
+func (pushSink) Name() string { return "Pushgateway" }
+
+// pushURL returns the URL of the group for this job and instance
+func pushURL(base, job, instance string) string {
+	return strings.TrimSuffix(base, "/") + "/metrics/job/" + url.PathEscape(job) +
+		"/instance/" + url.PathEscape(instance)
+}
+
+func (p pushSink) Publish(ctx context.Context, s *StatsSet) error {
+
+	var b bytes.Buffer
+	if err := (prometheusRenderer{}).Render(&b, s); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", pushURL(p.url, p.job, sinkHost()), &b)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("pushgateway said %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+// Per-vdev I/O queue statistics for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// Shows the pending and active I/Os of each queue class per vdev from
+// "zpool iostat -q" and flags queues that are at their max_active limit. See
+// arc_summary.go for the license
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// queueClasses are the I/O classes in the order zpool iostat -q prints them.
+// Older versions of ZFS don't have all of them
+var queueClasses = []string{"sync_read", "sync_write", "async_read", "async_write", "scrub", "trim", "rebuild"}
+
+// vdevQueues holds the pending and active I/Os of a vdev per class
+type vdevQueues struct {
+	name    string
+	pending []uint64
+	active  []uint64
+}
+
+// parseZpoolQueues takes the output of "zpool iostat -q -v -H -p" and
+// returns the queues of each pool and vdev. The first seven columns are the
+// name and the normal iostat values, after that come pairs of pending and
+// active I/Os
+func parseZpoolQueues(out string) []vdevQueues {
+
+	var result []vdevQueues
+
+	input := bufio.NewScanner(strings.NewReader(out))
+
+	for input.Scan() {
+		fields := strings.Split(input.Text(), "\t")
+		if len(fields) < 9 {
+			continue
+		}
+
+		q := vdevQueues{name: strings.TrimSpace(fields[0])}
+
+		for i := 7; i+1 < len(fields) && len(q.pending) < len(queueClasses); i += 2 {
+			pend, _ := strconv.ParseUint(strings.TrimSpace(fields[i]), 10, 64)
+			act, _ := strconv.ParseUint(strings.TrimSpace(fields[i+1]), 10, 64)
+			q.pending = append(q.pending, pend)
+			q.active = append(q.active, act)
+		}
+
+		result = append(result, q)
+	}
+
+	return result
+}
+
+// printQueues displays the I/O queues of every vdev. A queue is flagged as
+// saturated when it has as many active I/Os as zfs_vdev_<class>_max_active
+// allows and more are waiting
+func printQueues() {
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	out, err := runCommand(ctx, "zpool", "iostat", "-q", "-v", "-H", "-p")
+	if err != nil {
+		skipSection("queues", fmt.Errorf("couldn't run 'zpool iostat': %v", err))
+		return
+	}
+
+	getTunables(ctx, tunables)
+
+	queues := parseZpoolQueues(string(out))
+
+	// Older versions of ZFS have fewer classes
+	classes := 0
+	for _, q := range queues {
+		if len(q.pending) > classes {
+			classes = len(q.pending)
+		}
+	}
+
+	var table layoutTable
+
+	if *OptPlain {
+		fmt.Fprintln(reportOut, "\nVdev queues (pending/active):")
+	} else {
+		fmt.Fprintln(reportOut)
+		table.add(append([]string{"Vdev (pending/active)"}, queueClasses[:classes]...)...)
+	}
+
+	var saturated []string
+
+	for _, q := range queues {
+
+		var pairs []string
+		cells := []string{redactName(q.name)}
+
+		for i := range q.pending {
+			counts := fmt.Sprintf("%d/%d", q.pending[i], q.active[i])
+			pairs = append(pairs, queueClasses[i], counts)
+			cells = append(cells, counts)
+
+			max, ok := tunables["zfs_vdev_"+queueClasses[i]+"_max_active"]
+			if !ok || q.pending[i] == 0 {
+				continue
+			}
+
+			if q.active[i] >= stringToUint64(max) {
+				saturated = append(saturated, redactName(q.name)+" "+queueClasses[i])
+			}
+		}
+
+		if *OptPlain {
+			printPlain(redactName(q.name), "", plainList(pairs...))
+			continue
+		}
+
+		for len(cells) <= classes {
+			cells = append(cells, "-")
+		}
+		table.add(cells...)
+	}
+
+	table.print()
+
+	if len(saturated) > 0 {
+		fmt.Fprintln(reportOut, "\nSaturated queues (active at max_active with I/Os waiting):")
+		for _, s := range saturated {
+			fmt.Fprintln(reportOut, indent+s)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+// Test file for queues.go
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseZpoolQueues(t *testing.T) {
+	out := "tank\t1000\t2000\t5\t6\t700\t800\t0\t1\t2\t3\t0\t0\t10\t4\t0\t0\t0\t0\n" +
+		"mirror-0\t1000\t2000\t5\t6\t700\t800\t0\t1\t2\t3\n" +
+		"garbage line\n"
+
+	want := []vdevQueues{
+		{"tank", []uint64{0, 2, 0, 10, 0, 0}, []uint64{1, 3, 0, 4, 0, 0}},
+		{"mirror-0", []uint64{0, 2}, []uint64{1, 3}},
+	}
+
+	got := parseZpoolQueues(out)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseZpoolQueues() = %v (wanted %v)", got, want)
+	}
+}
@@ -0,0 +1,155 @@
+// Valid ranges of tunables for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// The kernel accepts any number that fits the type of a tunable, even if ZFS
+// then ignores it or behaves oddly. tunableRanges are the limits of common
+// tunables, tunableOrders pairs of tunables where one must not be larger
+// than the other, such as zfs_arc_min and zfs_arc_max. Both are checked in
+// the report, when editing tunables and when validating modprobe.d files.
+// See arc_summary.go for the license
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// tunableRange is the smallest and largest sensible value of a tunable
+type tunableRange struct {
+	min, max uint64
+}
+
+// tunableRanges are the limits of common tunables
+var tunableRanges = map[string]tunableRange{
+	"l2arc_noprefetch":                              {0, 1},
+	"spa_slop_shift":                                {1, 31},
+	"zfs_arc_dnode_limit_percent":                   {0, 100},
+	"zfs_arc_lotsfree_percent":                      {0, 100},
+	"zfs_compressed_arc_enabled":                    {0, 1},
+	"zfs_dirty_data_max_percent":                    {1, 100},
+	"zfs_dirty_data_sync_percent":                   {0, 100},
+	"zfs_prefetch_disable":                          {0, 1},
+	"zfs_txg_timeout":                               {1, 1<<31 - 1},
+	"zfs_vdev_async_read_max_active":                {1, 1<<32 - 1},
+	"zfs_vdev_async_write_active_max_dirty_percent": {0, 100},
+	"zfs_vdev_async_write_active_min_dirty_percent": {0, 100},
+	"zfs_vdev_async_write_max_active":               {1, 1<<32 - 1},
+	"zfs_vdev_max_active":                           {1, 1<<32 - 1},
+	"zfs_vdev_scrub_max_active":                     {1, 1<<32 - 1},
+	"zfs_vdev_sync_read_max_active":                 {1, 1<<32 - 1},
+	"zfs_vdev_sync_write_max_active":                {1, 1<<32 - 1},
+}
+
+// tunableOrders are pairs of tunables where the first must not be larger
+// than the second. Zero means ZFS picks the value, so it isn't compared
+var tunableOrders = []struct{ small, large string }{
+	{"zfs_arc_min", "zfs_arc_max"},
+	{"zfs_vdev_async_read_min_active", "zfs_vdev_async_read_max_active"},
+	{"zfs_vdev_async_write_min_active", "zfs_vdev_async_write_max_active"},
+	{"zfs_vdev_sync_read_min_active", "zfs_vdev_sync_read_max_active"},
+	{"zfs_vdev_sync_write_min_active", "zfs_vdev_sync_write_max_active"},
+	{"zfs_vdev_scrub_min_active", "zfs_vdev_scrub_max_active"},
+	{"zfs_vdev_async_write_active_min_dirty_percent", "zfs_vdev_async_write_active_max_dirty_percent"},
+}
+
+// checkTunableRange tests a value against the limits of a tunable. Tunables
+// without known limits and values that aren't numbers are accepted, the type
+// is checked by checkTypeRange
+func checkTunableRange(name, value string) error {
+
+	r, ok := tunableRanges[name]
+	if !ok {
+		return nil
+	}
+
+	v, err := strconv.ParseUint(value, 0, 64)
+	if err != nil {
+		return nil
+	}
+
+	if v < r.min || v > r.max {
+		return fmt.Errorf("%s is outside the sensible range %d to %d", value, r.min, r.max)
+	}
+
+	return nil
+}
+
+// checkTunableOrders returns the pairs of tunables in values that are in the
+// wrong order. If name isn't empty, only the pairs it is part of are checked
+func checkTunableOrders(values map[string]string, name string) []string {
+
+	var problems []string
+
+	for _, o := range tunableOrders {
+		if name != "" && name != o.small && name != o.large {
+			continue
+		}
+
+		small, err1 := strconv.ParseUint(values[o.small], 0, 64)
+		large, err2 := strconv.ParseUint(values[o.large], 0, 64)
+		if err1 != nil || err2 != nil || small == 0 || large == 0 {
+			continue
+		}
+
+		if small > large {
+			problems = append(problems, fmt.Sprintf("%s (%d) is larger than %s (%d)", o.small, small, o.large, large))
+		}
+	}
+
+	return problems
+}
+
+// checkTunableValues returns the problems with the ranges and order of all
+// tunables in values
+func checkTunableValues(values map[string]string) []string {
+
+	var names []string
+	for n := range values {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	var problems []string
+
+	for _, n := range names {
+		if err := checkTunableRange(n, values[n]); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", n, err))
+		}
+	}
+
+	return append(problems, checkTunableOrders(values, "")...)
+}
+
+// checkNewTunable returns the problems with setting a tunable to a new value,
+// given the current values of all tunables
+func checkNewTunable(values map[string]string, name, value string) []string {
+
+	var problems []string
+
+	if err := checkTunableRange(name, value); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	changed := make(map[string]string)
+	for k, v := range values {
+		changed[k] = v
+	}
+	changed[name] = value
+
+	return append(problems, checkTunableOrders(changed, name)...)
+}
+
+// printRangeWarnings prints the live tunables with values out of range
+func printRangeWarnings() {
+
+	problems := checkTunableValues(tunables)
+	if len(problems) == 0 {
+		return
+	}
+
+	fmt.Fprintln(reportOut, "\nTunables out of range:")
+	for _, p := range problems {
+		fmt.Fprintln(reportOut, indent+p)
+	}
+}
@@ -0,0 +1,67 @@
+// Test file for ranges.go
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckTunableRange(t *testing.T) {
+
+	var tests = []struct {
+		name, value string
+		ok          bool
+	}{
+		{"zfs_prefetch_disable", "1", true},
+		{"zfs_prefetch_disable", "2", false},
+		{"zfs_dirty_data_max_percent", "0", false},
+		{"zfs_dirty_data_max_percent", "101", false},
+		{"zfs_txg_timeout", "5", true},
+		{"zfs_txg_timeout", "0", false},
+		{"zfs_txg_timeout", "2147483648", false},
+		{"zfs_unknown", "12345", true},
+		{"zfs_prefetch_disable", "yes", true},
+	}
+
+	for _, test := range tests {
+		err := checkTunableRange(test.name, test.value)
+		if (err == nil) != test.ok {
+			t.Errorf("checkTunableRange(%s, %s) = %v (wanted ok %v)", test.name, test.value, err, test.ok)
+		}
+	}
+}
+
+func TestCheckTunableValues(t *testing.T) {
+
+	values := map[string]string{
+		"zfs_arc_min":                    "8589934592",
+		"zfs_arc_max":                    "4294967296",
+		"zfs_vdev_sync_read_min_active":  "10",
+		"zfs_vdev_sync_read_max_active":  "10",
+		"zfs_vdev_async_read_min_active": "5",
+		"zfs_vdev_async_read_max_active": "0",
+		"zfs_prefetch_disable":           "3",
+	}
+
+	want := []string{
+		"zfs_prefetch_disable: 3 is outside the sensible range 0 to 1",
+		"zfs_vdev_async_read_max_active: 0 is outside the sensible range 1 to 4294967295",
+		"zfs_arc_min (8589934592) is larger than zfs_arc_max (4294967296)",
+	}
+
+	if got := checkTunableValues(values); !reflect.DeepEqual(got, want) {
+		t.Errorf("checkTunableValues() = %q (wanted %q)", got, want)
+	}
+
+	// A zfs_arc_max of 0 means ZFS picks it
+	values["zfs_arc_max"] = "0"
+	if got := checkNewTunable(values, "zfs_arc_min", "1073741824"); got != nil {
+		t.Errorf("checkNewTunable(zfs_arc_min) = %q (wanted nil)", got)
+	}
+
+	values["zfs_arc_min"] = "1073741824"
+	want = []string{"zfs_arc_min (1073741824) is larger than zfs_arc_max (536870912)"}
+	if got := checkNewTunable(values, "zfs_arc_max", "536870912"); !reflect.DeepEqual(got, want) {
+		t.Errorf("checkNewTunable(zfs_arc_max) = %q (wanted %q)", got, want)
+	}
+}
@@ -0,0 +1,106 @@
+// Memory reclaim indicators for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// When the kernel runs short of memory, it asks the shrinkers of the ARC to
+// give some back. The ARC then stops growing (arc_no_grow), notes how much it
+// still has to free (arc_need_free) and counts the reclaims triggered
+// directly by an allocation and those done in the background by kswapd.
+// These are the first signs of the kernel and the ARC fighting over memory.
+// With -state, we also show how much the counters grew since the last run.
+// See arc_summary.go for the license
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// previousRun is the sample of the last run read from the -state file, nil
+// if there was none
+var previousRun *historyRecord
+
+// reclaimCounters are the reclaim counters of arcstats with their labels
+var reclaimCounters = []struct{ stat, label string }{
+	{"memory_direct_count", "Direct reclaims:"},
+	{"memory_indirect_count", "Indirect reclaims:"},
+}
+
+// reclaimDelta returns how much an arcstats counter grew since the previous
+// sample. It fails if there is no previous sample, the counter is missing
+// from either, or it went backwards because the module was reloaded
+func reclaimDelta(prev *historyRecord, stats map[string]string, stat string) (uint64, bool) {
+
+	if prev == nil {
+		return 0, false
+	}
+
+	old, err := strconv.ParseUint(prev.Stats["arcstats."+stat], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	cur, err := strconv.ParseUint(stats[stat], 10, 64)
+	if err != nil || cur < old {
+		return 0, false
+	}
+
+	return cur - old, true
+}
+
+// printReclaim prints the memory reclaim indicators of the ARC
+func printReclaim(stats map[string]string) {
+
+	noGrow, hasNoGrow := stats["arc_no_grow"]
+	needFree, hasNeedFree := stats["arc_need_free"]
+
+	if !hasNoGrow && !hasNeedFree {
+		return
+	}
+
+	fmt.Fprintln(reportOut, "\nMemory reclaim:")
+
+	if hasNoGrow {
+		grow := "yes"
+		if noGrow != "0" {
+			grow = "no"
+		}
+		prtL2("ARC may grow:", grow)
+	}
+
+	if hasNeedFree {
+		prtL2("ARC must free:", fBytes(needFree))
+	}
+
+	var since string
+	if previousRun != nil {
+		since = fUptime(time.Since(time.Unix(previousRun.Time, 0)).Seconds())
+	}
+
+	var direct uint64
+	var directKnown bool
+
+	for _, c := range reclaimCounters {
+		value, ok := stats[c.stat]
+		if !ok {
+			continue
+		}
+
+		result := strings.TrimSpace(fHits(value))
+		if d, ok := reclaimDelta(previousRun, stats, c.stat); ok {
+			result += fmt.Sprintf(" (+%s in last %s)", strings.TrimSpace(fHits(strconv.FormatUint(d, 10))), since)
+			if c.stat == "memory_direct_count" {
+				direct, directKnown = d, true
+			}
+		}
+		prtL2(c.label, result)
+	}
+
+	need, _ := strconv.ParseUint(needFree, 10, 64)
+	explain(explainReclaim(noGrow == "1", need, direct, directKnown))
+
+	if noGrow == "1" {
+		addWarning("reclaim", "the ARC is not allowed to grow because the system is short of memory")
+	}
+}
@@ -0,0 +1,34 @@
+// Test file for reclaim.go
+package main
+
+import "testing"
+
+func TestReclaimDelta(t *testing.T) {
+
+	prev := &historyRecord{Stats: map[string]string{
+		"arcstats.memory_direct_count":   "10",
+		"arcstats.memory_indirect_count": "500",
+	}}
+
+	var tests = []struct {
+		prev  *historyRecord
+		stat  string
+		value string
+		want  uint64
+		ok    bool
+	}{
+		{prev, "memory_direct_count", "15", 5, true},
+		{prev, "memory_direct_count", "10", 0, true},
+		{prev, "memory_indirect_count", "100", 0, false},
+		{prev, "memory_throttle_count", "3", 0, false},
+		{prev, "memory_direct_count", "", 0, false},
+		{nil, "memory_direct_count", "15", 0, false},
+	}
+
+	for _, test := range tests {
+		got, ok := reclaimDelta(test.prev, map[string]string{test.stat: test.value}, test.stat)
+		if got != test.want || ok != test.ok {
+			t.Errorf("reclaimDelta(%s=%s) = %v, %v (wanted \"%v, %v\")", test.stat, test.value, got, ok, test.want, test.ok)
+		}
+	}
+}
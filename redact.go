@@ -0,0 +1,152 @@
+// Redaction of pool, dataset and host names for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// With -redact, pool names, dataset names and the hostname are replaced by
+// short hashes in reports and bundles, so the output can be posted in public
+// without giving away internal naming. The same name always gives the same
+// hash, so reports of one system can still be compared. Note the hashes of
+// common names like "tank" are easy to guess. See arc_summary.go for the
+// license
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const hostnamePath = "/proc/sys/kernel/hostname"
+
+var (
+	redactPools = make(map[string]bool)
+	redactHost  string
+
+	// redactFieldRE finds the words of a line that could be names. Names
+	// can't contain whitespace, '=' or ','
+	redactFieldRE = regexp.MustCompile(`[^\s=,]+`)
+
+	// redactDatasets are the dataset names with whitespace in them, longest
+	// first. redactFieldRE would split them into words and miss them
+	redactDatasets []string
+)
+
+// initRedaction gets the names that must not show up in the output
+func initRedaction() {
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	if out, err := runCommand(ctx, "zpool", "list", "-H", "-o", "name"); err == nil {
+		for _, p := range strings.Fields(string(out)) {
+			redactPools[p] = true
+		}
+	}
+
+	if data, err := readFile(ctx, hostnamePath); err == nil {
+		redactHost = strings.TrimSpace(string(data))
+	}
+
+	if out, err := runCommand(ctx, "zfs", "list", "-H", "-o", "name"); err == nil {
+		for _, d := range strings.Split(string(out), "\n") {
+			addRedactDataset(d)
+		}
+	}
+}
+
+// addRedactDataset remembers a dataset name if redactFieldRE would split it
+func addRedactDataset(name string) {
+
+	if !strings.ContainsAny(name, " \t") {
+		return
+	}
+
+	redactDatasets = append(redactDatasets, name)
+	sort.Slice(redactDatasets, func(i, j int) bool {
+		return len(redactDatasets[i]) > len(redactDatasets[j])
+	})
+}
+
+// redactToken returns the replacement for a name of the given kind
+func redactToken(kind, name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return kind + "-" + hex.EncodeToString(sum[:3])
+}
+
+// redactName returns the name as it may be printed. Pools and the host are
+// replaced by a token, as are datasets, which are recognized by the pool
+// they live on. Anything else is returned unchanged, as is everything if
+// -redact isn't set
+func redactName(name string) string {
+
+	if !*OptRedact {
+		return name
+	}
+
+	switch {
+	case name == "":
+		return name
+	case name == redactHost:
+		return redactToken("host", name)
+	case redactPools[name]:
+		return redactToken("pool", name)
+	}
+
+	if idx := strings.Index(name, "/"); idx > 0 && redactPools[name[:idx]] {
+		return redactToken("dataset", name)
+	}
+
+	return name
+}
+
+// redactText replaces every word of s that is a name. This errs on the side
+// of caution: a pool named like a common word also hides that word. Dataset
+// names may contain spaces, so the known ones and the values of the
+// dataset_name stats of the objset kstats are replaced as a whole first
+func redactText(s string) string {
+
+	if !*OptRedact {
+		return s
+	}
+
+	lines := strings.SplitAfter(s, "\n")
+	for i, l := range lines {
+		lines[i] = redactLine(l)
+	}
+
+	return strings.Join(lines, "")
+}
+
+// redactLine replaces the names in a line of text
+func redactLine(l string) string {
+
+	if f := strings.Fields(l); len(f) > 2 && f[0] == "dataset_name" {
+		name := strings.TrimRight(afterFields(l, 2), " \t\r\n")
+		idx := strings.LastIndex(l, name)
+		return redactFieldRE.ReplaceAllStringFunc(l[:idx], redactName) +
+			redactToken("dataset", name) + l[idx+len(name):]
+	}
+
+	for _, d := range redactDatasets {
+		l = strings.Replace(l, d, redactName(d), -1)
+	}
+
+	return redactFieldRE.ReplaceAllStringFunc(l, redactName)
+}
+
+// redactPath replaces every component of a path that is a name, such as the
+// per-pool kstat directories
+func redactPath(path string) string {
+
+	if !*OptRedact {
+		return path
+	}
+
+	parts := strings.Split(path, "/")
+	for i, p := range parts {
+		parts[i] = redactName(p)
+	}
+
+	return strings.Join(parts, "/")
+}
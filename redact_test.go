@@ -0,0 +1,52 @@
+// Test file for redact.go
+package main
+
+import "testing"
+
+func TestRedact(t *testing.T) {
+
+	*OptRedact = true
+	redactPools["data"] = true
+	redactHost = "fileserver"
+
+	addRedactDataset("data/My Projects")
+	addRedactDataset("data/My Projects/Old Stuff")
+
+	defer func() {
+		*OptRedact = false
+		delete(redactPools, "data")
+		redactHost = ""
+		redactDatasets = nil
+	}()
+
+	pool := redactToken("pool", "data")
+	host := redactToken("host", "fileserver")
+	dataset := redactToken("dataset", "data/home/alice")
+	projects := redactToken("dataset", "data/My Projects")
+	old := redactToken("dataset", "data/My Projects/Old Stuff")
+
+	var tests = []struct {
+		f      func(string) string
+		input  string
+		wanted string
+	}{
+		{redactName, "data", pool},
+		{redactName, "fileserver", host},
+		{redactName, "data/home/alice", dataset},
+		{redactName, "database", "database"},
+		{redactName, "sda1", "sda1"},
+		{redactText, "  pool: data\n", "  pool: " + pool + "\n"},
+		{redactText, "root=ZFS=data/home/alice quiet", "root=ZFS=" + dataset + " quiet"},
+		{redactText, "metadata_size 4 100", "metadata_size 4 100"},
+		{redactText, "dataset_name                    7    data/My Projects\n", "dataset_name                    7    " + projects + "\n"},
+		{redactText, "dataset_name 7 other/Not Known", "dataset_name 7 " + redactToken("dataset", "other/Not Known")},
+		{redactText, "data/My Projects/Old Stuff\ndata/My Projects\tbusy\n", old + "\n" + projects + "\tbusy\n"},
+		{redactPath, "proc/spl/kstat/zfs/data/io", "proc/spl/kstat/zfs/" + pool + "/io"},
+	}
+
+	for _, test := range tests {
+		if got := test.f(test.input); got != test.wanted {
+			t.Errorf("redact(%s) = %v (wanted \"%v\")", test.input, got, test.wanted)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+// Replay of recorded snapshots for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// "arc_summary replay /var/lib/arc_summary" steps through the JSON snapshots
+// watch mode wrote to -snapshot-dir in the order they were taken, showing
+// the full report for each. Enter or "n" goes to the next snapshot, "p" back
+// to the previous one, "f" and "l" to the first and last, a number to that
+// snapshot and "q" quits. Each snapshot is turned into the files of a
+// bundle, so the report is made by the same code as for the live system.
+// See arc_summary.go for the license
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// replayPatterns match the snapshots in a directory
+var replayPatterns = []string{"snapshot-*.json", "snapshot-*.json" + gzipSuffix}
+
+// replayFiles returns the snapshots in dir
+func replayFiles(dir string) ([]string, error) {
+
+	var result []string
+
+	for _, p := range replayPatterns {
+		matches, err := filepath.Glob(filepath.Join(dir, p))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, matches...)
+	}
+
+	return result, nil
+}
+
+// statsSetFiles returns the files of a bundle that hold the stats of a
+// StatsSet
+func statsSetFiles(s *StatsSet) map[string][]byte {
+
+	files := make(map[string][]byte)
+
+	for file, m := range s.Kstats {
+		var names []string
+		for n := range m {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+
+		// getKstats skips the two header lines of a kstat file
+		var b strings.Builder
+		b.WriteString("0 1 0x01 0 0 0 0\nname type data\n")
+		for _, n := range names {
+			fmt.Fprintf(&b, "%s 4 %d\n", n, m[n])
+		}
+
+		files[bundleKey(procPath+file)] = []byte(b.String())
+	}
+
+	for name, v := range s.Tunables {
+		files[bundleKey(filepath.Join(tunablesPath, name))] = []byte(v + "\n")
+	}
+
+	return files
+}
+
+// replayStep returns the snapshot to show after a command of the user, out
+// of n snapshots, and if the user wants to quit
+func replayStep(cmd string, cur, n int) (int, bool, error) {
+
+	switch cmd = strings.TrimSpace(cmd); cmd {
+	case "", "n":
+		if cur < n-1 {
+			cur++
+		}
+	case "p":
+		if cur > 0 {
+			cur--
+		}
+	case "f":
+		cur = 0
+	case "l":
+		cur = n - 1
+	case "q":
+		return cur, true, nil
+	default:
+		i, err := strconv.Atoi(cmd)
+		if err != nil || i < 1 || i > n {
+			return cur, false, fmt.Errorf("unknown command '%s'", cmd)
+		}
+		cur = i - 1
+	}
+
+	return cur, false, nil
+}
+
+// showSnapshot prints the report for a snapshot
+func showSnapshot(s *StatsSet, path string, i, n int) {
+
+	bundleFiles = statsSetFiles(s)
+
+	ctx, cancel := collectContext()
+	getKstats(ctx, kstats)
+	for k := range tunables {
+		delete(tunables, k)
+	}
+	getTunables(ctx, tunables)
+	cancel()
+
+	fmt.Fprint(reportOut, clearScreen)
+	fmt.Fprintf(reportOut, "Snapshot %d of %d at %s (%s)\n", i+1, n, s.Time.Format("2006-01-02 15:04:05"), filepath.Base(path))
+	printReport()
+}
+
+// cmdReplay handles the "replay" subcommand
+func cmdReplay(args []string) {
+
+	if len(args) != 1 {
+		log.Fatal("Usage: arc_summary replay <snapshot directory>")
+	}
+
+	if bundleFiles != nil {
+		log.Fatal("Can't replay snapshots from a bundle")
+	}
+
+	paths, err := replayFiles(args[0])
+	if err != nil {
+		log.Fatal("Couldn't list snapshots: ", err)
+	}
+
+	if len(paths) == 0 {
+		log.Fatal("No snapshots in ", args[0])
+	}
+
+	sets := make([]*StatsSet, len(paths))
+	for i, p := range paths {
+		if sets[i], err = readStatsSet(p); err != nil {
+			log.Fatal("Couldn't read snapshot: ", err)
+		}
+	}
+
+	order := make([]int, len(paths))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return sets[order[i]].Time.Before(sets[order[j]].Time) })
+
+	input := bufio.NewScanner(os.Stdin)
+	cur, show := 0, true
+
+	for {
+		if show {
+			showSnapshot(sets[order[cur]], paths[order[cur]], cur, len(order))
+		}
+
+		fmt.Fprint(reportOut, "\n[n]ext, [p]revious, [f]irst, [l]ast, number or [q]uit: ")
+		if !input.Scan() {
+			fmt.Fprintln(reportOut)
+			return
+		}
+
+		next, quit, err := replayStep(input.Text(), cur, len(order))
+		if quit {
+			return
+		}
+
+		// A typo keeps the report on the screen
+		show = err == nil
+		if err != nil {
+			fmt.Fprintln(reportOut, err)
+		}
+		cur = next
+	}
+}
@@ -0,0 +1,101 @@
+// Test file for replay.go
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestReplayStep(t *testing.T) {
+
+	var tests = []struct {
+		cmd    string
+		cur    int
+		wanted int
+		quit   bool
+		fail   bool
+	}{
+		{"", 0, 1, false, false},
+		{"n", 4, 4, false, false},
+		{"p", 2, 1, false, false},
+		{"p", 0, 0, false, false},
+		{"f", 3, 0, false, false},
+		{"l", 0, 4, false, false},
+		{"3", 0, 2, false, false},
+		{"6", 1, 1, false, true},
+		{"x", 1, 1, false, true},
+		{"q", 2, 2, true, false},
+	}
+
+	for _, test := range tests {
+		got, quit, err := replayStep(test.cmd, test.cur, 5)
+		if got != test.wanted || quit != test.quit || (err != nil) != test.fail {
+			t.Errorf("replayStep(%q, %d) = %d, %v, %v (wanted \"%d\")", test.cmd, test.cur, got, quit, err, test.wanted)
+		}
+	}
+}
+
+func TestReplayFiles(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "arc_summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, n := range []string{"snapshot-1.json", "snapshot-2.json.gz", "notes.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, n), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := replayFiles(dir)
+	sort.Strings(files)
+	wanted := []string{filepath.Join(dir, "snapshot-1.json"), filepath.Join(dir, "snapshot-2.json.gz")}
+	if err != nil || !reflect.DeepEqual(files, wanted) {
+		t.Errorf("replayFiles() = %v, %v (wanted \"%v\")", files, err, wanted)
+	}
+}
+
+func TestStatsSetFiles(t *testing.T) {
+
+	s := &StatsSet{
+		Kstats:   map[string]map[string]uint64{"arcstats": {"hits": 42, "size": 7, "l2_size": 18446744073709551615}},
+		Tunables: map[string]string{"zfs_arc_max": "0"},
+	}
+
+	bundleFiles = statsSetFiles(s)
+	defer func() { bundleFiles = nil }()
+
+	m := make(map[string][]string)
+	getKstats(context.Background(), m)
+
+	if v, err := lookupStatIn(m, "hits"); err != nil || v != "42" {
+		t.Errorf("hits after replay = %s, %v (wanted \"42\")", v, err)
+	}
+
+	v, err := lookupStatIn(m, "l2_size")
+	if err != nil || stringToUint64(v) != 18446744073709551615 {
+		t.Errorf("l2_size after replay = %s, %v (wanted \"18446744073709551615\")", v, err)
+	}
+
+	data, err := readFile(context.Background(), tunablesPath+"/zfs_arc_max")
+	if err != nil || string(data) != "0\n" {
+		t.Errorf("zfs_arc_max after replay = %q, %v (wanted \"0\\n\")", data, err)
+	}
+}
+
+// lookupStatIn returns the value of an arcstats stat in kstat lines
+func lookupStatIn(m map[string][]string, name string) (string, error) {
+	for _, l := range m["arcstats"] {
+		if n, v := cleanProcLine(l); n == name {
+			return v, nil
+		}
+	}
+	return "", os.ErrNotExist
+}
@@ -0,0 +1,158 @@
+// Rotation of the history file for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// With -rotate-size or -rotate-age, the history file is moved aside before
+// it grows too large or old, like logrotate would: history.ndjson becomes
+// history.ndjson.1, the old .1 becomes .2 and so on, and only -rotate-keep
+// old files are kept. The number goes before ".gz", so rotated files are
+// still compressed. See arc_summary.go for the license
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sizeUnits are the suffixes accepted by -rotate-size
+var sizeUnits = map[string]int64{
+	"K": 1 << 10,
+	"M": 1 << 20,
+	"G": 1 << 30,
+}
+
+// parseSize returns the number of bytes of a size such as "100M"
+func parseSize(s string) (int64, error) {
+
+	mult := int64(1)
+
+	if n := len(s); n > 0 {
+		if m, ok := sizeUnits[strings.ToUpper(s[n-1:])]; ok {
+			s, mult = s[:n-1], m
+		}
+	}
+
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("bad size '%s' (wanted bytes or a number with K, M or G)", s)
+	}
+
+	return v * mult, nil
+}
+
+// rotatedName returns the name of the nth old file of path
+func rotatedName(path string, n int) string {
+
+	num := "." + strconv.Itoa(n)
+
+	if strings.HasSuffix(path, gzipSuffix) {
+		return strings.TrimSuffix(path, gzipSuffix) + num + gzipSuffix
+	}
+
+	return path + num
+}
+
+// rotationDue says if a file of the given size with its first record written
+// at started must be rotated. A limit of zero is no limit
+func rotationDue(size, maxSize int64, started, now time.Time, maxAge time.Duration) bool {
+
+	if maxSize > 0 && size >= maxSize {
+		return true
+	}
+
+	return maxAge > 0 && !started.IsZero() && now.Sub(started) >= maxAge
+}
+
+// historyStart returns the time of the first record in a history file
+func historyStart(path string) time.Time {
+
+	f, err := openInput(path)
+	if err != nil {
+		return time.Time{}
+	}
+	defer f.Close()
+
+	input := bufio.NewScanner(f)
+	input.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var rec historyRecord
+	if !input.Scan() || json.Unmarshal(input.Bytes(), &rec) != nil {
+		return time.Time{}
+	}
+
+	return time.Unix(rec.Time, 0)
+}
+
+// rotateFiles moves path to its first old name and every old file one
+// further, removing those past keep
+func rotateFiles(path string, keep int) error {
+
+	if keep < 1 {
+		return os.Remove(path)
+	}
+
+	os.Remove(rotatedName(path, keep))
+
+	for n := keep - 1; n > 0; n-- {
+		err := os.Rename(rotatedName(path, n), rotatedName(path, n+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return os.Rename(path, rotatedName(path, 1))
+}
+
+// maybeRotateHistory rotates the history file if it is past the limits given
+// with -rotate-size and -rotate-age
+func maybeRotateHistory(path string) error {
+
+	maxSize, err := parseSize(*OptRotateSize)
+	if err != nil {
+		return err
+	}
+
+	if maxSize == 0 && *OptRotateAge == 0 {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var started time.Time
+	if *OptRotateAge > 0 {
+		started = historyStart(path)
+	}
+
+	if !rotationDue(info.Size(), maxSize, started, time.Now(), *OptRotateAge) {
+		return nil
+	}
+
+	return rotateFiles(path, *OptRotateKeep)
+}
+
+// historyFiles returns the rotated history files that exist followed by the
+// current one, oldest first
+func historyFiles(path string) []string {
+
+	var files []string
+
+	for n := 1; ; n++ {
+		name := rotatedName(path, n)
+		if _, err := os.Stat(name); err != nil {
+			break
+		}
+		files = append([]string{name}, files...)
+	}
+
+	return append(files, path)
+}
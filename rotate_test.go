@@ -0,0 +1,119 @@
+// Test file for rotate.go
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+
+	var tests = []struct {
+		input string
+		want  int64
+		fails bool
+	}{
+		{"0", 0, false},
+		{"1234", 1234, false},
+		{"10K", 10 << 10, false},
+		{"100m", 100 << 20, false},
+		{"2G", 2 << 30, false},
+		{"", 0, true},
+		{"M", 0, true},
+		{"-1", 0, true},
+		{"10T", 0, true},
+	}
+
+	for _, test := range tests {
+		got, err := parseSize(test.input)
+		if got != test.want || (err != nil) != test.fails {
+			t.Errorf("parseSize(%s) = %v, %v (wanted \"%v\")", test.input, got, err, test.want)
+		}
+	}
+}
+
+func TestRotatedName(t *testing.T) {
+
+	var tests = []struct {
+		path string
+		n    int
+		want string
+	}{
+		{"history.ndjson", 1, "history.ndjson.1"},
+		{"history.ndjson.gz", 3, "history.ndjson.3.gz"},
+	}
+
+	for _, test := range tests {
+		if got := rotatedName(test.path, test.n); got != test.want {
+			t.Errorf("rotatedName(%s, %d) = %v (wanted \"%v\")", test.path, test.n, got, test.want)
+		}
+	}
+}
+
+func TestRotationDue(t *testing.T) {
+
+	now := time.Now()
+
+	var tests = []struct {
+		size    int64
+		maxSize int64
+		started time.Time
+		maxAge  time.Duration
+		want    bool
+	}{
+		{100, 0, now.Add(-time.Hour), 0, false},
+		{100, 100, now, 0, true},
+		{99, 100, now, 0, false},
+		{1, 0, now.Add(-2 * time.Hour), time.Hour, true},
+		{1, 0, now.Add(-time.Minute), time.Hour, false},
+		{1, 0, time.Time{}, time.Hour, false},
+	}
+
+	for _, test := range tests {
+		got := rotationDue(test.size, test.maxSize, test.started, now, test.maxAge)
+		if got != test.want {
+			t.Errorf("rotationDue(%d, %d, %v, %v) = %v (wanted \"%v\")",
+				test.size, test.maxSize, now.Sub(test.started), test.maxAge, got, test.want)
+		}
+	}
+}
+
+func TestRotateFiles(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "arc_summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "history.ndjson")
+
+	// Three rotations with two kept leave the two newest old files
+	for _, content := range []string{"a", "b", "c", "d"} {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if content != "d" {
+			if err := rotateFiles(path, 2); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	files := historyFiles(path)
+	want := []string{"b", "c", "d"}
+
+	if len(files) != len(want) {
+		t.Fatalf("historyFiles(%s) = %v (wanted %d files)", path, files, len(want))
+	}
+
+	for i, fn := range files {
+		data, _ := ioutil.ReadFile(fn)
+		if string(data) != want[i] {
+			t.Errorf("%s = %q (wanted \"%v\")", fn, data, want[i])
+		}
+	}
+}
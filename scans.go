@@ -0,0 +1,213 @@
+// Scrub, resilver and trim progress for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// Scrubs and resilvers read every block of a pool, and trims keep the
+// devices busy, so cache behavior during one of them is not the usual. The
+// scans section shows what is running on each pool from "zpool status":
+// progress, rates and the time to go for scrubs and resilvers, and how far
+// each device is trimmed. Trim progress needs "zpool status -t", which older
+// versions of ZFS don't have. See arc_summary.go for the license
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// Current versions of ZFS print the progress of a scan as
+	// "1.23T scanned at 500M/s, 600G issued at 250M/s, 2.00T total" and
+	// "0B repaired, 30.00% done, 01:35:12 to go"
+	scanIssuedRE = regexp.MustCompile(`(\S+) scanned at (\S+), (\S+) issued at (\S+), (\S+) total`)
+	scanDoneRE   = regexp.MustCompile(`(\S+) (?:repaired|resilvered), ([\d.]+)% done, (.+)$`)
+
+	// OpenZFS 2.2 puts the total after both amounts, as in "1.03T / 3.49T
+	// scanned at 2.03G/s, 221G / 3.49T issued at 437M/s". A paused scan
+	// has no rates
+	scanSlashRE = regexp.MustCompile(`(\S+) / (\S+) scanned(?: at (\S+))?, (\S+) / \S+ issued(?: at (\S+))?`)
+
+	// Older versions print "123G scanned out of 1T at 100M/s, 2h30m to go"
+	// and "0 repaired, 12.30% done"
+	scanOldRE     = regexp.MustCompile(`(\S+) scanned out of (\S+) at (\S+), (.+) to go`)
+	scanOldDoneRE = regexp.MustCompile(`(\S+) (?:repaired|resilvered), ([\d.]+)% done`)
+
+	// Devices being trimmed are followed by "(45% trimmed, started at ...)"
+	trimRE = regexp.MustCompile(`\((\d+)% trimmed, started at`)
+)
+
+// poolScan is what zpool status says about the scans of a pool. Kind is
+// "scrub" or "resilver" if one is running or paused, last is the scan line
+// of the last one that finished
+type poolScan struct {
+	pool      string
+	kind      string
+	state     string
+	scanned   string
+	scanRate  string
+	issued    string
+	issueRate string
+	total     string
+	percent   string
+	eta       string
+	last      string
+	trims     []deviceTrim
+}
+
+// deviceTrim is a device that is being trimmed
+type deviceTrim struct {
+	device  string
+	percent string
+}
+
+// parseScanLine fills in the kind and state of a scan from the line after
+// "scan:", eg "scrub in progress since Sun Oct 11 00:24:01 2026"
+func parseScanLine(p *poolScan, line string) {
+
+	for _, kind := range []string{"scrub", "resilver"} {
+		for _, state := range []string{"in progress", "paused"} {
+			if strings.HasPrefix(line, kind+" "+state) {
+				p.kind, p.state = kind, state
+				return
+			}
+		}
+	}
+
+	if line != "none requested" {
+		p.last = line
+	}
+}
+
+// parseScanProgress fills in the progress of a scan from the lines below
+// the scan line
+func parseScanProgress(p *poolScan, line string) {
+
+	if m := scanSlashRE.FindStringSubmatch(line); m != nil {
+		p.scanned, p.total, p.scanRate, p.issued, p.issueRate = m[1], m[2], m[3], m[4], m[5]
+		return
+	}
+
+	if m := scanIssuedRE.FindStringSubmatch(line); m != nil {
+		p.scanned, p.scanRate, p.issued, p.issueRate, p.total = m[1], m[2], m[3], m[4], m[5]
+		return
+	}
+
+	if m := scanDoneRE.FindStringSubmatch(line); m != nil {
+		p.percent = m[2]
+		if strings.HasSuffix(m[3], " to go") {
+			p.eta = strings.TrimSuffix(m[3], " to go")
+		}
+		return
+	}
+
+	if m := scanOldRE.FindStringSubmatch(line); m != nil {
+		p.scanned, p.total, p.scanRate, p.eta = m[1], m[2], m[3], m[4]
+		return
+	}
+
+	if m := scanOldDoneRE.FindStringSubmatch(line); m != nil {
+		p.percent = m[2]
+	}
+}
+
+// parseZpoolScans takes the output of "zpool status" and returns the scans
+// of each pool in the order of the output
+func parseZpoolScans(out string) []poolScan {
+
+	var result []poolScan
+	var p *poolScan
+	inScan := false
+
+	input := bufio.NewScanner(strings.NewReader(out))
+
+	for input.Scan() {
+		line := strings.TrimSpace(input.Text())
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch {
+		case fields[0] == "pool:" && len(fields) > 1:
+			result = append(result, poolScan{pool: fields[1]})
+			p = &result[len(result)-1]
+			inScan = false
+			continue
+		case p == nil:
+			continue
+		case fields[0] == "scan:":
+			parseScanLine(p, strings.TrimSpace(strings.TrimPrefix(line, "scan:")))
+			inScan = p.kind != ""
+			continue
+		case strings.HasSuffix(fields[0], ":"):
+			inScan = false
+		}
+
+		if inScan {
+			parseScanProgress(p, line)
+			continue
+		}
+
+		if m := trimRE.FindStringSubmatch(line); m != nil {
+			p.trims = append(p.trims, deviceTrim{fields[0], m[1]})
+		}
+	}
+
+	return result
+}
+
+// printScans displays the scrubs, resilvers and trims of every pool
+func printScans() {
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	out, err := runCommand(ctx, "zpool", "status", "-t", "-P")
+	if err != nil {
+		out, err = runCommand(ctx, "zpool", "status", "-P")
+	}
+	if err != nil {
+		skipSection("scans", fmt.Errorf("couldn't run 'zpool status': %v", err))
+		return
+	}
+
+	active := false
+
+	for _, p := range parseZpoolScans(string(out)) {
+		fmt.Fprintf(reportOut, "\nPool %s:\n", redactName(p.pool))
+
+		if p.kind == "" && len(p.trims) == 0 {
+			prtL2("Running:", "nothing")
+		}
+
+		if p.kind != "" {
+			active = true
+			percent := p.percent
+			if percent == "" {
+				percent = "?"
+			}
+			prtL2(strings.ToUpper(p.kind[:1])+p.kind[1:]+":", fmt.Sprintf("%s, %s %% done", p.state, percent))
+			if p.scanned != "" {
+				prtL2("Scanned:", p.scanned+" of "+p.total+" at "+p.scanRate)
+			}
+			if p.issued != "" {
+				prtL2("Issued:", p.issued+" at "+p.issueRate)
+			}
+			if p.eta != "" {
+				prtL2("Time to go:", p.eta)
+			}
+		}
+
+		for _, t := range p.trims {
+			active = true
+			prtL2("Trimming "+redactPath(t.device)+":", t.percent+" % done")
+		}
+
+		if p.kind == "" && p.last != "" {
+			prtL2("Last scan:", p.last)
+		}
+	}
+
+	explain(explainScans(active))
+}
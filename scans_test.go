@@ -0,0 +1,69 @@
+// Test file for scans.go
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseZpoolScans(t *testing.T) {
+
+	out := `  pool: tank
+ state: ONLINE
+  scan: scrub in progress since Sun Oct 11 00:24:01 2026
+	1.23T scanned at 500M/s, 600G issued at 250M/s, 2.00T total
+	0B repaired, 30.00% done, 01:35:12 to go
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	tank        ONLINE       0     0     0
+	  sda       ONLINE       0     0     0  (45% trimmed, started at Sun Oct 11 01:00:00 2026)
+	  sdb       ONLINE       0     0     0  (100% trimmed, completed at Sun Oct 11 01:00:00 2026)
+
+errors: No known data errors
+
+  pool: old
+ state: DEGRADED
+  scan: resilver in progress since Sat Oct 10 10:00:00 2026
+    123G scanned out of 1T at 100M/s, 2h30m to go
+    12G resilvered, 12.30% done
+config:
+
+  pool: new
+ state: ONLINE
+  scan: scrub in progress since Thu Oct 15 08:00:00 2026
+	1.03T / 3.49T scanned at 2.03G/s, 221G / 3.49T issued at 437M/s
+	0B repaired, 6.18% done, 02:17:54 to go
+config:
+
+  pool: paused
+ state: ONLINE
+  scan: scrub paused since Thu Oct 15 09:00:00 2026
+	1.50T / 3.49T scanned, 1.20T / 3.49T issued
+	0B repaired, 34.38% done
+config:
+
+  pool: idle
+ state: ONLINE
+  scan: scrub repaired 0B in 01:23:45 with 0 errors on Sun Oct  4 01:47:46 2026
+config:
+`
+
+	got := parseZpoolScans(out)
+	wanted := []poolScan{
+		{pool: "tank", kind: "scrub", state: "in progress", scanned: "1.23T", scanRate: "500M/s",
+			issued: "600G", issueRate: "250M/s", total: "2.00T", percent: "30.00", eta: "01:35:12",
+			trims: []deviceTrim{{"sda", "45"}}},
+		{pool: "old", kind: "resilver", state: "in progress", scanned: "123G", scanRate: "100M/s",
+			total: "1T", percent: "12.30", eta: "2h30m"},
+		{pool: "new", kind: "scrub", state: "in progress", scanned: "1.03T", scanRate: "2.03G/s",
+			issued: "221G", issueRate: "437M/s", total: "3.49T", percent: "6.18", eta: "02:17:54"},
+		{pool: "paused", kind: "scrub", state: "paused", scanned: "1.50T", issued: "1.20T", total: "3.49T",
+			percent: "34.38"},
+		{pool: "idle", last: "scrub repaired 0B in 01:23:45 with 0 errors on Sun Oct  4 01:47:46 2026"},
+	}
+
+	if !reflect.DeepEqual(got, wanted) {
+		t.Errorf("parseZpoolScans() = %+v (wanted \"%+v\")", got, wanted)
+	}
+}
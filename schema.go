@@ -0,0 +1,174 @@
+// Catalogue of the known stats for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// "arc_summary schema" prints every stat the tool knows about as JSON: which
+// section it belongs to, if it is a counter or a gauge, its unit, the
+// description from -describe and the version of ZFS that added it where we
+// know it. Tools that build dashboards or alerts from our output can be
+// generated from this instead of keeping their own lists. See arc_summary.go
+// for the license
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// schemaEntry is one stat in the catalogue
+type schemaEntry struct {
+	Section     string   `json:"section"`
+	File        string   `json:"file"`
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Unit        string   `json:"unit,omitempty"`
+	Expected    bool     `json:"expected"`
+	Description string   `json:"description,omitempty"`
+	Reading     string   `json:"reading,omitempty"`
+	Since       string   `json:"since,omitempty"`
+	Aliases     []string `json:"aliases,omitempty"`
+}
+
+// kstatSince is the first version of ZFS on Linux or OpenZFS that has a stat,
+// for the stats where it matters
+var kstatSince = map[string]string{
+	"arcstats.dbuf_size":     "0.7",
+	"arcstats.dnode_size":    "0.7",
+	"arcstats.bonus_size":    "0.7",
+	"arcstats.iohits":        "2.2",
+	"arcstats.uncached_hits": "2.2",
+}
+
+// statSince returns the version that added a stat. The split of the lists
+// into data and metadata and the uncached list came with OpenZFS 2.2
+func statSince(file, name string) string {
+
+	if v, ok := kstatSince[file+"."+name]; ok {
+		return v
+	}
+
+	if file != "arcstats" {
+		return ""
+	}
+
+	if strings.HasPrefix(name, "uncached_") {
+		return "2.2"
+	}
+
+	for _, l := range arcLists {
+		if name == l.name+"_data" || name == l.name+"_metadata" {
+			return "2.2"
+		}
+	}
+
+	return ""
+}
+
+// statSection returns the section of the report a stat belongs to
+func statSection(file, name string) string {
+
+	if file == "arcstats" && strings.HasPrefix(name, "l2_") {
+		return "l2arc"
+	}
+
+	for s, f := range sectionPaths {
+		if f == file {
+			return s
+		}
+	}
+
+	return ""
+}
+
+// schemaEntries returns the catalogue sorted by file and name
+func schemaEntries() []schemaEntry {
+
+	names := make(map[string]map[string]bool)
+	add := func(file, name string) {
+		if names[file] == nil {
+			names[file] = make(map[string]bool)
+		}
+		names[file][name] = true
+	}
+
+	for _, file := range sectionPaths {
+		for _, n := range expectedStats[file] {
+			add(file, n)
+		}
+		for _, n := range optionalStats(file) {
+			add(file, n)
+		}
+	}
+
+	for k := range kstatDescs {
+		if idx := strings.Index(k, "."); idx != -1 {
+			add(k[:idx], k[idx+1:])
+		}
+	}
+
+	for k := range kstatAliases {
+		if idx := strings.Index(k, "."); idx != -1 {
+			add(k[:idx], k[idx+1:])
+		}
+	}
+
+	var result []schemaEntry
+
+	for file, m := range names {
+		for name := range m {
+			typ, unit := kstatType(file, name)
+			desc := kstatDescs[file+"."+name]
+
+			result = append(result, schemaEntry{
+				Section:     statSection(file, name),
+				File:        file,
+				Name:        name,
+				Type:        typ,
+				Unit:        unit,
+				Expected:    contains(expectedStats[file], name),
+				Description: desc.meaning,
+				Reading:     desc.reading,
+				Since:       statSince(file, name),
+				Aliases:     kstatAliases[file+"."+name],
+			})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].File != result[j].File {
+			return result[i].File < result[j].File
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// contains says if s is in list
+func contains(list []string, s string) bool {
+
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cmdSchema handles the "schema" subcommand
+func cmdSchema(args []string) {
+
+	if len(args) != 0 {
+		log.Fatal("Usage: arc_summary schema")
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(schemaEntries()); err != nil {
+		log.Fatal("Couldn't write schema: ", err)
+	}
+}
@@ -0,0 +1,51 @@
+// Test file for schema.go
+package main
+
+import "testing"
+
+func TestSchemaEntries(t *testing.T) {
+
+	entries := schemaEntries()
+
+	find := func(file, name string) *schemaEntry {
+		for i := range entries {
+			if entries[i].File == file && entries[i].Name == name {
+				return &entries[i]
+			}
+		}
+		return nil
+	}
+
+	var tests = []struct {
+		file, name    string
+		section, unit string
+		since         string
+		expected      bool
+	}{
+		{"arcstats", "hits", "arc", "", "", true},
+		{"arcstats", "c_max", "arc", "bytes", "", true},
+		{"arcstats", "l2_size", "l2arc", "bytes", "", true},
+		{"arcstats", "mru_data", "arc", "bytes", "2.2", false},
+		{"arcstats", "other_size", "arc", "bytes", "", false},
+		{"zil", "zil_commit_count", "zil", "", "", true},
+	}
+
+	for _, test := range tests {
+		e := find(test.file, test.name)
+		if e == nil {
+			t.Errorf("schemaEntries() has no %s.%s", test.file, test.name)
+			continue
+		}
+		if e.Section != test.section || e.Unit != test.unit || e.Since != test.since || e.Expected != test.expected {
+			t.Errorf("schemaEntries() %s.%s = %+v (wanted section %s, unit %s, since %s, expected %v)",
+				test.file, test.name, *e, test.section, test.unit, test.since, test.expected)
+		}
+	}
+
+	for i := 1; i < len(entries); i++ {
+		a, b := entries[i-1], entries[i]
+		if a.File > b.File || (a.File == b.File && a.Name >= b.Name) {
+			t.Errorf("schemaEntries() not sorted at %s.%s", b.File, b.Name)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Listing of the report sections for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// -list-sections prints every section -s accepts together with where its
+// data comes from and if that exists on this system, so wrapper scripts
+// can build menus without hardcoding the names. With -o json the list is
+// printed as JSON. See arc_summary.go for the license
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+)
+
+// sectionInfo describes a section of the report
+type sectionInfo struct {
+	Name      string `json:"name"`
+	Optional  bool   `json:"optional"`
+	Source    string `json:"source"`
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// sectionSources are the data sources of the sections that don't print a
+// kstat file, and a check if they can be read
+var sectionSources = map[string]struct {
+	source string
+	check  func(ctx context.Context) error
+}{
+	"tunables": {tunablesPath, func(ctx context.Context) error {
+		_, err := readDirNames(ctx, tunablesPath)
+		return err
+	}},
+	"bench": {procPath + "*_bench", func(ctx context.Context) error {
+		_, err := readFile(ctx, procPath+"fletcher_4_bench")
+		return err
+	}},
+	"disks": {diskstatsPath + ", zpool status", func(ctx context.Context) error {
+		if _, err := readFile(ctx, diskstatsPath); err != nil {
+			return err
+		}
+		_, err := runCommand(ctx, "zpool", "status", "-P")
+		return err
+	}},
+	"encryption": {"zpool get, kcf kstat", func(ctx context.Context) error {
+		_, err := runCommand(ctx, "zpool", "get", "-H", "-o", "name,value", "feature@encryption")
+		return err
+	}},
+	"errors": {"zpool status", func(ctx context.Context) error {
+		_, err := runCommand(ctx, "zpool", "status", "-P")
+		return err
+	}},
+	"icp": {icpParamsPath, func(ctx context.Context) error {
+		_, err := readDirNames(ctx, icpParamsPath)
+		return err
+	}},
+	"scans": {"zpool status", func(ctx context.Context) error {
+		_, err := runCommand(ctx, "zpool", "status", "-P")
+		return err
+	}},
+	"txgs": {procPath + "<pool>/txgs", func(ctx context.Context) error {
+		_, err := runCommand(ctx, "zpool", "list", "-H", "-o", "name")
+		return err
+	}},
+	"latency": {"zpool iostat -w, dmu_tx_assign", func(ctx context.Context) error {
+		_, err := runCommand(ctx, "zpool", "iostat", "-w", "-H", "-p")
+		return err
+	}},
+	"queues": {"zpool iostat", func(ctx context.Context) error {
+		_, err := runCommand(ctx, "zpool", "iostat", "-q", "-v", "-H", "-p")
+		return err
+	}},
+}
+
+// sectionStatus returns what we know about a section. The kstats must have
+// been read already
+func sectionStatus(ctx context.Context, name string, optional bool) sectionInfo {
+
+	info := sectionInfo{Name: name, Optional: optional}
+
+	if linuxSections[name] && runtime.GOOS != "linux" && bundleFiles == nil {
+		info.Source = sectionSources[name].source
+		info.Reason = "not supported on " + runtime.GOOS
+		return info
+	}
+
+	if src, ok := sectionSources[name]; ok {
+		info.Source = src.source
+		if err := src.check(ctx); err != nil {
+			info.Reason = err.Error()
+		} else {
+			info.Available = true
+		}
+		return info
+	}
+
+	// The L2ARC stats are part of arcstats
+	file := sectionPaths[name]
+	if name == "l2arc" {
+		file = sectionPaths["arc"]
+	}
+
+	info.Source = procPath + file
+	if err := kstatError(file); err != nil {
+		info.Reason = err.Error()
+	} else {
+		info.Available = true
+	}
+
+	return info
+}
+
+// listSections returns the status of all sections, the normal ones first
+func listSections(ctx context.Context) []sectionInfo {
+
+	var result []sectionInfo
+
+	for _, s := range sections {
+		result = append(result, sectionStatus(ctx, s, false))
+	}
+	for _, s := range optionalSections {
+		result = append(result, sectionStatus(ctx, s, true))
+	}
+
+	return result
+}
+
+// printSectionList prints the sections as text or, with -o json, as JSON
+func printSectionList() {
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	list := listSections(ctx)
+
+	if *OptOutput == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(list); err != nil {
+			log.Fatal("Couldn't write section list: ", err)
+		}
+		return
+	}
+
+	for _, s := range list {
+		status := "yes"
+		if !s.Available {
+			status = "no (" + s.Reason + ")"
+		}
+
+		if *OptPlain {
+			printPlain(s.Name, "", plainList("source", s.Source, "available", status))
+			continue
+		}
+
+		fmt.Fprintf(reportOut, "%-12s%-40s%s\n", s.Name, s.Source, status)
+	}
+}
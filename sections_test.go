@@ -0,0 +1,39 @@
+// Test file for sections.go
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestSectionStatus(t *testing.T) {
+
+	kstatErrors["zil"] = os.ErrNotExist
+	defer delete(kstatErrors, "zil")
+	delete(kstatErrors, "arcstats")
+
+	var tests = []struct {
+		name      string
+		source    string
+		available bool
+	}{
+		{"arc", procPath + "arcstats", true},
+		{"l2arc", procPath + "arcstats", true},
+		{"zil", procPath + "zil", false},
+	}
+
+	for _, test := range tests {
+		got := sectionStatus(context.Background(), test.name, false)
+		if got.Source != test.source || got.Available != test.available {
+			t.Errorf("sectionStatus(%s) = %v (wanted \"%v %v\")", test.name, got, test.source, test.available)
+		}
+		if !got.Available && got.Reason == "" {
+			t.Errorf("sectionStatus(%s) gives no reason", test.name)
+		}
+	}
+
+	if n := len(listSections(context.Background())); n != len(sections)+len(optionalSections) {
+		t.Errorf("listSections returned %d sections (wanted %d)", n, len(sections)+len(optionalSections))
+	}
+}
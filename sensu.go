@@ -0,0 +1,100 @@
+// Sensu check output for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// "-o sensu" makes arc_summary a Sensu Go check: the exit code is the check
+// status, the first line of output the summary, and the lines after it the
+// metrics in Graphite or InfluxDB line format as chosen with -sensu-metrics,
+// to be picked up with the matching output_metric_format. Run it with -state
+// so the check can warn about throttling since the last run. See
+// arc_summary.go for the license
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// sensuMetricFormats are the metric formats known to -sensu-metrics
+var sensuMetricFormats = map[string]func(io.Writer, []checkMetric, int64) error{
+	"graphite": writeGraphite,
+	"influx":   writeInflux,
+}
+
+// sensuFormatNames returns the names of the metric formats in alphabetical
+// order
+func sensuFormatNames() []string {
+
+	var names []string
+
+	for n := range sensuMetricFormats {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// sensuRenderer writes the check result for Sensu
+type sensuRenderer struct{}
+
+func (sensuRenderer) Render(w io.Writer, s *StatsSet) error {
+
+	write, ok := sensuMetricFormats[*OptSensuMetrics]
+	if !ok {
+		return fmt.Errorf("unknown metric format '%s'", *OptSensuMetrics)
+	}
+
+	state, summary := checkState(s)
+	if _, err := fmt.Fprintf(w, "ZFS %s: %s\n", checkStateNames[state], summary); err != nil {
+		return err
+	}
+
+	return write(w, checkMetrics(s), s.Time.Unix())
+}
+
+func (sensuRenderer) Status(s *StatsSet) int {
+	state, _ := checkState(s)
+	return state
+}
+
+// writeGraphite writes the metrics in Graphite plaintext format, eg
+// "zfs.arc.size 7500000000 1700000000"
+func writeGraphite(w io.Writer, metrics []checkMetric, ts int64) error {
+
+	for _, m := range metrics {
+		_, err := fmt.Fprintf(w, "zfs.%s.%s %s %d\n", m.service, m.name,
+			strconv.FormatFloat(m.value, 'f', -1, 64), ts)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeInflux writes the metrics in InfluxDB line protocol with one line for
+// each service, eg "zfs_arc size=7500000000,hit_ratio=95.2 1700000000000000000"
+func writeInflux(w io.Writer, metrics []checkMetric, ts int64) error {
+
+	var services []string
+	fields := make(map[string][]string)
+
+	for _, m := range metrics {
+		if _, ok := fields[m.service]; !ok {
+			services = append(services, m.service)
+		}
+		fields[m.service] = append(fields[m.service], m.name+"="+strconv.FormatFloat(m.value, 'f', -1, 64))
+	}
+
+	for _, svc := range services {
+		_, err := fmt.Fprintf(w, "zfs_%s %s %d\n", svc, strings.Join(fields[svc], ","), ts*1000000000)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
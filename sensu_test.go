@@ -0,0 +1,34 @@
+// Test file for sensu.go
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSensuMetrics(t *testing.T) {
+
+	metrics := []checkMetric{
+		{"arc", "size", 100, "bytes"},
+		{"arc", "hit_ratio", 95.5, "percent"},
+		{"zil", "commit_count", 7, "count"},
+	}
+
+	var tests = []struct {
+		format string
+		want   string
+	}{
+		{"graphite", "zfs.arc.size 100 10\nzfs.arc.hit_ratio 95.5 10\nzfs.zil.commit_count 7 10\n"},
+		{"influx", "zfs_arc size=100,hit_ratio=95.5 10000000000\nzfs_zil commit_count=7 10000000000\n"},
+	}
+
+	for _, test := range tests {
+		var b bytes.Buffer
+		if err := sensuMetricFormats[test.format](&b, metrics, 10); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != test.want {
+			t.Errorf("%s metrics = %q (wanted %q)", test.format, b.String(), test.want)
+		}
+	}
+}
@@ -0,0 +1,442 @@
+// HTTP server for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// "arc_summary serve" collects the stats at a fixed interval and serves the
+// most recent set over HTTP: /metrics for Prometheus (or OpenMetrics if the
+// scraper asks for it), /api/stats as JSON and /report as the text report
+// (/report?section=arc for a single section). Requests never read /proc
+// themselves. If -history is given, every set is also added to the history.
+// TLS and basic or bearer token authentication are optional. Besides TCP, the
+// server can listen on a unix socket ("-listen unix:/run/arc_summary.sock")
+// or on a socket handed over by systemd. Scheduled jobs from the
+// configuration file run alongside (see cron.go). See arc_summary.go for the
+// license
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultListen = ":9134"
+	unixPrefix    = "unix:"
+
+	// systemd passes sockets starting at this file descriptor
+	listenFdsStart = 3
+)
+
+// server holds the most recent stats for the handlers
+type server struct {
+	notifier dbusNotifier // only used by the collecting goroutine
+
+	mu      sync.RWMutex
+	set     *StatsSet
+	reports map[string][]byte // by section, "" is the whole report
+}
+
+// refresh collects the stats and renders the report. Only the collecting
+// goroutine calls this, since the print functions use the globals
+func (s *server) refresh() {
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	sampleSeq++
+
+	set, err := kstatCollector{}.Collect(ctx)
+	if err != nil {
+		log.Print("Couldn't collect stats: ", err)
+		return
+	}
+
+	updateAverages(set.Time)
+	set.Averages = averages.values()
+
+	if *OptDBus {
+		s.notifier.update(ctx)
+	}
+
+	if *OptHistory != "" {
+		appendHistory(*OptHistory)
+	}
+
+	publishSample(set)
+
+	// The header runs zpool, so it is only made once for all reports
+	reportWarnings = nil
+	header := captureOutput(printHeader)
+
+	withHeader := func(f func()) []byte {
+		return append(append([]byte(nil), header...), captureOutput(f)...)
+	}
+
+	reports := map[string][]byte{"": withHeader(printReportBody)}
+
+	for _, sec := range sections {
+		reports[sec] = withHeader(func() { printSection(sec) })
+	}
+
+	s.mu.Lock()
+	s.set, s.reports = set, reports
+	s.mu.Unlock()
+}
+
+// current returns the most recent stats and the report of a section
+func (s *server) current(section string) (*StatsSet, []byte) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.set, s.reports[section]
+}
+
+// handleRenderer returns a handler that writes the stats with a renderer
+func (s *server) handleRenderer(r Renderer, contentType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+
+		set, _ := s.current("")
+		if set == nil {
+			http.Error(w, "no stats collected yet", http.StatusServiceUnavailable)
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := r.Render(&buf, set); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Write(buf.Bytes())
+	}
+}
+
+// handleMetrics serves OpenMetrics to scrapers that ask for it and the
+// Prometheus text format to everyone else
+func (s *server) handleMetrics(w http.ResponseWriter, req *http.Request) {
+
+	if strings.Contains(req.Header.Get("Accept"), "application/openmetrics-text") {
+		s.handleRenderer(openMetricsRenderer{}, "application/openmetrics-text; version=1.0.0; charset=utf-8")(w, req)
+		return
+	}
+
+	s.handleRenderer(prometheusRenderer{}, "text/plain; version=0.0.4; charset=utf-8")(w, req)
+}
+
+// handleReport serves the text report
+func (s *server) handleReport(w http.ResponseWriter, req *http.Request) {
+
+	section := req.URL.Query().Get("section")
+	if section != "" && !isLegalSection(section) {
+		http.Error(w, "unknown section", http.StatusNotFound)
+		return
+	}
+
+	set, report := s.current(section)
+	if set == nil {
+		http.Error(w, "no stats collected yet", http.StatusServiceUnavailable)
+		return
+	}
+	if report == nil {
+		http.Error(w, "section not collected by the server", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write(report)
+}
+
+// auth holds the credentials a request may use. If both are empty, no
+// authentication is required
+type auth struct {
+	users  map[string]string // basic auth user -> password
+	tokens []string          // bearer tokens
+}
+
+// readBasicAuthFile reads "user:password" lines
+func readBasicAuthFile(path string) (map[string]string, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	users := make(map[string]string)
+	input := bufio.NewScanner(f)
+
+	for input.Scan() {
+		l := strings.TrimSpace(input.Text())
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+
+		idx := strings.Index(l, ":")
+		if idx <= 0 {
+			return nil, fmt.Errorf("%s: line '%s' is not user:password", path, l)
+		}
+		users[l[:idx]] = l[idx+1:]
+	}
+
+	return users, input.Err()
+}
+
+// readTokenFile reads one bearer token per line
+func readTokenFile(path string) ([]string, error) {
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := strings.Fields(string(data))
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("%s has no tokens", path)
+	}
+
+	return tokens, nil
+}
+
+// equal compares secrets in constant time
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// allowed tests if a request carries valid credentials
+func (a auth) allowed(req *http.Request) bool {
+
+	if len(a.users) == 0 && len(a.tokens) == 0 {
+		return true
+	}
+
+	if user, pass, ok := req.BasicAuth(); ok {
+		if want, ok := a.users[user]; ok && equal(pass, want) {
+			return true
+		}
+	}
+
+	h := req.Header.Get("Authorization")
+	if strings.HasPrefix(h, "Bearer ") {
+		got := strings.TrimPrefix(h, "Bearer ")
+		for _, t := range a.tokens {
+			if equal(got, t) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// wrap returns a handler that only passes on authenticated requests
+func (a auth) wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !a.allowed(req) {
+			if len(a.users) > 0 {
+				w.Header().Set("WWW-Authenticate", `Basic realm="arc_summary"`)
+			}
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, req)
+	})
+}
+
+// systemdListener returns the socket passed by systemd socket activation,
+// or nil if there is none. See sd_listen_fds(3)
+func systemdListener() (net.Listener, error) {
+
+	if os.Getenv("LISTEN_PID") != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, nil
+	}
+	if n > 1 {
+		return nil, fmt.Errorf("systemd passed %d sockets, but we can only use one", n)
+	}
+
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+
+	return net.FileListener(os.NewFile(listenFdsStart, "systemd socket"))
+}
+
+// unixListener removes its socket when it is closed. The socket was created
+// under a temporary name, which net would try to remove instead
+type unixListener struct {
+	*net.UnixListener
+	path string
+}
+
+func (l unixListener) Close() error {
+	err := l.UnixListener.Close()
+	os.Remove(l.path)
+	return err
+}
+
+// listen opens the socket to serve on. Addresses starting with "unix:" are
+// unix sockets, which get the given permissions. The socket is made under a
+// temporary name and renamed once it has them, so nobody can connect before.
+// A socket left over from an earlier run is replaced, unless another
+// instance is still listening on it
+func listen(addr string, mode os.FileMode) (net.Listener, error) {
+
+	if l, err := systemdListener(); l != nil || err != nil {
+		return l, err
+	}
+
+	if !strings.HasPrefix(addr, unixPrefix) {
+		return net.Listen("tcp", addr)
+	}
+
+	path := strings.TrimPrefix(addr, unixPrefix)
+
+	if c, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		c.Close()
+		return nil, fmt.Errorf("another instance is listening on %s", path)
+	}
+
+	tmp := filepath.Join(filepath.Dir(path), fmt.Sprintf(".%s.%d", filepath.Base(path), os.Getpid()))
+	os.Remove(tmp)
+
+	l, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, err
+	}
+
+	ul := l.(*net.UnixListener)
+	ul.SetUnlinkOnClose(false)
+
+	if err := os.Chmod(tmp, mode); err != nil {
+		ul.Close()
+		os.Remove(tmp)
+		return nil, err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		ul.Close()
+		os.Remove(tmp)
+		return nil, err
+	}
+
+	return unixListener{ul, path}, nil
+}
+
+// cmdServe handles the "serve" subcommand
+func cmdServe(args []string) {
+
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("listen", defaultListen, "Address to listen on, or unix:<path> for a unix socket")
+	sockMode := fs.String("socket-mode", "0660", "Permissions of the unix socket")
+	interval := fs.Duration("interval", 15*time.Second, "How often to collect the stats")
+	certFile := fs.String("tls-cert", "", "TLS certificate file (needs -tls-key)")
+	keyFile := fs.String("tls-key", "", "TLS key file (needs -tls-cert)")
+	basicFile := fs.String("basic-auth-file", "", "File with user:password lines for basic authentication")
+	tokenFile := fs.String("bearer-token-file", "", "File with bearer tokens, one per line")
+	fs.Parse(args)
+
+	if (*certFile == "") != (*keyFile == "") {
+		log.Fatal("-tls-cert and -tls-key must be given together")
+	}
+
+	mode, err := strconv.ParseUint(*sockMode, 8, 32)
+	if err != nil {
+		log.Fatal("Bad -socket-mode '", *sockMode, "': ", err)
+	}
+
+	var a auth
+
+	if *basicFile != "" {
+		if a.users, err = readBasicAuthFile(*basicFile); err != nil {
+			log.Fatal("Couldn't read basic auth file: ", err)
+		}
+	}
+
+	if *tokenFile != "" {
+		if a.tokens, err = readTokenFile(*tokenFile); err != nil {
+			log.Fatal("Couldn't read bearer token file: ", err)
+		}
+	}
+
+	if (len(a.users) > 0 || len(a.tokens) > 0) && *certFile == "" {
+		log.Print("WARNING: credentials will be sent in clear text without -tls-cert")
+	}
+
+	lockDaemon()
+
+	s := &server{}
+
+	// With jitter, the first collection waits as well, so servers started
+	// together don't stay in step. Until then, requests get an error
+	if *OptJitter > 0 {
+		go func() {
+			time.Sleep(firstDelay(*OptJitter))
+			s.refresh()
+			for {
+				time.Sleep(jittered(*interval, *OptJitter))
+				s.refresh()
+			}
+		}()
+	} else {
+		s.refresh()
+		go func() {
+			for range time.Tick(*interval) {
+				s.refresh()
+			}
+		}()
+	}
+
+	go runJobs(s, cfg.Jobs)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	mux.HandleFunc("/api/stats", s.handleRenderer(jsonRenderer{}, "application/json"))
+	mux.HandleFunc("/report", s.handleReport)
+
+	l, err := listen(*addr, os.FileMode(mode))
+	if err != nil {
+		log.Fatal("Couldn't listen on ", *addr, ": ", err)
+	}
+
+	srv := &http.Server{Handler: a.wrap(mux)}
+
+	// Finish the requests being served before quitting
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, stopSignals...)
+
+	go func() {
+		<-sigs
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	if *certFile != "" {
+		err = srv.ServeTLS(l, *certFile, *keyFile)
+	} else {
+		err = srv.Serve(l)
+	}
+
+	releasePidFile()
+
+	if err != nil && err != http.ErrServerClosed {
+		log.Fatal("Server failed: ", err)
+	}
+}
@@ -0,0 +1,100 @@
+// Test file for serve.go
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAuthAllowed(t *testing.T) {
+
+	a := auth{
+		users:  map[string]string{"admin": "secret"},
+		tokens: []string{"tok123"},
+	}
+
+	var tests = []struct {
+		user, pass string
+		bearer     string
+		wanted     bool
+	}{
+		{"admin", "secret", "", true},
+		{"admin", "wrong", "", false},
+		{"nobody", "secret", "", false},
+		{"", "", "tok123", true},
+		{"", "", "tok12", false},
+		{"", "", "", false},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/metrics", nil)
+		if test.user != "" {
+			req.SetBasicAuth(test.user, test.pass)
+		}
+		if test.bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+test.bearer)
+		}
+
+		if got := a.allowed(req); got != test.wanted {
+			t.Errorf("allowed(%s:%s, %s) = %v (wanted \"%v\")", test.user, test.pass, test.bearer, got, test.wanted)
+		}
+	}
+
+	if !(auth{}).allowed(httptest.NewRequest("GET", "/metrics", nil)) {
+		t.Errorf("allowed() without credentials configured = false (wanted \"true\")")
+	}
+}
+
+func TestListenUnix(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "arc_summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.sock")
+
+	l, err := listen(unixPrefix+path, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode()&os.ModeSocket == 0 || info.Mode().Perm() != 0600 {
+		t.Errorf("listen(unix:%s) made %v (wanted \"socket with 0600\")", path, info.Mode())
+	}
+
+	// A live socket is not taken over
+	if l2, err := listen(unixPrefix+path, 0600); err == nil {
+		l2.Close()
+		t.Errorf("listen(unix:%s) while in use succeeded (wanted error)", path)
+	}
+
+	// A stale one is replaced, and removed on close
+	l.Close()
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	l, err = listen(unixPrefix+path, 0600)
+	if err != nil {
+		t.Fatalf("listen(unix:%s) over a stale socket = %v", path, err)
+	}
+	l.Close()
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket %s still there after Close (%v)", path, err)
+	}
+}
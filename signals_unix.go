@@ -0,0 +1,20 @@
+// Signals for watch mode on Unix systems
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// See arc_summary.go for the license
+
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+var (
+	reloadSignal   os.Signal = syscall.SIGHUP
+	snapshotSignal os.Signal = syscall.SIGUSR1
+	stopSignals              = []os.Signal{syscall.SIGTERM, syscall.SIGINT}
+)
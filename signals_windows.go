@@ -0,0 +1,18 @@
+// Signals for watch mode on Windows
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// Windows only knows about interrupts, so there is no way to reload the
+// configuration or ask for a snapshot. See arc_summary.go for the license
+
+//go:build windows
+// +build windows
+
+package main
+
+import "os"
+
+var (
+	reloadSignal   os.Signal
+	snapshotSignal os.Signal
+	stopSignals    = []os.Signal{os.Interrupt}
+)
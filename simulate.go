@@ -0,0 +1,197 @@
+// What-if simulation of the ARC size for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// With -simulate-arc-max, we estimate how the hit ratio since boot would
+// have changed with a different zfs_arc_max, before anyone touches the
+// tunable. The ghost lists remember blocks recently evicted from the MRU and
+// MFU lists, so their hits are what a larger ARC would have turned into real
+// hits. We give the extra memory to the list with the most ghost hits per
+// byte first, as the ARC adapts that way too. Nothing tells us what lies
+// beyond the ghost lists, so growth past them is not counted. For a smaller
+// ARC, the blocks at the cold end of the lists are lost. Their hits are at
+// least those of the ghost lists per byte and at most the average of the
+// list, so we give a range. This is a rough estimate that assumes the
+// workload stays the same. See arc_summary.go for the license
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// simList is what we know about the MRU or MFU list of the ARC
+type simList struct {
+	name      string
+	size      uint64
+	hits      uint64
+	ghostSize uint64
+	ghostHits uint64
+}
+
+// ghostDensity returns the ghost hits per byte of ghost list
+func (l simList) ghostDensity() float64 {
+
+	if l.ghostSize == 0 {
+		return 0
+	}
+
+	return float64(l.ghostHits) / float64(l.ghostSize)
+}
+
+// simResult is the estimated change in hits for a new maximum size. Gains
+// are positive, losses negative, low is the worse of the two. Beyond says
+// the new size goes past what the ghost lists can tell us, notFull that the
+// ARC never reached its maximum, so raising it changes nothing
+type simResult struct {
+	low, high float64
+	beyond    bool
+	notFull   bool
+}
+
+// simulateArcMax estimates the change in hits if the maximum size of an ARC
+// of the given size and maximum had been target
+func simulateArcMax(lists []simList, size, cMax, target uint64) simResult {
+
+	var r simResult
+
+	switch {
+
+	case target > cMax:
+		if float64(size) < 0.95*float64(cMax) {
+			r.notFull = true
+			return r
+		}
+
+		sorted := append([]simList{}, lists...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].ghostDensity() > sorted[j].ghostDensity()
+		})
+
+		extra := target - cMax
+		for _, l := range sorted {
+			use := l.ghostSize
+			if use > extra {
+				use = extra
+			}
+			r.high += float64(use) * l.ghostDensity()
+			extra -= use
+		}
+
+		r.low = r.high
+		r.beyond = extra > 0
+
+	case target < size:
+		var listsSize uint64
+		for _, l := range lists {
+			listsSize += l.size
+		}
+		if listsSize == 0 {
+			return r
+		}
+
+		removed := float64(size - target)
+
+		for _, l := range lists {
+			if l.size == 0 {
+				continue
+			}
+
+			gone := removed * float64(l.size) / float64(listsSize)
+			if gone > float64(l.size) {
+				gone = float64(l.size)
+			}
+
+			best := gone * l.ghostDensity()
+			worst := gone * float64(l.hits) / float64(l.size)
+			if best > worst {
+				best, worst = worst, best
+			}
+
+			r.high -= best
+			r.low -= worst
+		}
+	}
+
+	return r
+}
+
+// printSimulation prints the estimated hit ratio with a maximum ARC size of
+// target bytes
+func printSimulation(target uint64) {
+
+	var arcStats = make(map[string]string)
+	procSection("arcstats", arcStats)
+
+	get := func(name string) uint64 {
+		v, _ := strconv.ParseUint(arcStats[name], 10, 64)
+		return v
+	}
+
+	lists := []simList{
+		{"MRU", get("mru_size"), get("mru_hits"), get("mru_ghost_size"), get("mru_ghost_hits")},
+		{"MFU", get("mfu_size"), get("mfu_hits"), get("mfu_ghost_size"), get("mfu_ghost_hits")},
+	}
+
+	size, cMax := get("size"), get("c_max")
+	hits, misses := get("hits"), get("misses")
+	total := hits + misses
+
+	r := simulateArcMax(lists, size, cMax, target)
+
+	u := func(v uint64) string { return strconv.FormatUint(v, 10) }
+	ratio := func(delta float64) float64 {
+		if total == 0 {
+			return 0
+		}
+		return (float64(hits) + delta) / float64(total) * 100
+	}
+
+	printTitle("arc size simulation")
+
+	prtL1p("ARC size:", fPerc(u(size), u(cMax)), fBytes(u(size)))
+	prtL2("Max size (c_max):", fBytes(u(cMax)))
+	prtL2("Simulated max size:", fBytes(u(target)))
+
+	for _, l := range lists {
+		prtL2p(l.name+" ghost hits (of misses):", fPerc(u(l.ghostHits), u(misses)), fHits(u(l.ghostHits)))
+		prtL2(l.name+" ghost list size:", fBytes(u(l.ghostSize)))
+	}
+
+	prtL2("Hit ratio since boot:", fmt.Sprintf("%.2f %%", ratio(0)))
+
+	projected := fmt.Sprintf("%.2f %%", ratio(r.low))
+	if r.low != r.high {
+		projected = fmt.Sprintf("%.2f - %.2f %%", ratio(r.low), ratio(r.high))
+	}
+	prtL2("Projected hit ratio:", projected)
+
+	change := fmt.Sprintf("%+.0f", r.low)
+	if r.low != r.high {
+		change = fmt.Sprintf("%+.0f to %+.0f", r.low, r.high)
+	}
+	prtL2("Change in hits:", change)
+
+	fmt.Fprintln(reportOut)
+
+	switch {
+	case r.notFull:
+		fmt.Fprintln(reportOut, "The ARC never filled up to its current maximum, so a larger one\n"+
+			"would not have cached more.")
+	case r.beyond:
+		fmt.Fprintln(reportOut, "The simulated size is larger than the ghost lists reach, so the\n"+
+			"gain beyond them can't be estimated and is not counted.")
+	case target < size:
+		fmt.Fprintln(reportOut, "A smaller ARC loses the blocks at the cold end of its lists. The\n"+
+			"range goes from losing only as many hits as the ghost lists to\n"+
+			"losing the average hits of the lists.")
+	}
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	if meminfo, err := readMeminfo(ctx); err == nil && target > meminfo["MemTotal"] {
+		fmt.Fprintf(reportOut, "WARNING: the simulated size is more than the %s of RAM of this system\n",
+			fBytes(u(meminfo["MemTotal"])))
+	}
+}
@@ -0,0 +1,32 @@
+// Test file for simulate.go
+package main
+
+import "testing"
+
+func TestSimulateArcMax(t *testing.T) {
+
+	// The MFU ghost list has twice the hits per byte of the MRU one, so
+	// it is filled first
+	lists := []simList{
+		{"MRU", 4 * gib, 4000, 2 * gib, 200},
+		{"MFU", 4 * gib, 8000, gib, 200},
+	}
+
+	var tests = []struct {
+		size, cMax, target uint64
+		want               simResult
+	}{
+		{8 * gib, 8 * gib, 9 * gib, simResult{low: 200, high: 200}},
+		{8 * gib, 8 * gib, 10 * gib, simResult{low: 300, high: 300}},
+		{8 * gib, 8 * gib, 12 * gib, simResult{low: 400, high: 400, beyond: true}},
+		{4 * gib, 8 * gib, 12 * gib, simResult{notFull: true}},
+		{8 * gib, 8 * gib, 8 * gib, simResult{}},
+		{8 * gib, 16 * gib, 6 * gib, simResult{low: -3000, high: -300}},
+	}
+
+	for _, test := range tests {
+		if got := simulateArcMax(lists, test.size, test.cMax, test.target); got != test.want {
+			t.Errorf("simulateArcMax(%d, %d, %d) = %+v (wanted \"%+v\")", test.size, test.cMax, test.target, got, test.want)
+		}
+	}
+}
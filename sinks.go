@@ -0,0 +1,118 @@
+// Output sinks for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// Sinks send every sample to some other system as it is collected: once for
+// a single report, at every refresh in watch and serve mode. Which sinks are
+// used depends on the flags given. A sink that fails is logged and doesn't
+// stop the others or the report. See arc_summary.go for the license
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// sink sends a sample somewhere
+type sink interface {
+	Name() string
+	Publish(ctx context.Context, s *StatsSet) error
+}
+
+// activeSinks returns the sinks selected by the flags
+func activeSinks() []sink {
+
+	var result []sink
+
+	if *OptKafkaBrokers != "" {
+		result = append(result, kafkaSink{brokers: *OptKafkaBrokers, topic: *OptKafkaTopic})
+	}
+
+	if *OptMQTTBroker != "" {
+		result = append(result, mqttSink{broker: *OptMQTTBroker, prefix: *OptMQTTPrefix})
+	}
+
+	if *OptRedis != "" {
+		result = append(result, redisSink{addr: *OptRedis, ttl: *OptRedisTTL, stream: *OptRedisStream})
+	}
+
+	if *OptPush != "" {
+		result = append(result, pushSink{url: *OptPush, job: *OptPushJob})
+	}
+
+	return result
+}
+
+// publishSample sends the sample to all active sinks
+func publishSample(s *StatsSet) {
+
+	for _, k := range activeSinks() {
+		ctx, cancel := collectContext()
+		if err := k.Publish(ctx, s); err != nil {
+			log.Printf("Couldn't publish to %s: %v", k.Name(), err)
+		}
+		cancel()
+	}
+}
+
+// sinkHost returns the name of this host as the sinks use it
+func sinkHost() string {
+
+	host, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+
+	return redactName(host)
+}
+
+// kafkaSink publishes every sample as a JSON message to a Kafka topic, with
+// the hostname as key. Kafka's protocol is far from simple, so the message
+// is handed to kcat (formerly kafkacat), much like D-Bus signals are sent
+// with gdbus
+type kafkaSink struct {
+	brokers string
+	topic   string
+}
+
+// kafkaKeyDelim separates the key from the message for kcat. JSON never
+// contains a raw tab
+const kafkaKeyDelim = "\t"
+
+func (kafkaSink) Name() string { return "Kafka" }
+
+// kcatArgs returns the arguments for kcat to produce one message
+func (k kafkaSink) kcatArgs() []string {
+	return []string{"-P", "-b", k.brokers, "-t", k.topic, "-K", kafkaKeyDelim}
+}
+
+func (k kafkaSink) Publish(ctx context.Context, s *StatsSet) error {
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	var msg bytes.Buffer
+	msg.WriteString(sinkHost() + kafkaKeyDelim)
+	msg.Write(data)
+	msg.WriteByte('\n')
+
+	if err := execLimit.wait(ctx); err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, "kcat", k.kcatArgs()...)
+	cmd.Stdin = &msg
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("kcat: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
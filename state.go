@@ -0,0 +1,143 @@
+// State kept between runs of arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// With -state, the last sample and events derived from comparing samples are
+// saved to a JSON file, so runs from cron can report on what happened in
+// between. See arc_summary.go for the license
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// We only keep a week of events
+	stateEventMaxAge = 7 * 24 * time.Hour
+
+	// A drop of the target size c smaller than this fraction is normal
+	// adaptive behavior and not counted as shrinking
+	shrinkThreshold = 0.01
+)
+
+// runState is what we remember between runs
+type runState struct {
+	Last    *historyRecord `json:"last"`
+	Shrinks []int64        `json:"shrinks"`
+}
+
+var state runState
+
+// loadState reads the state file. A missing file is not an error, since
+// there is no state on the first run
+func loadState(path string, s *runState) {
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return
+		}
+		log.Fatal("Couldn't read state file ", path, ": ", err)
+	}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		log.Fatal("Couldn't parse state file ", path, ": ", err)
+	}
+}
+
+// saveState writes the state file via a temporary file so an interrupted
+// run doesn't leave a corrupt file behind
+func saveState(path string, s *runState) {
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		log.Fatal("Couldn't encode state: ", err)
+	}
+
+	tmp := path + ".tmp"
+
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		log.Fatal("Couldn't write state file ", tmp, ": ", err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		log.Fatal("Couldn't write state file ", path, ": ", err)
+	}
+}
+
+// updateState compares the current stats with the last run, records any
+// events, and saves the current stats as the new last run
+func updateState(path string) {
+
+	loadState(path, &state)
+
+	now := historyRecord{Time: time.Now().Unix(), Stats: flattenKstats()}
+
+	if state.Last != nil && isShrink(state.Last.Stats, now.Stats) {
+		state.Shrinks = append(state.Shrinks, now.Time)
+	}
+
+	// Drop old events
+	cutoff := time.Now().Add(-stateEventMaxAge).Unix()
+	var recent []int64
+
+	for _, t := range state.Shrinks {
+		if t >= cutoff {
+			recent = append(recent, t)
+		}
+	}
+
+	state.Shrinks = recent
+	previousRun, state.Last = state.Last, &now
+
+	saveState(path, &state)
+}
+
+// isShrink decides if the ARC was forced to shrink between two samples: The
+// target size c dropped noticeably, or the ARC was throttled or reclaimed
+// from because of memory pressure
+func isShrink(prev, cur map[string]string) bool {
+
+	get := func(m map[string]string, name string) float64 {
+		v, _ := strconv.ParseFloat(m["arcstats."+name], 64)
+		return v
+	}
+
+	// Counters going backwards means the module was reloaded, and comparing
+	// the samples makes no sense
+	if get(cur, "hits") < get(prev, "hits") {
+		return false
+	}
+
+	if get(cur, "c") < get(prev, "c")*(1-shrinkThreshold) {
+		return true
+	}
+
+	for _, counter := range []string{"memory_throttle_count", "memory_direct_count", "memory_indirect_count"} {
+		if get(cur, counter) > get(prev, counter) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// countEvents returns how many of the events happened within the given
+// duration before now
+func countEvents(events []int64, within time.Duration) int {
+
+	cutoff := time.Now().Add(-within).Unix()
+	n := 0
+
+	for _, t := range events {
+		if t >= cutoff {
+			n++
+		}
+	}
+
+	return n
+}
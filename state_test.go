@@ -0,0 +1,54 @@
+// Test file for state.go
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsShrink(t *testing.T) {
+	base := map[string]string{
+		"arcstats.hits":                  "1000",
+		"arcstats.c":                     "8000000000",
+		"arcstats.memory_throttle_count": "0",
+		"arcstats.memory_direct_count":   "0",
+		"arcstats.memory_indirect_count": "0",
+	}
+
+	with := func(name, value string) map[string]string {
+		m := make(map[string]string)
+		for k, v := range base {
+			m[k] = v
+		}
+		m["arcstats."+name] = value
+		return m
+	}
+
+	var tests = []struct {
+		name string
+		cur  map[string]string
+		want bool
+	}{
+		{"no change", base, false},
+		{"small adaptive drop", with("c", "7990000000"), false},
+		{"c collapsed", with("c", "4000000000"), true},
+		{"direct reclaim", with("memory_direct_count", "1"), true},
+		{"module reloaded", with("hits", "10"), false},
+	}
+
+	for _, test := range tests {
+		got := isShrink(base, test.cur)
+		if got != test.want {
+			t.Errorf("isShrink(%s) = %v (wanted %v)", test.name, got, test.want)
+		}
+	}
+}
+
+func TestCountEvents(t *testing.T) {
+	now := time.Now().Unix()
+	events := []int64{now - 3*24*3600, now - 7200, now - 60}
+
+	if got := countEvents(events, 24*time.Hour); got != 2 {
+		t.Errorf("countEvents() = %d (wanted 2)", got)
+	}
+}
@@ -0,0 +1,215 @@
+// Strict checking of the kstats for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// By default, stats we don't know are ignored and stats that are missing are
+// left out of the report. With -strict, both are warnings and arc_summary
+// exits with exitPartial, so a CI run that validates a new kernel notices
+// when the kstats changed. See arc_summary.go for the license
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// expectedStats are the stats the report and the check formats rely on,
+// keyed by kstat file
+var expectedStats = map[string][]string{
+	"arcstats": {
+		"c", "c_max", "c_min", "hits", "l2_hits", "l2_misses", "l2_size",
+		"memory_throttle_count", "mfu_ghost_hits", "mfu_hits", "mfu_size",
+		"misses", "mru_ghost_hits", "mru_hits", "mru_size", "size",
+	},
+	"vdev_cache_stats": {"delegations", "hits", "misses"},
+	"zil":              {"zil_commit_count", "zil_itx_count"},
+}
+
+// arcstatNames are the names in arcstats of each release of ZFS on Linux and
+// OpenZFS, listing only those that are new since the release before. Stats
+// that were dropped later stay in the list of their release, so older
+// kernels pass -strict as well
+var arcstatNames = map[string][]string{
+	"0.7": {
+		"hits", "misses", "demand_data_hits", "demand_data_misses",
+		"demand_metadata_hits", "demand_metadata_misses", "prefetch_data_hits",
+		"prefetch_data_misses", "prefetch_metadata_hits", "prefetch_metadata_misses",
+		"mru_hits", "mru_ghost_hits", "mfu_hits", "mfu_ghost_hits", "deleted",
+		"mutex_miss", "access_skip", "evict_skip", "evict_not_enough",
+		"evict_l2_cached", "evict_l2_eligible", "evict_l2_ineligible",
+		"evict_l2_skip", "hash_elements", "hash_elements_max", "hash_collisions",
+		"hash_chains", "hash_chain_max", "p", "c", "c_min", "c_max", "size",
+		"compressed_size", "uncompressed_size", "overhead_size", "hdr_size",
+		"data_size", "metadata_size", "dbuf_size", "dnode_size", "bonus_size",
+		"other_size", "anon_size", "anon_evictable_data", "anon_evictable_metadata",
+		"mru_size", "mru_evictable_data", "mru_evictable_metadata", "mru_ghost_size",
+		"mru_ghost_evictable_data", "mru_ghost_evictable_metadata", "mfu_size",
+		"mfu_evictable_data", "mfu_evictable_metadata", "mfu_ghost_size",
+		"mfu_ghost_evictable_data", "mfu_ghost_evictable_metadata", "l2_hits",
+		"l2_misses", "l2_feeds", "l2_rw_clash", "l2_read_bytes", "l2_write_bytes",
+		"l2_writes_sent", "l2_writes_done", "l2_writes_error",
+		"l2_writes_lock_retry", "l2_evict_lock_retry", "l2_evict_reading",
+		"l2_evict_l1cached", "l2_free_on_write", "l2_abort_lowmem", "l2_cksum_bad",
+		"l2_io_error", "l2_size", "l2_asize", "l2_hdr_size", "l2_padding_needed",
+		"memory_throttle_count", "memory_direct_count", "memory_indirect_count",
+		"memory_all_bytes", "memory_free_bytes", "memory_available_bytes",
+		"arc_no_grow", "arc_tempreserve", "arc_loaned_bytes", "arc_prune",
+		"arc_meta_used", "arc_meta_limit", "arc_dnode_limit", "arc_meta_max",
+		"arc_meta_min", "sync_wait_for_async", "demand_hit_predictive_prefetch",
+		"arc_need_free", "arc_sys_free",
+	},
+	"0.8": {
+		"async_upgrade_sync", "demand_hit_prescient_prefetch", "arc_raw_size",
+	},
+	"2.0": {
+		"evict_l2_eligible_mfu", "evict_l2_eligible_mru", "l2_mru_asize",
+		"l2_mfu_asize", "l2_prefetch_asize", "l2_bufc_data_asize",
+		"l2_bufc_metadata_asize", "l2_log_blk_writes", "l2_log_blk_avg_asize",
+		"l2_log_blk_asize", "l2_log_blk_count", "l2_data_to_meta_ratio",
+		"l2_rebuild_success", "l2_rebuild_unsupported", "l2_rebuild_io_errors",
+		"l2_rebuild_dh_errors", "l2_rebuild_cksum_lb_errors", "l2_rebuild_lowmem",
+		"l2_rebuild_size", "l2_rebuild_asize", "l2_rebuild_bufs",
+		"l2_rebuild_bufs_precached", "l2_rebuild_log_blks", "cached_only_in_progress",
+	},
+	"2.1": {
+		"abd_chunk_waste_size",
+	},
+	"2.2": {
+		"iohits", "demand_data_iohits", "demand_metadata_iohits",
+		"prefetch_data_iohits", "prefetch_metadata_iohits", "uncached_hits",
+		"meta", "pd", "pm", "anon_data", "anon_metadata", "mru_data",
+		"mru_metadata", "mru_ghost_data", "mru_ghost_metadata", "mfu_data",
+		"mfu_metadata", "mfu_ghost_data", "mfu_ghost_metadata", "uncached_size",
+		"uncached_data", "uncached_metadata", "uncached_evictable_data",
+		"uncached_evictable_metadata", "predictive_prefetch",
+		"demand_iohit_predictive_prefetch", "prescient_prefetch",
+		"demand_iohit_prescient_prefetch",
+	},
+}
+
+// knownArcstat says if a stat is in the arcstats of any release
+func knownArcstat(name string) bool {
+
+	for _, names := range arcstatNames {
+		if contains(names, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// optionalStats returns the stats of a kstat file the report uses when the
+// kernel has them
+func optionalStats(file string) []string {
+
+	if file == "zstd" {
+		return zstdStatNames()
+	}
+
+	if file != "arcstats" {
+		return nil
+	}
+
+	names := []string{"iohits", "uncached_hits"}
+
+	for _, c := range arcConsumers {
+		names = append(names, c.stat)
+	}
+
+	for _, l := range arcLists {
+		for _, suffix := range []string{"_size", "_data", "_metadata", "_evictable_data", "_evictable_metadata"} {
+			names = append(names, l.name+suffix)
+		}
+	}
+
+	return names
+}
+
+// knownStat says if we know what a stat is: because it is in the arcstats of
+// a release, the report uses it, we have a description for it or it has an
+// alias
+func knownStat(file, name string) bool {
+
+	if file == "arcstats" && knownArcstat(name) {
+		return true
+	}
+
+	if contains(expectedStats[file], name) || contains(optionalStats(file), name) {
+		return true
+	}
+
+	if _, ok := kstatDescs[file+"."+name]; ok {
+		return true
+	}
+
+	_, ok := kstatAliases[file+"."+name]
+	return ok
+}
+
+// strictProblems returns the expected stats that are missing from a kstat
+// file and the stats in it we don't know, both in alphabetical order
+func strictProblems(file string, names []string) ([]string, []string) {
+
+	have := make(map[string]bool)
+	var unknown []string
+
+	for _, n := range names {
+		have[n] = true
+		if !knownStat(file, n) {
+			unknown = append(unknown, n)
+		}
+	}
+
+	var missing []string
+	for _, n := range expectedStats[file] {
+		if !have[n] {
+			missing = append(missing, n)
+		}
+	}
+
+	sort.Strings(missing)
+	sort.Strings(unknown)
+
+	return missing, unknown
+}
+
+// strictWarnings returns the warnings of -strict for the kstats that were
+// read. Files that couldn't be read are already warned about elsewhere
+func strictWarnings() []reportWarning {
+
+	var files []string
+	for f := range kstats {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+
+	var result []reportWarning
+
+	for _, f := range files {
+		var names []string
+		for _, l := range kstats[f] {
+			name, _ := cleanProcLine(l)
+			names = append(names, name)
+		}
+
+		missing, unknown := strictProblems(f, names)
+		if len(missing) > 0 {
+			result = append(result, reportWarning{f, "missing stats: " + strings.Join(missing, ", ")})
+		}
+		if len(unknown) > 0 {
+			result = append(result, reportWarning{f, "unknown stats: " + strings.Join(unknown, ", ")})
+		}
+	}
+
+	return result
+}
+
+// printStrictWarnings writes the warnings of -strict to stderr for the
+// output formats that have no place for them
+func printStrictWarnings(warnings []reportWarning) {
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", w.section, w.reason)
+	}
+}
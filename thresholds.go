@@ -0,0 +1,133 @@
+// Threshold colors for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// Rows of the text report can be colored when a value crosses a threshold,
+// so problems catch the eye. A threshold names the label of the row, an
+// expression over the stats (see expr.go) and the limit it must stay above
+// or below. The "thresholds" list of the configuration file replaces the
+// defaults. Colors are used when the report goes to a terminal, unless
+// -color says otherwise or NO_COLOR is set. See arc_summary.go for the
+// license
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// threshold colors the row with Label if Expr is above Above or below Below
+type threshold struct {
+	Label string   `json:"label"`
+	Expr  string   `json:"expr"`
+	Above *float64 `json:"above"`
+	Below *float64 `json:"below"`
+	Color string   `json:"color"`
+}
+
+// thresholdColors are the colors known to thresholds
+var thresholdColors = map[string]string{
+	"green":  "\033[32m",
+	"red":    "\033[31m",
+	"yellow": "\033[33m",
+}
+
+// limit returns a pointer to a threshold limit
+func limit(f float64) *float64 {
+	return &f
+}
+
+// defaultThresholds are used if the configuration file has none
+var defaultThresholds = []threshold{
+	{Label: "Cache hits:", Expr: "100 * hits / (hits + misses)", Below: limit(80), Color: "red"},
+	{Label: "ARC size:", Expr: "100 * size / c_max", Above: limit(98), Color: "yellow"},
+	{Label: "Memory throttle count:", Expr: "memory_throttle_count", Above: limit(0), Color: "red"},
+}
+
+// colorEnabled is set if the report is printed with colors
+var colorEnabled bool
+
+// check returns an error if the threshold can't be used
+func (t threshold) check() error {
+
+	if t.Label == "" {
+		return errors.New("threshold without label")
+	}
+
+	if (t.Above == nil) == (t.Below == nil) {
+		return fmt.Errorf("threshold '%s' needs either above or below", t.Label)
+	}
+
+	if _, ok := thresholdColors[t.Color]; !ok {
+		return fmt.Errorf("threshold '%s' has unknown color '%s'", t.Label, t.Color)
+	}
+
+	dummy := func(string) (float64, error) { return 1, nil }
+	if _, err := evalExpr(t.Expr, dummy); err != nil {
+		return fmt.Errorf("threshold '%s' has bad expression: %v", t.Label, err)
+	}
+
+	return nil
+}
+
+// crossed returns true if the value is past the threshold
+func (t threshold) crossed(value float64) bool {
+	if t.Above != nil {
+		return value > *t.Above
+	}
+	return value < *t.Below
+}
+
+// activeThresholds returns the thresholds from the configuration file, or
+// the defaults if there are none
+func activeThresholds() []threshold {
+	if cfg.Thresholds != nil {
+		return cfg.Thresholds
+	}
+	return defaultThresholds
+}
+
+// useColor decides if the report is printed with colors for -color "auto",
+// "always" or "never"
+func useColor(mode string) (bool, error) {
+
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		if *OptPlain || *OptOutput != "text" || os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		fi, err := os.Stdout.Stat()
+		return err == nil && fi.Mode()&os.ModeCharDevice != 0, nil
+	}
+
+	return false, fmt.Errorf("bad -color '%s' (wanted auto, always or never)", mode)
+}
+
+// colorRow returns the line of a row in the color of the first threshold it
+// crossed. Expressions that can't be evaluated (eg a division by zero on an
+// idle system) don't color anything
+func colorRow(r layoutRow, line string) string {
+
+	if !colorEnabled {
+		return line
+	}
+
+	label := strings.TrimSpace(r.label)
+
+	for _, t := range activeThresholds() {
+		if t.Label != label {
+			continue
+		}
+		value, err := evalExpr(t.Expr, lookupStat)
+		if err == nil && t.crossed(value) {
+			return thresholdColors[t.Color] + line + ansiReset
+		}
+	}
+
+	return line
+}
@@ -0,0 +1,121 @@
+// Test file for thresholds.go
+package main
+
+import (
+	"testing"
+)
+
+func TestThresholdCheck(t *testing.T) {
+
+	var tests = []struct {
+		t    threshold
+		fail bool
+	}{
+		{threshold{Label: "Cache hits:", Expr: "hits", Below: limit(80), Color: "red"}, false},
+		{threshold{Label: "", Expr: "hits", Below: limit(80), Color: "red"}, true},
+		{threshold{Label: "Cache hits:", Expr: "hits", Color: "red"}, true},
+		{threshold{Label: "Cache hits:", Expr: "hits", Above: limit(1), Below: limit(80), Color: "red"}, true},
+		{threshold{Label: "Cache hits:", Expr: "hits", Below: limit(80), Color: "pink"}, true},
+		{threshold{Label: "Cache hits:", Expr: "hits +", Below: limit(80), Color: "red"}, true},
+	}
+
+	for _, test := range tests {
+		if err := test.t.check(); (err != nil) != test.fail {
+			t.Errorf("check(%+v) = %v (wanted failure %v)", test.t, err, test.fail)
+		}
+	}
+
+	for _, d := range defaultThresholds {
+		if err := d.check(); err != nil {
+			t.Errorf("default threshold: %v", err)
+		}
+	}
+}
+
+func TestThresholdCrossed(t *testing.T) {
+
+	var tests = []struct {
+		t      threshold
+		value  float64
+		wanted bool
+	}{
+		{threshold{Below: limit(80)}, 79.9, true},
+		{threshold{Below: limit(80)}, 80, false},
+		{threshold{Above: limit(0)}, 0, false},
+		{threshold{Above: limit(0)}, 1, true},
+	}
+
+	for _, test := range tests {
+		if got := test.t.crossed(test.value); got != test.wanted {
+			t.Errorf("crossed(%v) = %v (wanted \"%v\")", test.value, got, test.wanted)
+		}
+	}
+}
+
+func TestUseColor(t *testing.T) {
+
+	var tests = []struct {
+		mode   string
+		wanted bool
+		fail   bool
+	}{
+		{"always", true, false},
+		{"never", false, false},
+		{"auto", false, false}, // stdout isn't a terminal under go test
+		{"rainbow", false, true},
+	}
+
+	for _, test := range tests {
+		got, err := useColor(test.mode)
+		if got != test.wanted || (err != nil) != test.fail {
+			t.Errorf("useColor(%s) = %v, %v (wanted \"%v\")", test.mode, got, err, test.wanted)
+		}
+	}
+}
+
+func TestColorRow(t *testing.T) {
+
+	savedStats, savedColor := kstats, colorEnabled
+	defer func() { kstats, colorEnabled = savedStats, savedColor }()
+
+	red, yellow := thresholdColors["red"], thresholdColors["yellow"]
+
+	var tests = []struct {
+		label  string
+		stats  []string
+		wanted string
+	}{
+		{"Cache hits:", []string{"hits 4 79", "misses 4 21"}, red},
+		{"Cache hits:", []string{"hits 4 80", "misses 4 20"}, ""},
+		{"Cache hits:", []string{"hits 4 81", "misses 4 19"}, ""},
+		{"Cache hits:", []string{"hits 4 0", "misses 4 0"}, ""},
+		{"ARC size:", []string{"size 4 97", "c_max 4 100"}, ""},
+		{"ARC size:", []string{"size 4 98", "c_max 4 100"}, ""},
+		{"ARC size:", []string{"size 4 99", "c_max 4 100"}, yellow},
+		{"Memory throttle count:", []string{"memory_throttle_count 4 0"}, ""},
+		{"Memory throttle count:", []string{"memory_throttle_count 4 1"}, red},
+		{"Other:", []string{"hits 4 0", "misses 4 100"}, ""},
+	}
+
+	colorEnabled = true
+
+	for _, test := range tests {
+		kstats = map[string][]string{"arcstats": test.stats}
+
+		wanted := "line"
+		if test.wanted != "" {
+			wanted = test.wanted + "line" + ansiReset
+		}
+
+		if got := colorRow(layoutRow{label: test.label}, "line"); got != wanted {
+			t.Errorf("colorRow(%s, %v) = %q (wanted %q)", test.label, test.stats, got, wanted)
+		}
+	}
+
+	colorEnabled = false
+	kstats = map[string][]string{"arcstats": {"hits 4 0", "misses 4 100"}}
+
+	if got := colorRow(layoutRow{label: "Cache hits:"}, "line"); got != "line" {
+		t.Errorf("colorRow() without colors = %q (wanted \"line\")", got)
+	}
+}
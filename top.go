@@ -0,0 +1,142 @@
+// Top mode for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// "arc_summary top" samples all kstats at a fixed interval and shows the
+// stats that changed the fastest, across every section, like top(1) does
+// for processes. When it isn't clear yet which counter matters, this is the
+// place to start looking. See arc_summary.go for the license
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// statRate is how fast a stat changed between two samples
+type statRate struct {
+	file  string
+	stat  string
+	value uint64
+	rate  float64 // per second
+}
+
+// topRates returns the n stats that changed the fastest, up or down, between
+// two samples dt seconds apart. Stats that didn't change are left out
+func topRates(prev, cur map[string]map[string]uint64, dt float64, n int) []statRate {
+
+	var result []statRate
+
+	if dt <= 0 {
+		return nil
+	}
+
+	for file, m := range cur {
+		for stat, v := range m {
+			old, ok := prev[file][stat]
+			if !ok || old == v {
+				continue
+			}
+			result = append(result, statRate{file, stat, v, float64(int64(v-old)) / dt})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		ri, rj := math.Abs(result[i].rate), math.Abs(result[j].rate)
+		if ri != rj {
+			return ri > rj
+		}
+		if result[i].file != result[j].file {
+			return result[i].file < result[j].file
+		}
+		return result[i].stat < result[j].stat
+	})
+
+	if len(result) > n {
+		result = result[:n]
+	}
+
+	return result
+}
+
+// fRate formats a rate, as bytes if the stat is in bytes
+func fRate(r statRate) string {
+
+	if _, unit := kstatType(r.file, r.stat); unit == "bytes" {
+		sign := ""
+		if r.rate < 0 {
+			sign = "-"
+		}
+		return sign + fBytes(strconv.FormatUint(uint64(math.Abs(r.rate)), 10)) + "/s"
+	}
+
+	return fmt.Sprintf("%+.1f/s", r.rate)
+}
+
+// printTop prints the table of the fastest changing stats
+func printTop(w io.Writer, rates []statRate, interval time.Duration) {
+
+	fmt.Fprintf(w, "Fastest changing stats over %v:\n\n", interval)
+
+	if len(rates) == 0 {
+		fmt.Fprintln(w, indent+"(none)")
+		return
+	}
+
+	fmt.Fprintf(w, indent+"%-45s%18s%20s\n", "Stat", "Change", "Value")
+
+	for _, r := range rates {
+		fmt.Fprintf(w, indent+"%-45s%18s%20d\n", r.file+"."+r.stat, fRate(r), r.value)
+	}
+}
+
+// topSample reads the kstats and returns them by file and stat
+func topSample() map[string]map[string]uint64 {
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	sampleSeq++
+	getKstats(ctx, kstats)
+
+	return newStatsSet().Kstats
+}
+
+// cmdTop handles the "top" subcommand. It does not return
+func cmdTop(args []string) {
+
+	fs := flag.NewFlagSet("top", flag.ExitOnError)
+	n := fs.Int("n", 20, "Number of stats to show")
+	interval := fs.Duration("interval", 2*time.Second, "Time between samples")
+	fs.Parse(args)
+
+	if *n < 1 {
+		log.Fatal("-n must be at least 1")
+	}
+
+	if *interval <= 0 {
+		log.Fatal("-interval must be positive")
+	}
+
+	prev, prevTime := topSample(), time.Now()
+
+	for {
+		time.Sleep(*interval)
+
+		cur, now := topSample(), time.Now()
+		rates := topRates(prev, cur, now.Sub(prevTime).Seconds(), *n)
+
+		fmt.Fprint(reportOut, clearScreen)
+		fmt.Fprintln(reportOut, sampleLine(sampleSeq, now))
+		fmt.Fprintln(reportOut)
+		printTop(os.Stdout, rates, *interval)
+
+		prev, prevTime = cur, now
+	}
+}
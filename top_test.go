@@ -0,0 +1,56 @@
+// Test file for top.go
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTopRates(t *testing.T) {
+
+	prev := map[string]map[string]uint64{
+		"arcstats":    {"hits": 100, "misses": 10, "size": 5000, "c_max": 9000},
+		"zfetchstats": {"hits": 0},
+	}
+	cur := map[string]map[string]uint64{
+		"arcstats":    {"hits": 300, "misses": 30, "size": 1000, "c_max": 9000, "new": 5},
+		"zfetchstats": {"hits": 20},
+	}
+
+	var names []string
+	for _, r := range topRates(prev, cur, 2, 3) {
+		names = append(names, r.file+"."+r.stat)
+	}
+
+	// Shrinking counts as much as growing, and ties go by name
+	wanted := []string{"arcstats.size", "arcstats.hits", "arcstats.misses"}
+	if !reflect.DeepEqual(names, wanted) {
+		t.Errorf("topRates() = %v (wanted \"%v\")", names, wanted)
+	}
+
+	if r := topRates(prev, cur, 2, 1)[0]; r.rate != -2000 || r.value != 1000 {
+		t.Errorf("topRates()[0] = %+v (wanted rate -2000, value 1000)", r)
+	}
+
+	if r := topRates(prev, cur, 0, 3); r != nil {
+		t.Errorf("topRates(dt 0) = %v (wanted nil)", r)
+	}
+}
+
+func TestFRate(t *testing.T) {
+
+	var tests = []struct {
+		r      statRate
+		wanted string
+	}{
+		{statRate{file: "arcstats", stat: "hits", rate: 12.34}, "+12.3/s"},
+		{statRate{file: "arcstats", stat: "misses", rate: -3}, "-3.0/s"},
+		{statRate{file: "arcstats", stat: "size", rate: -2048}, "-2.0 KiB/s"},
+	}
+
+	for _, test := range tests {
+		if got := fRate(test.r); got != test.wanted {
+			t.Errorf("fRate(%s) = %s (wanted \"%s\")", test.r.stat, got, test.wanted)
+		}
+	}
+}
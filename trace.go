@@ -0,0 +1,128 @@
+// Latency tracing for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// "arc_summary trace" measures how long arc_read() and zil_commit() actually
+// take over a sampling window and prints histograms, split into ARC hits and
+// misses where the module has the tracepoints for it. The probes are run by
+// bpftrace, which has to be installed, so we don't need an eBPF library of
+// our own. Linux only, and needs root. See arc_summary.go for the license
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// traceEventDirs are where the kernel lists its tracepoints, depending on
+// whether tracefs is mounted on its own or under debugfs
+var traceEventDirs = []string{
+	"/sys/kernel/tracing/events/zfs",
+	"/sys/kernel/debug/tracing/events/zfs",
+}
+
+// haveArcTracepoints tests if the zfs module was built with the arc__hit and
+// arc__miss tracepoints
+func haveArcTracepoints() bool {
+
+	for _, d := range traceEventDirs {
+		if _, err := os.Stat(d + "/zfs_arc__hit"); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// traceScript returns the bpftrace program that collects the histograms for
+// the given number of seconds. Without the tracepoints, all ARC reads end up
+// in one histogram. Latencies are in microseconds
+func traceScript(seconds int, hitMiss bool) string {
+
+	var b strings.Builder
+
+	b.WriteString("kprobe:arc_read { @start[tid] = nsecs; }\n")
+
+	if hitMiss {
+		b.WriteString("tracepoint:zfs:zfs_arc__hit /@start[tid]/ { @hit[tid] = 1; }\n")
+		b.WriteString("tracepoint:zfs:zfs_arc__miss /@start[tid]/ { @miss[tid] = 1; }\n")
+	}
+
+	b.WriteString("kretprobe:arc_read /@start[tid]/ {\n")
+	b.WriteString("\t$us = (nsecs - @start[tid]) / 1000;\n")
+
+	if hitMiss {
+		b.WriteString("\tif (@hit[tid]) { @arc_hit_us = hist($us); }\n")
+		b.WriteString("\telse if (@miss[tid]) { @arc_miss_us = hist($us); }\n")
+		b.WriteString("\telse { @arc_read_us = hist($us); }\n")
+		b.WriteString("\tdelete(@hit[tid]); delete(@miss[tid]);\n")
+	} else {
+		b.WriteString("\t@arc_read_us = hist($us);\n")
+	}
+
+	b.WriteString("\tdelete(@start[tid]);\n}\n")
+
+	b.WriteString("kprobe:zil_commit { @zstart[tid] = nsecs; }\n")
+	b.WriteString("kretprobe:zil_commit /@zstart[tid]/ {\n")
+	b.WriteString("\t@zil_commit_us = hist((nsecs - @zstart[tid]) / 1000);\n")
+	b.WriteString("\tdelete(@zstart[tid]);\n}\n")
+
+	fmt.Fprintf(&b, "interval:s:%d { exit(); }\n", seconds)
+
+	b.WriteString("END { clear(@start); clear(@zstart);")
+	if hitMiss {
+		b.WriteString(" clear(@hit); clear(@miss);")
+	}
+	b.WriteString(" }\n")
+
+	return b.String()
+}
+
+// cmdTrace handles the "trace" subcommand
+func cmdTrace(args []string) {
+
+	fs := flag.NewFlagSet("trace", flag.ExitOnError)
+	window := fs.Duration("d", 10*time.Second, "Length of the sampling window")
+	show := fs.Bool("script", false, "Print the bpftrace program instead of running it")
+	fs.Parse(args)
+
+	seconds := int(window.Seconds())
+	if seconds < 1 {
+		log.Fatal("The sampling window must be at least one second")
+	}
+
+	hitMiss := haveArcTracepoints()
+	script := traceScript(seconds, hitMiss)
+
+	if *show {
+		fmt.Fprint(reportOut, script)
+		return
+	}
+
+	if runtime.GOOS != "linux" {
+		log.Fatal("Tracing is only supported on Linux")
+	}
+
+	if _, err := exec.LookPath("bpftrace"); err != nil {
+		log.Fatal("Tracing needs bpftrace, which wasn't found: ", err)
+	}
+
+	fmt.Fprintf(reportOut, "Tracing arc_read() and zil_commit() for %d seconds (latencies in microseconds) ...\n", seconds)
+	if !hitMiss {
+		fmt.Fprintln(reportOut, "The zfs module has no arc__hit/arc__miss tracepoints, so hits and misses are not told apart")
+	}
+	fmt.Fprintln(reportOut, "Misses of asynchronous reads return before the I/O is done and look like hits")
+
+	cmd := exec.Command("bpftrace", "-e", script)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		log.Fatal("bpftrace failed: ", err)
+	}
+}
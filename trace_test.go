@@ -0,0 +1,34 @@
+// Test file for trace.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTraceScript(t *testing.T) {
+
+	var tests = []struct {
+		hitMiss bool
+		wanted  []string
+		unwant  []string
+	}{
+		{true, []string{"@arc_hit_us", "@arc_miss_us", "zfs_arc__hit", "interval:s:5 "}, nil},
+		{false, []string{"@arc_read_us", "@zil_commit_us", "interval:s:5 "}, []string{"zfs_arc__hit", "@miss"}},
+	}
+
+	for _, test := range tests {
+		s := traceScript(5, test.hitMiss)
+
+		for _, w := range test.wanted {
+			if !strings.Contains(s, w) {
+				t.Errorf("traceScript(5, %v) is missing \"%v\"", test.hitMiss, w)
+			}
+		}
+		for _, u := range test.unwant {
+			if strings.Contains(s, u) {
+				t.Errorf("traceScript(5, %v) contains \"%v\"", test.hitMiss, u)
+			}
+		}
+	}
+}
@@ -0,0 +1,261 @@
+// Tunable metadata for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// The kernel only tells us the current value of a tunable. To say where that
+// value came from, we compare it with what was set at boot (modprobe.d and
+// the kernel command line) and with the known defaults. See arc_summary.go
+// for the license
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const cmdlinePath = "/proc/cmdline"
+
+// tunableDefaults are the default values of common tunables in OpenZFS 2.x.
+// Tunables computed at load time (eg from the amount of RAM) are not listed
+var tunableDefaults = map[string]string{
+	"l2arc_feed_secs":                 "1",
+	"l2arc_noprefetch":                "1",
+	"l2arc_write_boost":               "8388608",
+	"l2arc_write_max":                 "8388608",
+	"spa_slop_shift":                  "5",
+	"zfs_abd_scatter_enabled":         "1",
+	"zfs_arc_average_blocksize":       "8192",
+	"zfs_arc_dnode_limit":             "0",
+	"zfs_arc_dnode_limit_percent":     "10",
+	"zfs_arc_grow_retry":              "0",
+	"zfs_arc_lotsfree_percent":        "10",
+	"zfs_arc_max":                     "0",
+	"zfs_arc_min":                     "0",
+	"zfs_arc_shrink_shift":            "0",
+	"zfs_arc_sys_free":                "0",
+	"zfs_compressed_arc_enabled":      "1",
+	"zfs_dedup_prefetch":              "0",
+	"zfs_dirty_data_max_percent":      "10",
+	"zfs_dirty_data_sync_percent":     "20",
+	"zfs_nocacheflush":                "0",
+	"zfs_prefetch_disable":            "0",
+	"zfs_read_history":                "0",
+	"zfs_txg_history":                 "100",
+	"zfs_txg_timeout":                 "5",
+	"zfs_vdev_async_read_max_active":  "3",
+	"zfs_vdev_async_read_min_active":  "1",
+	"zfs_vdev_async_write_max_active": "10",
+	"zfs_vdev_async_write_min_active": "2",
+	"zfs_vdev_max_active":             "1000",
+	"zfs_vdev_scrub_max_active":       "3",
+	"zfs_vdev_scrub_min_active":       "1",
+	"zfs_vdev_sync_read_max_active":   "10",
+	"zfs_vdev_sync_read_min_active":   "10",
+	"zfs_vdev_sync_write_max_active":  "10",
+	"zfs_vdev_sync_write_min_active":  "10",
+	"zil_slog_bulk":                   "786432",
+}
+
+// tunableUnits are the units of tunables whose names don't give them away
+var tunableUnits = map[string]string{
+	"l2arc_write_boost":         "bytes",
+	"l2arc_write_max":           "bytes",
+	"zfs_arc_average_blocksize": "bytes",
+	"zfs_arc_dnode_limit":       "bytes",
+	"zfs_arc_max":               "bytes",
+	"zfs_arc_meta_limit":        "bytes",
+	"zfs_arc_meta_min":          "bytes",
+	"zfs_arc_min":               "bytes",
+	"zfs_arc_sys_free":          "bytes",
+	"zfs_dirty_data_max":        "bytes",
+	"zfs_dirty_data_max_max":    "bytes",
+	"zfs_txg_timeout":           "seconds",
+	"zil_slog_bulk":             "bytes",
+}
+
+// tunableUnit returns the unit of a tunable from its name and the type modinfo
+// gives for it: "bool", "bytes", "percent", "seconds" or "" if we don't know
+func tunableUnit(name, typ string) string {
+
+	if u, ok := tunableUnits[name]; ok {
+		return u
+	}
+
+	switch {
+	case typ == "bool" || typ == "invbool":
+		return "bool"
+	case strings.HasSuffix(name, "_percent") || strings.HasSuffix(name, "_pct"):
+		return "percent"
+	case strings.HasSuffix(name, "_secs"):
+		return "seconds"
+	case strings.HasSuffix(name, "_disable") || strings.HasSuffix(name, "_enabled") ||
+		strings.HasPrefix(name, "zfs_no") || strings.HasPrefix(name, "l2arc_no"):
+		return "bool"
+	}
+
+	return ""
+}
+
+// fTunable formats the value of a tunable by its unit. 0 often means "pick a
+// value for me", so it isn't turned into 0 bytes
+func fTunable(name, typ, value string) string {
+
+	switch tunableUnit(name, typ) {
+	case "bool":
+		switch value {
+		case "0", "n", "N":
+			return "off"
+		case "1", "y", "Y":
+			return "on"
+		}
+	case "bytes":
+		if _, err := strconv.ParseUint(value, 10, 64); err == nil && value != "0" {
+			return fBytes(value)
+		}
+	case "percent":
+		return value + " %"
+	case "seconds":
+		return value + " s"
+	}
+
+	return value
+}
+
+// parseCmdline returns the zfs module parameters set on the kernel command
+// line as "zfs.name=value"
+func parseCmdline(cmdline string) map[string]string {
+
+	m := make(map[string]string)
+
+	for _, f := range strings.Fields(cmdline) {
+		if !strings.HasPrefix(f, "zfs.") {
+			continue
+		}
+
+		kv := strings.SplitN(strings.TrimPrefix(f, "zfs."), "=", 2)
+		if len(kv) == 2 {
+			m[kv[0]] = kv[1]
+		}
+	}
+
+	return m
+}
+
+// getBootTunables returns the tunables set at boot. The kernel command line
+// overrides modprobe.d
+func getBootTunables(ctx context.Context) map[string]string {
+
+	m := make(map[string]string)
+
+	if opts, err := readModprobeDir(ctx, modprobePath); err == nil {
+		for _, o := range opts {
+			m[o.name] = o.value
+		}
+	}
+
+	if data, err := readFile(ctx, cmdlinePath); err == nil {
+		for k, v := range parseCmdline(string(data)) {
+			m[k] = v
+		}
+	}
+
+	return m
+}
+
+// tunableOrigin describes where the live value of a tunable comes from. It
+// returns an empty string if we can't tell
+func tunableOrigin(name, live string, boot map[string]string) string {
+
+	if b, ok := boot[name]; ok {
+		if b == live {
+			return "set at boot"
+		}
+		return fmt.Sprintf("changed at runtime (boot: %s)", b)
+	}
+
+	if d, ok := tunableDefaults[name]; ok {
+		if d == live {
+			return "default"
+		}
+		return fmt.Sprintf("changed at runtime (default: %s)", d)
+	}
+
+	return ""
+}
+
+// frozenTunables returns the names of the tunables that have to go into a
+// modprobe.d file to recreate the live settings: Everything that differs from
+// its known default, or, if we don't know the default, was set at boot.
+// Other tunables are left out, since we can't tell if they were changed
+func frozenTunables(live, boot map[string]string) []string {
+
+	var names []string
+
+	for n, v := range live {
+		_, atBoot := boot[n]
+		d, hasDefault := tunableDefaults[n]
+
+		if (hasDefault && d != v) || (!hasDefault && atBoot) {
+			names = append(names, n)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// cmdTunables handles the "tunables" subcommand. "tunables freeze" prints a
+// modprobe.d snippet that makes the live settings permanent, "tunables edit"
+// starts the interactive editor and "tunables rollback" undoes the last
+// change made with it
+func cmdTunables(args []string) {
+
+	usage := "Usage: arc_summary tunables freeze|edit|rollback"
+
+	if len(args) != 1 {
+		log.Fatal(usage)
+	}
+
+	switch args[0] {
+	case "edit":
+		editTunables(os.Stdin, os.Stdout)
+	case "freeze":
+		freezeTunables()
+	case "rollback":
+		rollbackTunable()
+	default:
+		log.Fatal(usage)
+	}
+}
+
+// freezeTunables prints a modprobe.d snippet with the live settings. A
+// snippet without the tunables that couldn't be read would look fine but
+// lose their settings when installed, so we refuse to print one
+func freezeTunables() {
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	getTunables(ctx, tunables)
+	if tunablesErr != nil {
+		log.Fatal("Couldn't read ", tunablesPath, ": ", tunablesErr)
+	}
+	if len(unreadableTunables) > 0 {
+		log.Fatal("Couldn't read the tunables ", strings.Join(unreadableTunables, ", "), " in ", tunablesPath)
+	}
+
+	boot := getBootTunables(ctx)
+
+	fmt.Fprintf(reportOut, "# Generated by arc_summary on %s from the live ZFS parameters\n",
+		time.Now().Format(time.RFC1123))
+	fmt.Fprintf(reportOut, "# Install as %s/zfs.conf\n", modprobePath)
+
+	for _, n := range frozenTunables(tunables, boot) {
+		fmt.Fprintf(reportOut, "options zfs %s=%s\n", n, tunables[n])
+	}
+}
@@ -0,0 +1,84 @@
+// Test file for tunables.go
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCmdline(t *testing.T) {
+	got := parseCmdline("BOOT_IMAGE=/vmlinuz root=ZFS=rpool/ROOT zfs.zfs_arc_max=1073741824 zfs.bad quiet")
+	want := map[string]string{"zfs_arc_max": "1073741824"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseCmdline() = %v (wanted %v)", got, want)
+	}
+}
+
+func TestTunableOrigin(t *testing.T) {
+	boot := map[string]string{"zfs_arc_max": "1073741824"}
+
+	var tests = []struct {
+		name string
+		live string
+		want string
+	}{
+		{"zfs_arc_max", "1073741824", "set at boot"},
+		{"zfs_arc_max", "2147483648", "changed at runtime (boot: 1073741824)"},
+		{"zfs_txg_timeout", "5", "default"},
+		{"zfs_txg_timeout", "10", "changed at runtime (default: 5)"},
+		{"zfs_unknown_tunable", "1", ""},
+	}
+
+	for _, test := range tests {
+		got := tunableOrigin(test.name, test.live, boot)
+		if got != test.want {
+			t.Errorf("tunableOrigin(%s, %s) = \"%v\" (wanted \"%v\")", test.name, test.live, got, test.want)
+		}
+	}
+}
+
+func TestFrozenTunables(t *testing.T) {
+	live := map[string]string{
+		"zfs_arc_max":          "1073741824",
+		"zfs_txg_timeout":      "10",
+		"zfs_prefetch_disable": "0",
+		"zfs_arc_min":          "0",
+		"zfs_unknown_tunable":  "7",
+		"zfs_boot_tunable":     "3",
+	}
+	boot := map[string]string{"zfs_arc_max": "1073741824", "zfs_arc_min": "1", "zfs_boot_tunable": "3"}
+
+	got := frozenTunables(live, boot)
+	want := []string{"zfs_arc_max", "zfs_boot_tunable", "zfs_txg_timeout"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("frozenTunables() = %v (wanted %v)", got, want)
+	}
+}
+
+func TestFTunable(t *testing.T) {
+
+	var tests = []struct {
+		name, typ, value string
+		want             string
+	}{
+		{"zfs_arc_max", "ulong", "0", "0"},
+		{"zfs_arc_max", "ulong", "8589934592", fBytes("8589934592")},
+		{"zfs_prefetch_disable", "int", "1", "on"},
+		{"zfs_compressed_arc_enabled", "int", "0", "off"},
+		{"zfs_some_flag", "bool", "Y", "on"},
+		{"zfs_prefetch_disable", "int", "2", "2"},
+		{"zfs_dirty_data_max_percent", "uint", "10", "10 %"},
+		{"zfs_txg_timeout", "int", "5", "5 s"},
+		{"l2arc_feed_secs", "ulong", "1", "1 s"},
+		{"zfs_vdev_async_write_max_active", "int", "10", "10"},
+		{"zfs_vdev_raidz_impl", "charp", "fastest", "fastest"},
+	}
+
+	for _, test := range tests {
+		if got := fTunable(test.name, test.typ, test.value); got != test.want {
+			t.Errorf("fTunable(%s, %s) = %v (wanted \"%v\")", test.name, test.value, got, test.want)
+		}
+	}
+}
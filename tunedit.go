@@ -0,0 +1,166 @@
+// Interactive tunable editor for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// "arc_summary tunables edit" lists tunables with their descriptions and
+// values and lets the user change them in place, with confirmation. This is
+// a line-oriented editor, not a full screen interface: it only reads lines
+// from its input, so it works on serial consoles and without a terminal
+// library, and can be fed from a script. See arc_summary.go for the license
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// writeTunable sets a tunable of the running module
+func writeTunable(name, value string) error {
+
+	path := filepath.Join(tunablesPath, name)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("no such tunable %s", name)
+	}
+
+	if info.Mode().Perm()&0222 == 0 {
+		return fmt.Errorf("%s is read-only and can only be set when the module is loaded", name)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(value), 0644); err != nil {
+		if os.IsPermission(err) {
+			return fmt.Errorf("permission denied writing %s (are you root?)", name)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// editTunables runs the interactive editor, reading commands from in and
+// printing prompts and results to out. It returns at an empty line or the
+// end of the input
+func editTunables(in io.Reader, out io.Writer) {
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	getTunables(ctx, tunables)
+
+	descs := make(map[string]string)
+	types := make(map[string]string)
+
+	if mi, err := runModinfo(ctx); err == nil {
+		parseModinfo(mi, descs, types)
+	} else {
+		fmt.Fprintln(out, "Couldn't get descriptions from modinfo:", err)
+	}
+
+	if os.Geteuid() != 0 {
+		fmt.Fprintln(out, "Not running as root, changes will fail")
+	}
+
+	boot := getBootTunables(ctx)
+	input := bufio.NewScanner(in)
+
+	prompt := func(p string) (string, bool) {
+		fmt.Fprint(out, p)
+		if !input.Scan() {
+			return "", false
+		}
+		return strings.TrimSpace(input.Text()), true
+	}
+
+	for {
+		cmd, ok := prompt("\nTunable ('?' or '? filter' to list, empty to quit): ")
+		if !ok || cmd == "" {
+			return
+		}
+
+		if strings.HasPrefix(cmd, "?") {
+			listTunables(out, strings.TrimSpace(cmd[1:]), boot)
+			continue
+		}
+
+		current, ok := tunables[cmd]
+		if !ok {
+			fmt.Fprintf(out, "No such tunable '%s'\n", cmd)
+			continue
+		}
+
+		if d, ok := descs[cmd]; ok {
+			fmt.Fprintf(out, "# %s\n", d)
+		}
+
+		fmt.Fprintf(out, "%s = %s", cmd, current)
+		if d, ok := tunableDefaults[cmd]; ok {
+			fmt.Fprintf(out, " (default %s)", d)
+		}
+		fmt.Fprintln(out)
+
+		value, ok := prompt("New value (empty to keep): ")
+		if !ok {
+			return
+		}
+
+		if value == "" || value == current {
+			continue
+		}
+
+		if err := checkTypeRange(types[cmd], value); err != nil {
+			fmt.Fprintf(out, "Not changed: %v\n", err)
+			continue
+		}
+
+		// Odd values are allowed, the user may know better
+		for _, p := range checkNewTunable(tunables, cmd, value) {
+			fmt.Fprintf(out, "Warning: %s\n", p)
+		}
+
+		answer, ok := prompt(fmt.Sprintf("Set %s from %s to %s? [y/N] ", cmd, current, value))
+		if !ok {
+			return
+		}
+
+		if answer != "y" && answer != "Y" {
+			fmt.Fprintln(out, "Not changed")
+			continue
+		}
+
+		if err := setTunable(cmd, current, value, 0); err != nil {
+			fmt.Fprintf(out, "Not changed: %v\n", err)
+			continue
+		}
+
+		if *OptDryRun {
+			continue
+		}
+
+		tunables[cmd] = value
+		fmt.Fprintf(out, "%s set to %s\n", cmd, value)
+	}
+}
+
+// listTunables prints all tunables whose name contains filter with their
+// value and where it comes from
+func listTunables(out io.Writer, filter string, boot map[string]string) {
+
+	var names []string
+
+	for n := range tunables {
+		if strings.Contains(n, filter) {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+
+	for _, n := range names {
+		fmt.Fprintf(out, "\t%-50s%-20s%s\n", n, tunables[n], tunableOrigin(n, tunables[n], boot))
+	}
+}
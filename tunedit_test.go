@@ -0,0 +1,63 @@
+// Test file for tunedit.go
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEditTunables(t *testing.T) {
+
+	bundleFiles = map[string][]byte{
+		bundleKey(filepath.Join(tunablesPath, "zfs_arc_max")):          []byte("0\n"),
+		bundleKey(filepath.Join(tunablesPath, "zfs_arc_min")):          []byte("0\n"),
+		bundleKey(filepath.Join(tunablesPath, "zfs_prefetch_disable")): []byte("0\n"),
+		commandKey(modinfoCommand(), "zfs", "-0"): []byte("parm:           zfs_arc_max:Max size of ARC in bytes (ulong)\000" +
+			"parm:           zfs_prefetch_disable:Disable all ZFS prefetching (int)\000"),
+	}
+	saved := tunables
+	defer func() { bundleFiles, tunables = nil, saved }()
+
+	var tests = []struct {
+		input    string
+		wanted   []string
+		unwanted []string
+	}{
+		{"", nil, []string{"New value"}},
+		{"?\n", []string{"zfs_arc_max", "zfs_arc_min", "zfs_prefetch_disable"}, nil},
+		{"? prefetch\n", []string{"zfs_prefetch_disable"}, []string{"zfs_arc_max"}},
+		{"zfs_arc_mxa\n", []string{"No such tunable 'zfs_arc_mxa'"}, []string{"New value"}},
+		{"zfs_arc_max\n\n", []string{"# Max size of ARC in bytes\nzfs_arc_max = 0 (default 0)", "New value (empty to keep): "}, []string{"Set zfs_arc_max"}},
+		{"zfs_arc_max\n0\n", nil, []string{"Set zfs_arc_max"}},
+		{"zfs_arc_max\nlots\n", []string{"Not changed: 'lots' is not a valid ulong"}, []string{"Set zfs_arc_max"}},
+		{"zfs_prefetch_disable\n2\nn\n", []string{"Warning: 2 is outside the sensible range 0 to 1",
+			"Set zfs_prefetch_disable from 0 to 2? [y/N] ", "Not changed\n"}, nil},
+		{"zfs_arc_max\n8589934592\ny\n", []string{"Not changed: can't change tunables when reading from a bundle"}, nil},
+		{"zfs_arc_max\n8589934592\n", []string{"Set zfs_arc_max from 0 to 8589934592? [y/N] "}, []string{"set to"}},
+	}
+
+	for _, test := range tests {
+		tunables = make(map[string]string)
+
+		var out bytes.Buffer
+		editTunables(strings.NewReader(test.input), &out)
+
+		for _, w := range test.wanted {
+			if !strings.Contains(out.String(), w) {
+				t.Errorf("editTunables(%q) = %q (wanted %q)", test.input, out.String(), w)
+			}
+		}
+
+		for _, w := range test.unwanted {
+			if strings.Contains(out.String(), w) {
+				t.Errorf("editTunables(%q) = %q (wanted no %q)", test.input, out.String(), w)
+			}
+		}
+
+		if tunables["zfs_arc_max"] != "0" || tunables["zfs_prefetch_disable"] != "0" {
+			t.Errorf("editTunables(%q) changed tunables to %v", test.input, tunables)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+// Transaction group timing section for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// ZFS collects writes in transaction groups (txgs). A txg is open while it
+// takes writes, quiesces until the writes in flight are done, waits for the
+// previous txg to sync and is then synced to disk. A new txg is started at
+// least every zfs_txg_timeout seconds. When syncing takes longer than that,
+// the disks can't keep up, dirty data piles up and ZFS starts to delay
+// writes. Each pool keeps the last zfs_txg_history txgs in its txgs kstat,
+// from which we show the average and worst time of each phase. Only txgs
+// that were committed count, the others are still running. Linux only. See
+// arc_summary.go for the license
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// defaultTxgTimeout is zfs_txg_timeout in seconds if we can't read it
+const defaultTxgTimeout = 5
+
+// txgPhases are the phases of a txg with the column of their time in ns
+var txgPhases = []struct{ column, label string }{
+	{"otime", "Open:"},
+	{"qtime", "Quiesce:"},
+	{"wtime", "Wait for sync:"},
+	{"stime", "Sync:"},
+}
+
+// txgTimes are the average and longest times of the phases of the txgs in
+// the window, in ns, keyed by column. Written is the average of bytes
+// written per txg
+type txgTimes struct {
+	count   int
+	avg     map[string]float64
+	max     map[string]uint64
+	written float64
+}
+
+// parseTxgs returns the times of the committed txgs of a txgs kstat. The
+// columns are named in the line starting with "txg"
+func parseTxgs(data []byte) (txgTimes, error) {
+
+	t := txgTimes{avg: make(map[string]float64), max: make(map[string]uint64)}
+
+	var columns map[string]int
+	sums := make(map[string]uint64)
+	var written uint64
+
+	input := bufio.NewScanner(bytes.NewReader(data))
+
+	for input.Scan() {
+		fields := strings.Fields(input.Text())
+
+		if len(fields) > 0 && fields[0] == "txg" {
+			columns = make(map[string]int)
+			for i, f := range fields {
+				columns[f] = i
+			}
+			continue
+		}
+
+		if columns == nil || len(fields) != len(columns) || fields[columns["state"]] != "C" {
+			continue
+		}
+
+		for _, p := range txgPhases {
+			i, ok := columns[p.column]
+			if !ok {
+				return t, fmt.Errorf("no column %s", p.column)
+			}
+			v, err := strconv.ParseUint(fields[i], 10, 64)
+			if err != nil {
+				return t, fmt.Errorf("bad %s '%s'", p.column, fields[i])
+			}
+			sums[p.column] += v
+			if v > t.max[p.column] {
+				t.max[p.column] = v
+			}
+		}
+
+		if i, ok := columns["nwritten"]; ok {
+			if v, err := strconv.ParseUint(fields[i], 10, 64); err == nil {
+				written += v
+			}
+		}
+
+		t.count++
+	}
+
+	if columns == nil {
+		return t, fmt.Errorf("no txg header")
+	}
+
+	if t.count > 0 {
+		for k, v := range sums {
+			t.avg[k] = float64(v) / float64(t.count)
+		}
+		t.written = float64(written) / float64(t.count)
+	}
+
+	return t, input.Err()
+}
+
+// fNanos returns a duration in nanoseconds in ns, µs, ms or s
+func fNanos(ns float64) string {
+
+	if ns < 1e3 {
+		return fmt.Sprintf("%.0f ns", ns)
+	}
+
+	if ns < 1e6 {
+		return fmt.Sprintf("%.0f µs", ns/1e3)
+	}
+
+	if ns < 1e9 {
+		return fmt.Sprintf("%.1f ms", ns/1e6)
+	}
+
+	return fmt.Sprintf("%.2f s", ns/1e9)
+}
+
+// txgTimeout returns zfs_txg_timeout in seconds
+func txgTimeout(ctx context.Context) uint64 {
+
+	if v, err := strconv.ParseUint(readFirstLine(ctx, tunablesPath+"/zfs_txg_timeout"), 10, 64); err == nil && v > 0 {
+		return v
+	}
+
+	return defaultTxgTimeout
+}
+
+// printTxgs displays the timing of the recent txgs of every pool
+func printTxgs() {
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	out, err := runCommand(ctx, "zpool", "list", "-H", "-o", "name")
+	if err != nil {
+		skipSection("txgs", fmt.Errorf("couldn't list pools: %v", err))
+		return
+	}
+
+	timeout := txgTimeout(ctx)
+	var slow []string
+
+	for _, pool := range strings.Fields(string(out)) {
+		data, err := readFile(ctx, procPath+pool+"/txgs")
+		if err != nil {
+			addWarning("txgs", "%s: %v", redactName(pool), err)
+			continue
+		}
+
+		t, err := parseTxgs(data)
+		if err != nil {
+			addWarning("txgs", "%s: %v", redactName(pool), err)
+			continue
+		}
+
+		fmt.Fprintf(reportOut, "\nPool %s (%d txgs, average / worst):\n", redactName(pool), t.count)
+
+		if t.count == 0 {
+			prtL2("Committed txgs:", "none (zfs_txg_history may be 0)")
+			continue
+		}
+
+		for _, p := range txgPhases {
+			prtL2(p.label, fNanos(t.avg[p.column])+" / "+fNanos(float64(t.max[p.column])))
+		}
+		prtL2("Written per txg:", fBytes(strconv.FormatUint(uint64(t.written), 10)))
+
+		if t.max["stime"] > timeout*1e9 {
+			slow = append(slow, redactName(pool))
+			addWarning("txgs", "%s: syncing took up to %s, longer than zfs_txg_timeout (%d s)",
+				redactName(pool), fNanos(float64(t.max["stime"])), timeout)
+		}
+	}
+
+	delays, _ := lookupStat("dmu_tx.dmu_tx_dirty_delay")
+	explain(explainTxgs(slow, uint64(delays)))
+}
@@ -0,0 +1,60 @@
+// Test file for txgs.go
+package main
+
+import "testing"
+
+const txgsData = `18 0 0x01 100 11200 5214936013 15383924717486
+txg      birth            state ndirty       nread        nwritten     reads    writes   otime        qtime        wtime        stime
+100      52373698862869   C     1556480      0            3000         0        186      5000000000   20000        70000        1000000000
+101      52378698862869   C     1556480      0            1000         0        186      4000000000   40000        90000        3000000000
+102      52383698929131   S     0            0            0            0        0        5000118364   61001        48154        0
+103      52388699047495   O     0            0            0            0        0        0            0            0            0
+`
+
+func TestParseTxgs(t *testing.T) {
+
+	got, err := parseTxgs([]byte(txgsData))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.count != 2 {
+		t.Errorf("parseTxgs() count = %d (wanted \"2\")", got.count)
+	}
+	if got.avg["stime"] != 2e9 || got.max["stime"] != 3e9 {
+		t.Errorf("parseTxgs() stime = %v / %v (wanted \"2e9 / 3e9\")", got.avg["stime"], got.max["stime"])
+	}
+	if got.avg["otime"] != 4.5e9 || got.max["qtime"] != 40000 {
+		t.Errorf("parseTxgs() otime avg = %v, qtime max = %v (wanted \"4.5e9, 40000\")", got.avg["otime"], got.max["qtime"])
+	}
+	if got.written != 2000 {
+		t.Errorf("parseTxgs() written = %v (wanted \"2000\")", got.written)
+	}
+
+	if got, err := parseTxgs([]byte("18 0 0x01 0 0 1 2\ntxg birth state ndirty nread nwritten reads writes otime qtime wtime stime\n")); err != nil || got.count != 0 {
+		t.Errorf("parseTxgs(no history) = %v, %v (wanted \"0 txgs\")", got.count, err)
+	}
+
+	if _, err := parseTxgs([]byte("garbage\n")); err == nil {
+		t.Errorf("parseTxgs(garbage) succeeded (wanted error)")
+	}
+}
+
+func TestFNanos(t *testing.T) {
+
+	var tests = []struct {
+		input float64
+		want  string
+	}{
+		{512, "512 ns"},
+		{20126, "20 µs"},
+		{18530542, "18.5 ms"},
+		{7.2e9, "7.20 s"},
+	}
+
+	for _, test := range tests {
+		if got := fNanos(test.input); got != test.want {
+			t.Errorf("fNanos(%v) = %v (wanted \"%v\")", test.input, got, test.want)
+		}
+	}
+}
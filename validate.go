@@ -0,0 +1,219 @@
+// Validation of modprobe.d configuration files for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// "arc_summary validate <file>" checks the zfs options in a modprobe.d file
+// against the running module, so typos are caught before the next reboot.
+// See arc_summary.go for the license
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// modprobeCommands are the keywords modprobe.d understands
+var modprobeCommands = map[string]bool{
+	"alias":     true,
+	"blacklist": true,
+	"install":   true,
+	"options":   true,
+	"remove":    true,
+	"softdep":   true,
+}
+
+// checkTypeRange tests if value is legal for a module parameter of the given
+// internal format as reported by modinfo. Unknown formats are accepted
+func checkTypeRange(typ, value string) error {
+
+	var bits int
+	signed := false
+
+	switch typ {
+	case "byte":
+		bits = 8
+	case "short":
+		bits, signed = 16, true
+	case "ushort":
+		bits = 16
+	case "int":
+		bits, signed = 32, true
+	case "uint":
+		bits = 32
+	case "long":
+		bits, signed = 64, true
+	case "ulong":
+		bits = 64
+	case "bool", "invbool":
+		switch value {
+		case "0", "1", "y", "Y", "n", "N":
+			return nil
+		}
+		return fmt.Errorf("'%s' is not a boolean", value)
+	default:
+		return nil
+	}
+
+	var err error
+
+	if signed {
+		_, err = strconv.ParseInt(value, 0, bits)
+	} else {
+		_, err = strconv.ParseUint(value, 0, bits)
+	}
+
+	if err != nil {
+		if ne, ok := err.(*strconv.NumError); ok && ne.Err == strconv.ErrRange {
+			return fmt.Errorf("%s is out of range for %s", value, typ)
+		}
+		return fmt.Errorf("'%s' is not a valid %s", value, typ)
+	}
+
+	return nil
+}
+
+// validateModprobe checks a modprobe.d file and returns a list of problems.
+// live are the parameters of the running module, types their formats
+func validateModprobe(r io.Reader, file string, live, types map[string]string) ([]string, error) {
+
+	lines, err := logicalLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+
+	values := make(map[string]string)
+	for k, v := range live {
+		values[k] = v
+	}
+
+	// setAt is the line that last sets a parameter
+	setAt := make(map[string]int)
+
+	report := func(line int, format string, a ...interface{}) {
+		problems = append(problems, fmt.Sprintf("%s:%d: ", file, line)+fmt.Sprintf(format, a...))
+	}
+
+	for _, l := range lines {
+
+		text := l.text
+		if idx := strings.Index(text, "#"); idx != -1 {
+			text = text[:idx]
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if !modprobeCommands[fields[0]] {
+			report(l.line, "unknown command '%s'", fields[0])
+			continue
+		}
+
+		if fields[0] != "options" || len(fields) < 2 || fields[1] != "zfs" {
+			continue
+		}
+
+		if len(fields) == 2 {
+			report(l.line, "'options zfs' without any parameters")
+			continue
+		}
+
+		for _, f := range fields[2:] {
+
+			idx := strings.Index(f, "=")
+			if idx <= 0 || idx == len(f)-1 {
+				report(l.line, "'%s' is not of the form name=value", f)
+				continue
+			}
+
+			name, value := f[:idx], f[idx+1:]
+
+			if _, ok := live[name]; !ok {
+				if o, ok := obsoleteTunables[name]; ok {
+					report(l.line, "%s", obsoleteWarning(name, o))
+				} else {
+					report(l.line, "%s is not a parameter of the running module", name)
+				}
+				continue
+			}
+
+			if err := checkTypeRange(types[name], value); err != nil {
+				report(l.line, "%s: %v", name, err)
+				continue
+			}
+
+			if err := checkTunableRange(name, value); err != nil {
+				report(l.line, "%s: %v", name, err)
+			}
+
+			values[name] = value
+			setAt[name] = l.line
+		}
+	}
+
+	// The order is checked once the whole file is read, with its options
+	// overriding the live values, so a pair may be set in any order
+	for _, o := range tunableOrders {
+		line := setAt[o.small]
+		if setAt[o.large] > line {
+			line = setAt[o.large]
+		}
+		if line == 0 {
+			continue
+		}
+
+		pair := map[string]string{o.small: values[o.small], o.large: values[o.large]}
+		for _, p := range checkTunableOrders(pair, "") {
+			report(line, "%s", p)
+		}
+	}
+
+	return problems, nil
+}
+
+// cmdValidate handles the "validate" subcommand. It exits with status 1 if
+// there are any problems
+func cmdValidate(args []string) {
+
+	if len(args) != 1 {
+		log.Fatal("Usage: arc_summary validate <modprobe.d file>")
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		log.Fatal("Couldn't open ", args[0], ": ", err)
+	}
+	defer f.Close()
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	getTunables(ctx, tunables)
+
+	types, err := getTunableTypes(ctx)
+	if err != nil {
+		fmt.Fprintln(reportOut, "Couldn't get parameter types from modinfo, not checking values:", err)
+	}
+
+	problems, err := validateModprobe(f, args[0], tunables, types)
+	if err != nil {
+		log.Fatal("Couldn't read ", args[0], ": ", err)
+	}
+
+	if len(problems) == 0 {
+		fmt.Fprintln(reportOut, args[0]+": OK")
+		return
+	}
+
+	for _, p := range problems {
+		fmt.Fprintln(reportOut, p)
+	}
+	f.Close()
+	os.Exit(1)
+}
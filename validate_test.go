@@ -0,0 +1,75 @@
+// Test file for validate.go
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckTypeRange(t *testing.T) {
+	var tests = []struct {
+		typ   string
+		value string
+		ok    bool
+	}{
+		{"int", "-1", true},
+		{"int", "2147483648", false},
+		{"uint", "-1", false},
+		{"uint", "4294967295", true},
+		{"ulong", "0x100000000", true},
+		{"ulong", "8G", false},
+		{"bool", "Y", true},
+		{"bool", "2", false},
+		{"charp", "fastest", true},
+		{"", "anything", true},
+	}
+
+	for _, test := range tests {
+		err := checkTypeRange(test.typ, test.value)
+		if (err == nil) != test.ok {
+			t.Errorf("checkTypeRange(%s, %s) = %v (wanted ok %v)", test.typ, test.value, err, test.ok)
+		}
+	}
+}
+
+func TestValidateModprobe(t *testing.T) {
+	conf := `# test
+options zfs zfs_arc_max=8G zfs_txg_timeout=10
+option zfs zfs_arc_min=1
+options zfs zfs_arc_mni=1 zfs_vdev_scheduler=noop
+options zfs zfs_prefetch_disable
+options spl spl_taskq_thread_bind=0
+options zfs
+options zfs zfs_arc_min=2147483648 zfs_arc_max=1073741824 zfs_txg_timeout=0
+options zfs zfs_arc_meta_limit=1073741824
+options zfs zfs_vdev_sync_read_min_active=20
+options zfs zfs_vdev_sync_read_max_active=30
+options zfs zfs_vdev_async_read_max_active=2 zfs_vdev_async_read_min_active=3
+`
+	live := map[string]string{"zfs_arc_max": "0", "zfs_arc_min": "0", "zfs_txg_timeout": "5", "zfs_prefetch_disable": "0",
+		"zfs_arc_meta_limit": "0", "zfs_vdev_sync_read_min_active": "10", "zfs_vdev_sync_read_max_active": "10",
+		"zfs_vdev_async_read_min_active": "1", "zfs_vdev_async_read_max_active": "3"}
+	types := map[string]string{"zfs_arc_max": "ulong", "zfs_txg_timeout": "int"}
+
+	want := []string{
+		"zfs.conf:2: zfs_arc_max: '8G' is not a valid ulong",
+		"zfs.conf:3: unknown command 'option'",
+		"zfs.conf:4: zfs_arc_mni is not a parameter of the running module",
+		"zfs.conf:4: zfs_vdev_scheduler was removed in OpenZFS 2.0",
+		"zfs.conf:5: 'zfs_prefetch_disable' is not of the form name=value",
+		"zfs.conf:7: 'options zfs' without any parameters",
+		"zfs.conf:8: zfs_txg_timeout: 0 is outside the sensible range 1 to 2147483647",
+		"zfs.conf:8: zfs_arc_min (2147483648) is larger than zfs_arc_max (1073741824)",
+		"zfs.conf:12: zfs_vdev_async_read_min_active (3) is larger than zfs_vdev_async_read_max_active (2)",
+	}
+
+	got, err := validateModprobe(strings.NewReader(conf), "zfs.conf", live, types)
+	if err != nil {
+		t.Fatalf("validateModprobe() returned error: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("validateModprobe() = %q (wanted %q)", got, want)
+	}
+}
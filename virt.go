@@ -0,0 +1,170 @@
+// Virtualization detection for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// In a virtual machine, the host can take memory back from the guest with a
+// balloon driver. The balloon allocates memory inside the guest like any
+// other program, so the ARC is squeezed hard and fast and may not give the
+// memory back quickly enough, which ends in stalls or the OOM killer. We
+// detect the hypervisor much like systemd-detect-virt does, from the DMI
+// strings and the hypervisor flag of the CPU, and look for a loaded balloon
+// driver. Linux only. See arc_summary.go for the license
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	dmiPath     = "/sys/class/dmi/id"
+	cpuinfoPath = "/proc/cpuinfo"
+)
+
+// dmiFiles are the DMI strings that name the hypervisor, in the order we
+// check them
+var dmiFiles = []string{"product_name", "sys_vendor", "board_vendor", "bios_vendor"}
+
+// virtVendors maps DMI strings to the name systemd-detect-virt uses for the
+// hypervisor. Microsoft also makes real hardware, so it only counts if the
+// CPU says there is a hypervisor
+var virtVendors = []struct{ match, name string }{
+	{"KVM", "kvm"},
+	{"QEMU", "qemu"},
+	{"VMware", "vmware"},
+	{"VMW", "vmware"},
+	{"innotek GmbH", "oracle"},
+	{"VirtualBox", "oracle"},
+	{"Xen", "xen"},
+	{"Bochs", "bochs"},
+	{"Parallels", "parallels"},
+	{"BHYVE", "bhyve"},
+	{"Amazon EC2", "amazon"},
+	{"Google", "google"},
+	{"Microsoft Corporation", "microsoft"},
+}
+
+// balloonDrivers are the balloon drivers of the hypervisors, with a file
+// that exists if the driver is loaded
+var balloonDrivers = []struct{ name, path string }{
+	{"virtio_balloon", "/sys/module/virtio_balloon/initstate"},
+	{"vmw_balloon", "/sys/module/vmw_balloon/initstate"},
+	{"hv_balloon", "/sys/module/hv_balloon/initstate"},
+	{"xen-balloon", "/sys/devices/system/xen_memory/xen_memory0/target_kb"},
+}
+
+// hasHypervisorFlag says if the CPU flags in /proc/cpuinfo include
+// "hypervisor", which x86 CPUs set when running under one
+func hasHypervisorFlag(cpuinfo string) bool {
+
+	for _, l := range strings.Split(cpuinfo, "\n") {
+		if !strings.HasPrefix(l, "flags") {
+			continue
+		}
+		for _, f := range strings.Fields(l) {
+			if f == "hypervisor" {
+				return true
+			}
+		}
+		return false
+	}
+
+	return false
+}
+
+// detectVirt returns the name of the hypervisor from the DMI strings and the
+// CPU flags, an empty string on bare metal. "unknown" means the CPU says it
+// runs under a hypervisor we don't recognize
+func detectVirt(dmi []string, cpuinfo string) string {
+
+	hypervisor := hasHypervisorFlag(cpuinfo)
+
+	for _, s := range dmi {
+		for _, v := range virtVendors {
+			if !strings.HasPrefix(s, v.match) {
+				continue
+			}
+			if v.name == "microsoft" && !hypervisor {
+				continue
+			}
+			return v.name
+		}
+	}
+
+	if hypervisor {
+		return "unknown"
+	}
+
+	return ""
+}
+
+// virtFiles returns the files we read to detect virtualization, for the
+// bundle
+func virtFiles() []string {
+
+	files := []string{cpuinfoPath}
+
+	for _, f := range dmiFiles {
+		files = append(files, dmiPath+"/"+f)
+	}
+	for _, b := range balloonDrivers {
+		files = append(files, b.path)
+	}
+
+	return files
+}
+
+// readVirt returns the hypervisor and the balloon driver that is loaded, if
+// any
+func readVirt(ctx context.Context) (string, string) {
+
+	var dmi []string
+	for _, f := range dmiFiles {
+		if s := readFirstLine(ctx, dmiPath+"/"+f); s != "" {
+			dmi = append(dmi, s)
+		}
+	}
+
+	cpuinfo, _ := readFile(ctx, cpuinfoPath)
+
+	virt := detectVirt(dmi, string(cpuinfo))
+	if virt == "" {
+		return "", ""
+	}
+
+	for _, b := range balloonDrivers {
+		if _, err := readFile(ctx, b.path); err == nil {
+			return virt, b.name
+		}
+	}
+
+	return virt, ""
+}
+
+// printVirt prints the hypervisor we run under, if any, and warns if a
+// balloon driver is loaded
+func printVirt() {
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	virt, balloon := readVirt(ctx)
+	if virt == "" {
+		return
+	}
+
+	fmt.Fprintln(reportOut, "\nVirtualization:")
+	prtL2("Hypervisor:", virt)
+
+	driver := balloon
+	if driver == "" {
+		driver = "none"
+	}
+	prtL2("Balloon driver:", driver)
+
+	explain(explainBalloon(balloon))
+
+	if balloon != "" {
+		addWarning("virt", "the %s driver can take memory from this VM faster than the ARC gives it back", balloon)
+	}
+}
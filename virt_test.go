@@ -0,0 +1,30 @@
+// Test file for virt.go
+package main
+
+import "testing"
+
+func TestDetectVirt(t *testing.T) {
+
+	const guest = "processor : 0\nflags\t\t: fpu vme sse2 hypervisor avx2\n"
+	const host = "processor : 0\nflags\t\t: fpu vme sse2 avx2\n"
+
+	var tests = []struct {
+		dmi     []string
+		cpuinfo string
+		want    string
+	}{
+		{[]string{"Standard PC (Q35 + ICH9, 2009)", "QEMU"}, guest, "qemu"},
+		{[]string{"VMware Virtual Platform", "VMware, Inc."}, guest, "vmware"},
+		{[]string{"Virtual Machine", "Microsoft Corporation"}, guest, "microsoft"},
+		{[]string{"Surface Laptop", "Microsoft Corporation"}, host, ""},
+		{[]string{"ProLiant DL380", "HPE"}, host, ""},
+		{nil, guest, "unknown"},
+		{nil, "", ""},
+	}
+
+	for _, test := range tests {
+		if got := detectVirt(test.dmi, test.cpuinfo); got != test.want {
+			t.Errorf("detectVirt(%v) = %v (wanted \"%v\")", test.dmi, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Kernel VM sysctls for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// How hard and how early the kernel takes memory back from the ARC depends
+// on the settings of its own memory management as much as on the ZFS
+// tunables. We show the vm sysctls that matter most next to the ARC. Linux
+// only. See arc_summary.go for the license
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+const vmSysctlPath = "/proc/sys/vm"
+
+// vmSysctls are the sysctls we show, with their label and how to print their
+// value
+var vmSysctls = []struct {
+	name   string
+	label  string
+	format func(uint64) string
+}{
+	{"min_free_kbytes", "vm.min_free_kbytes:", func(v uint64) string { return fBytes(strconv.FormatUint(v*1024, 10)) }},
+	{"swappiness", "vm.swappiness:", func(v uint64) string { return strconv.FormatUint(v, 10) }},
+	{"dirty_ratio", "vm.dirty_ratio:", fVMRatio},
+	{"dirty_background_ratio", "vm.dirty_background_ratio:", fVMRatio},
+	{"dirty_bytes", "vm.dirty_bytes:", fVMBytes},
+	{"dirty_background_bytes", "vm.dirty_background_bytes:", fVMBytes},
+	{"dirty_expire_centisecs", "vm.dirty_expire_centisecs:", fCentisecs},
+	{"dirty_writeback_centisecs", "vm.dirty_writeback_centisecs:", fCentisecs},
+}
+
+// fVMRatio returns a percentage of memory
+func fVMRatio(v uint64) string {
+	return fmt.Sprintf("%d %%", v)
+}
+
+// fVMBytes returns a size where 0 means the ratio is used instead
+func fVMBytes(v uint64) string {
+
+	if v == 0 {
+		return "0 (ratio used)"
+	}
+
+	return fBytes(strconv.FormatUint(v, 10))
+}
+
+// fCentisecs returns hundredths of a second in seconds
+func fCentisecs(v uint64) string {
+	return fmt.Sprintf("%.1f s", float64(v)/100)
+}
+
+// vmSysctlFiles returns the files of the sysctls, for the bundle
+func vmSysctlFiles() []string {
+
+	var files []string
+
+	for _, s := range vmSysctls {
+		files = append(files, vmSysctlPath+"/"+s.name)
+	}
+
+	return files
+}
+
+// readVMSysctls returns the values of the sysctls that can be read
+func readVMSysctls(ctx context.Context) map[string]uint64 {
+
+	values := make(map[string]uint64)
+
+	for _, s := range vmSysctls {
+		if v, err := strconv.ParseUint(readFirstLine(ctx, vmSysctlPath+"/"+s.name), 10, 64); err == nil {
+			values[s.name] = v
+		}
+	}
+
+	return values
+}
+
+// printVMSysctls prints the kernel VM settings that affect the ARC
+func printVMSysctls() {
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	values := readVMSysctls(ctx)
+	if len(values) == 0 {
+		return
+	}
+
+	fmt.Fprintln(reportOut, "\nKernel VM settings:")
+
+	for _, s := range vmSysctls {
+		if v, ok := values[s.name]; ok {
+			prtL2(s.label, s.format(v))
+		}
+	}
+
+	var memTotal uint64
+	if m, err := readMeminfo(ctx); err == nil {
+		memTotal = m["MemTotal"]
+	}
+
+	explain(explainVMSysctls(values, memTotal))
+}
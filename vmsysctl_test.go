@@ -0,0 +1,25 @@
+// Test file for vmsysctl.go
+package main
+
+import "testing"
+
+func TestVMSysctlFormats(t *testing.T) {
+
+	var tests = []struct {
+		f     func(uint64) string
+		input uint64
+		want  string
+	}{
+		{fVMRatio, 20, "20 %"},
+		{fVMBytes, 0, "0 (ratio used)"},
+		{fVMBytes, 512, "512 Bytes"},
+		{fCentisecs, 3000, "30.0 s"},
+		{fCentisecs, 50, "0.5 s"},
+	}
+
+	for _, test := range tests {
+		if got := test.f(test.input); got != test.want {
+			t.Errorf("f(%d) = %v (wanted \"%v\")", test.input, got, test.want)
+		}
+	}
+}
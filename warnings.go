@@ -0,0 +1,77 @@
+// Warnings about partial failures for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// Sections that can't be collected are skipped so the rest of the report can
+// still be printed. Everything that was skipped is listed again in a
+// Warnings section at the end, and arc_summary exits with exitPartial so
+// scripts notice. See arc_summary.go for the license
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// exitPartial is the exit code when the report was printed but parts of it
+// had to be skipped
+const exitPartial = 2
+
+// reportWarning is something that was skipped and the reason why
+type reportWarning struct {
+	section string
+	reason  string
+}
+
+// reportWarnings are the warnings of the report being printed
+var reportWarnings []reportWarning
+
+// addWarning remembers that part of a section was skipped
+func addWarning(section, format string, args ...interface{}) {
+	reportWarnings = append(reportWarnings, reportWarning{section, fmt.Sprintf(format, args...)})
+}
+
+// skipSection prints why a section is skipped and remembers it for the
+// Warnings section
+func skipSection(section string, err error) {
+	fmt.Fprintf(reportOut, "\nSkipped: %v\n", err)
+	addWarning(section, "%v", err)
+}
+
+// printWarnings prints the Warnings section if anything was skipped
+func printWarnings() {
+
+	if len(reportWarnings) == 0 {
+		return
+	}
+
+	printTitle("warnings")
+	fmt.Fprintln(reportOut)
+
+	for _, w := range reportWarnings {
+		if *OptPlain {
+			printPlain(w.section, "", w.reason)
+			continue
+		}
+		fmt.Fprintf(reportOut, indent+"%-12s%s\n", strings.ToUpper(w.section)+":", w.reason)
+	}
+}
+
+// partialFailure says if the output is missing something. The text report
+// knows which of its sections were skipped, the other formats contain
+// everything that was collected. With -strict, these print the warnings
+// about the stats to stderr
+func partialFailure(s *StatsSet) bool {
+
+	if *OptOutput == "text" {
+		return len(reportWarnings) > 0
+	}
+
+	if *OptStrict {
+		if w := strictWarnings(); len(w) > 0 {
+			printStrictWarnings(w)
+			return true
+		}
+	}
+
+	return s.Self.ReadErrors > 0
+}
@@ -0,0 +1,36 @@
+// Test file for warnings.go
+package main
+
+import "testing"
+
+func TestPartialFailure(t *testing.T) {
+
+	defer func(o string) { *OptOutput = o; reportWarnings = nil }(*OptOutput)
+
+	var tests = []struct {
+		output     string
+		warning    bool
+		readErrors int
+		want       bool
+	}{
+		{"text", false, 0, false},
+		{"text", true, 0, true},
+		{"text", false, 1, false},
+		{"json", false, 1, true},
+		{"json", true, 0, false},
+	}
+
+	for _, test := range tests {
+		*OptOutput = test.output
+		reportWarnings = nil
+		if test.warning {
+			addWarning("zil", "%s does not exist", "zil")
+		}
+
+		s := &StatsSet{Self: SelfStats{ReadErrors: test.readErrors}}
+		if got := partialFailure(s); got != test.want {
+			t.Errorf("partialFailure(%s, %v, %d) = %v (wanted \"%v\")",
+				test.output, test.warning, test.readErrors, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+// Watch mode for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// Repeats the report at a fixed interval, optionally followed by sparklines
+// of selected stats. As this is the mode that runs for a long time, it reacts
+// to signals: SIGHUP reloads the configuration file, SIGUSR1 writes a JSON
+// snapshot of the stats to -snapshot-dir and SIGTERM or SIGINT write a last
+// history record before quitting. Windows only has interrupts. Every
+// refresh starts with its sequence number and time, which also go into the
+// history records, so logs can be matched up with other events. See
+// arc_summary.go for the license
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	clearScreen        = "\033[H\033[2J"
+	defaultSnapshotDir = "/var/lib/arc_summary"
+	sparkWidth         = 40  // number of samples kept for each sparkline
+	chartLength        = 300 // number of hit ratios kept for the chart
+)
+
+var sparkTicks = []rune("▁▂▃▄▅▆▇█")
+
+// sampleSeq is the number of the current sample in watch and serve mode,
+// starting at 1. It is zero for a single report
+var sampleSeq int64
+
+// sampleLine returns the line that starts a refresh in watch mode
+func sampleLine(seq int64, t time.Time) string {
+	return fmt.Sprintf("Sample %d at %s", seq, t.Format(time.RFC3339))
+}
+
+// watch prints the report every interval until the program is interrupted.
+// It does not return
+func watch(interval time.Duration) {
+
+	var sparkNames []string
+	history := make(map[string][]float64)
+
+	var hitRatios []float64
+	var lastHits, lastMisses float64
+	first := true
+
+	if *OptSpark != "" {
+		sparkNames = strings.Split(*OptSpark, ",")
+	}
+
+	var notifier dbusNotifier
+
+	if *OptHighlight && !*OptPlain {
+		highlight = &rowHighlighter{}
+	}
+
+	sigs := make(chan os.Signal, 4)
+	signal.Notify(sigs, stopSignals...)
+	if reloadSignal != nil {
+		signal.Notify(sigs, reloadSignal, snapshotSignal)
+	}
+
+	for {
+		sampleSeq++
+		sampleTime := time.Now()
+
+		ctx, cancel := collectContext()
+		getKstats(ctx, kstats)
+		updateAverages(sampleTime)
+
+		if *OptDBus {
+			notifier.update(ctx)
+		}
+		cancel()
+
+		if *OptHistory != "" {
+			appendHistory(*OptHistory)
+		}
+
+		if *OptState != "" {
+			updateState(*OptState)
+		}
+
+		if len(activeSinks()) > 0 {
+			ctx, cancel := collectContext()
+			getTunables(ctx, tunables)
+			cancel()
+			publishSample(newStatsSet())
+		}
+
+		if *OptTextfileDir != "" {
+			ctx, cancel := collectContext()
+			getTunables(ctx, tunables)
+			cancel()
+
+			if err := writeTextfile(*OptTextfileDir, newStatsSet()); err != nil {
+				log.Print("Couldn't write textfile: ", err)
+			}
+		}
+
+		for _, n := range sparkNames {
+			value, err := lookupStat(n)
+			if err != nil {
+				value = math.NaN()
+			}
+
+			h := append(history[n], value)
+			if len(h) > sparkWidth {
+				h = h[len(h)-sparkWidth:]
+			}
+			history[n] = h
+		}
+
+		if *OptChart != "" {
+			hits, _ := lookupStat("hits")
+			misses, _ := lookupStat("misses")
+
+			// The chart shows the hit ratio of each interval, not the
+			// ratio since boot, so we need two samples to start
+			if !first {
+				hitRatios = append(hitRatios, intervalHitRatio(hits-lastHits, misses-lastMisses))
+				if len(hitRatios) > chartLength {
+					hitRatios = hitRatios[len(hitRatios)-chartLength:]
+				}
+			}
+			lastHits, lastMisses, first = hits, misses, false
+
+			if err := writeChart(*OptChart, hitRatios); err != nil {
+				log.Fatal("Couldn't write chart: ", err)
+			}
+		}
+
+		fmt.Fprint(reportOut, clearScreen)
+		fmt.Fprintln(reportOut, sampleLine(sampleSeq, sampleTime))
+		if highlight != nil {
+			highlight.begin()
+		}
+		printReport()
+		if highlight != nil {
+			highlight.end()
+		}
+		printSparks(sparkNames, history)
+
+		waitInterval(interval, sigs)
+	}
+}
+
+// waitInterval waits for the next round of watch mode while handling
+// signals. A reload of the configuration ends the wait early so the new
+// configuration shows up at once
+func waitInterval(interval time.Duration, sigs chan os.Signal) {
+
+	next := time.After(jittered(interval, *OptJitter))
+
+	for {
+		select {
+		case <-next:
+			return
+
+		case sig := <-sigs:
+			switch sig {
+			case reloadSignal:
+				var c config
+				if err := readConfig(*OptConfig, configExplicit, &c); err != nil {
+					log.Print("Not reloading config: ", err)
+					continue
+				}
+				cfg = c
+				applyLayout(cfg.Layout)
+				return
+
+			case snapshotSignal:
+				path, err := writeSnapshot(*OptSnapshotDir)
+				if err != nil {
+					log.Print("Couldn't write snapshot: ", err)
+					continue
+				}
+				log.Print("Wrote snapshot to ", path)
+
+			default:
+				// History records are written as soon as they are
+				// collected, so all that is left is a last sample
+				if *OptHistory != "" {
+					sampleSeq++
+					ctx, cancel := collectContext()
+					getKstats(ctx, kstats)
+					cancel()
+					appendHistory(*OptHistory)
+				}
+				releasePidFile()
+				os.Exit(0)
+			}
+		}
+	}
+}
+
+// writeSnapshot collects the stats and writes them as JSON to a new file in
+// dir, returning its path
+func writeSnapshot(dir string) (string, error) {
+
+	ctx, cancel := collectContext()
+	defer cancel()
+
+	set, err := kstatCollector{}.Collect(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	suffix, err := compressSuffix(*OptCompress)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("snapshot-%d.json%s", set.Time.Unix(), suffix))
+
+	f, err := openOutput(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY)
+	if err != nil {
+		return "", err
+	}
+
+	if err := (jsonRenderer{}).Render(f, set); err != nil {
+		f.Close()
+		return "", err
+	}
+
+	return path, f.Close()
+}
+
+// printSparks prints one sparkline for each of the given stats along with the
+// most recent value
+func printSparks(names []string, history map[string][]float64) {
+
+	if len(names) == 0 {
+		return
+	}
+
+	printTitle("trends")
+	if !*OptPlain {
+		fmt.Fprintln(reportOut)
+	}
+
+	for _, n := range names {
+		h := history[n]
+		current := "n/a"
+
+		if len(h) > 0 && !math.IsNaN(h[len(h)-1]) {
+			current = fmt.Sprintf("%.0f", h[len(h)-1])
+		}
+
+		if *OptPlain {
+			printPlain(n, "", current)
+			continue
+		}
+
+		fmt.Fprintf(reportOut, "%s%-24s%s %s\n", indent, n, padSpark(sparkline(h)), current)
+	}
+}
+
+// padSpark pads a sparkline with blanks to sparkWidth. The ticks take three
+// bytes each but one column, so the padding goes by runes
+func padSpark(s string) string {
+
+	if n := sparkWidth - utf8.RuneCountInString(s); n > 0 {
+		return s + strings.Repeat(" ", n)
+	}
+
+	return s
+}
+
+// sparkline turns a series of values into a string of unicode block
+// characters scaled between the smallest and largest value. Values that
+// could not be read (NaN) are shown as blanks
+func sparkline(values []float64) string {
+
+	min, max := math.Inf(1), math.Inf(-1)
+
+	for _, v := range values {
+		if math.IsNaN(v) {
+			continue
+		}
+		min = math.Min(min, v)
+		max = math.Max(max, v)
+	}
+
+	var b strings.Builder
+
+	for _, v := range values {
+
+		if math.IsNaN(v) {
+			b.WriteRune(' ')
+			continue
+		}
+
+		idx := 0
+		if max > min {
+			idx = int((v - min) / (max - min) * float64(len(sparkTicks)-1))
+		}
+		b.WriteRune(sparkTicks[idx])
+	}
+
+	return b.String()
+}
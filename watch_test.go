@@ -0,0 +1,60 @@
+// Test file for watch.go
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestSparkline(t *testing.T) {
+	var tests = []struct {
+		have []float64
+		want string
+	}{
+		{[]float64{}, ""},
+		{[]float64{5}, "▁"},
+		{[]float64{3, 3, 3}, "▁▁▁"},
+		{[]float64{0, 7}, "▁█"},
+		{[]float64{0, 1, 2, 3, 4, 5, 6, 7}, "▁▂▃▄▅▆▇█"},
+		{[]float64{0, math.NaN(), 7}, "▁ █"},
+	}
+
+	for _, test := range tests {
+		got := sparkline(test.have)
+		if got != test.want {
+			t.Errorf("sparkline(%v) = %v (wanted \"%v\")", test.have, got, test.want)
+		}
+	}
+}
+
+func TestPadSpark(t *testing.T) {
+	var tests = []struct {
+		have string
+		want int
+	}{
+		{"", sparkWidth},
+		{"▁▂▃", sparkWidth},
+		{strings.Repeat("█", sparkWidth), sparkWidth},
+		{strings.Repeat("█", sparkWidth+1), sparkWidth + 1},
+	}
+
+	for _, test := range tests {
+		got := padSpark(test.have)
+		if n := utf8.RuneCountInString(got); n != test.want || !strings.HasPrefix(got, test.have) {
+			t.Errorf("padSpark(%q) = %q, %d runes (wanted \"%v\")", test.have, got, n, test.want)
+		}
+	}
+}
+
+func TestSampleLine(t *testing.T) {
+
+	got := sampleLine(42, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	want := "Sample 42 at 2026-01-02T03:04:05Z"
+
+	if got != want {
+		t.Errorf("sampleLine(42) = %v (wanted \"%v\")", got, want)
+	}
+}
@@ -0,0 +1,103 @@
+// ZIL section for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// The ZIL keeps a log of sync writes so they survive a crash before the
+// next transaction group is written. Each change becomes an intent log
+// transaction (itx). Small sync writes copy their data into the log, large
+// ones and datasets with logbias=throughput only log a pointer to data
+// written directly into the pool (indirect), and async writes are only
+// copied if they later have to be committed (needcopy). The log blocks go
+// to a separate log device (SLOG) if there is one, otherwise to the normal
+// pool devices. See arc_summary.go for the license
+package main
+
+// zilWritePaths are the ways the data of a write gets into the log, with
+// the counters of their itxs and bytes
+var zilWritePaths = []struct{ label, count, bytes string }{
+	{"Indirect:", "zil_itx_indirect_count", "zil_itx_indirect_bytes"},
+	{"Copied:", "zil_itx_copied_count", "zil_itx_copied_bytes"},
+	{"Need copy:", "zil_itx_needcopy_count", "zil_itx_needcopy_bytes"},
+}
+
+// zilDevices are the devices log blocks are written to
+var zilDevices = []struct{ label, count, bytes string }{
+	{"Normal pool:", "zil_itx_metaslab_normal_count", "zil_itx_metaslab_normal_bytes"},
+	{"Log device (SLOG):", "zil_itx_metaslab_slog_count", "zil_itx_metaslab_slog_bytes"},
+}
+
+// zilCommitProblems are counters of newer versions of OpenZFS for commits
+// that didn't go as planned
+var zilCommitProblems = []struct{ stat, label string }{
+	{"zil_commit_error_count", "Commits that failed (txg sync instead):"},
+	{"zil_commit_stall_count", "Commits that waited for a txg sync:"},
+	{"zil_commit_suspend_count", "Commits while the pool was suspended:"},
+}
+
+// zilParts returns a breakdown of the ZIL stats, by count or by bytes. Rows
+// with missing stats are left out
+func zilParts(stats map[string]string, rows []struct{ label, count, bytes string }, bytes bool) ([]breakdownPart, uint64, bool) {
+
+	var parts []breakdownPart
+	var total uint64
+
+	for _, r := range rows {
+		name := r.count
+		if bytes {
+			name = r.bytes
+		}
+		if v, ok := statValue(stats, name); ok {
+			parts = append(parts, breakdownPart{r.label, v})
+			total += v
+		}
+	}
+
+	return parts, total, len(parts) > 0
+}
+
+// printZIL displays the statistics of the ZFS Intent Log
+func printZIL() {
+
+	var zilStats = make(map[string]string)
+	procSection("zil", zilStats)
+
+	itxs, _ := statValue(zilStats, "zil_itx_count")
+	prtL1("ZIL intent log transactions:", fHits(zilStats["zil_itx_count"]))
+
+	for _, c := range []struct{ stat, label string }{
+		{"zil_commit_count", "Commit requests:"},
+		{"zil_commit_writer_count", "Commits that wrote log blocks:"},
+	} {
+		if v, ok := zilStats[c.stat]; ok {
+			prtL2(c.label, fHits(v))
+		}
+	}
+
+	for _, c := range zilCommitProblems {
+		if v, ok := zilStats[c.stat]; ok {
+			prtL2(c.label, fHits(v))
+		}
+	}
+
+	for _, b := range []struct {
+		title string
+		rows  []struct{ label, count, bytes string }
+		bytes bool
+		f     func(string) string
+	}{
+		{"ZIL itxs by write path:", zilWritePaths, false, fHits},
+		{"ZIL itx bytes by write path:", zilWritePaths, true, fBytes},
+		{"ZIL log blocks by device:", zilDevices, false, fHits},
+		{"ZIL log bytes by device:", zilDevices, true, fBytes},
+	} {
+		if parts, total, ok := zilParts(zilStats, b.rows, b.bytes); ok {
+			printBreakdown(b.title, parts, total, b.f)
+		}
+	}
+
+	indirect, _ := statValue(zilStats, "zil_itx_indirect_bytes")
+	copied, _ := statValue(zilStats, "zil_itx_copied_bytes")
+	normal, _ := statValue(zilStats, "zil_itx_metaslab_normal_count")
+	slog, _ := statValue(zilStats, "zil_itx_metaslab_slog_count")
+
+	explain(explainZIL(itxs, indirect, copied, normal, slog))
+}
@@ -0,0 +1,34 @@
+// Test file for zil.go
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestZilParts(t *testing.T) {
+
+	stats := map[string]string{
+		"zil_itx_indirect_count":        "1",
+		"zil_itx_indirect_bytes":        "4096",
+		"zil_itx_copied_count":          "3",
+		"zil_itx_copied_bytes":          "512",
+		"zil_itx_metaslab_normal_count": "2",
+	}
+
+	parts, total, ok := zilParts(stats, zilWritePaths, false)
+	wanted := []breakdownPart{{"Indirect:", 1}, {"Copied:", 3}}
+	if !ok || total != 4 || !reflect.DeepEqual(parts, wanted) {
+		t.Errorf("zilParts(counts) = %v, %d, %v (wanted \"%v, 4\")", parts, total, ok, wanted)
+	}
+
+	parts, total, ok = zilParts(stats, zilWritePaths, true)
+	wanted = []breakdownPart{{"Indirect:", 4096}, {"Copied:", 512}}
+	if !ok || total != 4608 || !reflect.DeepEqual(parts, wanted) {
+		t.Errorf("zilParts(bytes) = %v, %d, %v (wanted \"%v, 4608\")", parts, total, ok, wanted)
+	}
+
+	if parts, _, ok := zilParts(stats, zilDevices, true); ok {
+		t.Errorf("zilParts(device bytes) = %v (wanted none)", parts)
+	}
+}
@@ -0,0 +1,112 @@
+// Zstd compression section for arc_summary
+// Scot W. Stevenson <scot.stevenson@gmail.com>
+//
+// OpenZFS 2.0 added zstd compression, and with it a kstat that counts what
+// went wrong: buffers that couldn't be allocated, levels that aren't
+// supported, and blocks that failed to compress or decompress. Since 2.2,
+// the higher levels first try a cheap pass (LZ4, then zstd-1) and give up
+// early on data that won't compress ("early abort"). The kstat has no
+// counters by level or of successful operations, so neither has the report.
+// See arc_summary.go for the license
+package main
+
+import (
+	"strconv"
+)
+
+// zstdErrors are the counters of the zstd kstat that count failures, with
+// their labels
+var zstdErrors = []struct{ stat, label string }{
+	{"compress_failed", "Compression failed:"},
+	{"decompress_failed", "Decompression failed:"},
+	{"compress_level_invalid", "Invalid level (compress):"},
+	{"decompress_level_invalid", "Invalid level (decompress):"},
+	{"decompress_header_invalid", "Invalid header (decompress):"},
+}
+
+// zstdAllocs are the counters about the memory zstd works in
+var zstdAllocs = []struct{ stat, label string }{
+	{"alloc_fail", "Allocations failed:"},
+	{"alloc_fallback", "Fallback buffer used:"},
+	{"compress_alloc_fail", "Allocations failed (compress):"},
+	{"decompress_alloc_fail", "Allocations failed (decompress):"},
+}
+
+// zstdStatNames returns the names of all stats the zstd section prints
+func zstdStatNames() []string {
+
+	names := []string{
+		"size", "buffers", "passignored", "passignored_size",
+		"lz4pass_allowed", "lz4pass_rejected", "zstdpass_allowed", "zstdpass_rejected",
+	}
+
+	for _, c := range append(zstdErrors, zstdAllocs...) {
+		names = append(names, c.stat)
+	}
+
+	return names
+}
+
+// zstdFailures returns the number of blocks that couldn't be compressed or
+// decompressed because of an error
+func zstdFailures(zstdStats map[string]string) uint64 {
+
+	var n uint64
+
+	for _, c := range zstdErrors {
+		if v, ok := zstdStats[c.stat]; ok {
+			n += stringToUint64(v)
+		}
+	}
+
+	return n
+}
+
+// printZstd displays the statistics of zstd compression
+func printZstd() {
+
+	var zstdStats = make(map[string]string)
+	procSection("zstd", zstdStats)
+
+	failures := zstdFailures(zstdStats)
+
+	status := "OK"
+	if failures > 0 {
+		status = "ERRORS"
+	}
+
+	prtL1("Zstd errors:", status)
+	for _, c := range zstdErrors {
+		if v, ok := zstdStats[c.stat]; ok {
+			prtL2(c.label, fHits(v))
+		}
+	}
+
+	prtL1("Zstd memory:", fBytes(zstdStats["size"]))
+	if v, ok := zstdStats["buffers"]; ok {
+		prtL2("Buffers:", fHits(v))
+	}
+	for _, c := range zstdAllocs {
+		if v, ok := zstdStats[c.stat]; ok {
+			prtL2(c.label, fHits(v))
+		}
+	}
+
+	// The early abort counters only exist since OpenZFS 2.2
+	if _, ok := zstdStats["lz4pass_allowed"]; !ok {
+		explain(explainZstd(failures, zstdStats["alloc_fallback"]))
+		return
+	}
+
+	prtL1("Early abort:", "")
+	for _, pass := range []struct{ prefix, name string }{{"lz4pass", "LZ4"}, {"zstdpass", "Zstd-1"}} {
+		allowed, rejected := zstdStats[pass.prefix+"_allowed"], zstdStats[pass.prefix+"_rejected"]
+		total := strconv.FormatUint(stringToUint64(allowed)+stringToUint64(rejected), 10)
+		prtL2p(pass.name+" pass, compressible:", fPerc(allowed, total), fHits(allowed))
+		prtL2p(pass.name+" pass, incompressible:", fPerc(rejected, total), fHits(rejected))
+	}
+	prtL2("Not tried (block too small):", fHits(zstdStats["passignored"]))
+	prtL2("Not tried (bytes):", fBytes(zstdStats["passignored_size"]))
+
+	explain(explainZstd(failures, zstdStats["alloc_fallback"]))
+}
@@ -0,0 +1,33 @@
+// Test file for zstd.go
+package main
+
+import (
+	"testing"
+)
+
+func TestZstdFailures(t *testing.T) {
+
+	var tests = []struct {
+		stats  map[string]string
+		wanted uint64
+	}{
+		{map[string]string{}, 0},
+		{map[string]string{"compress_failed": "0", "alloc_fallback": "7"}, 0},
+		{map[string]string{"compress_failed": "2", "decompress_header_invalid": "1"}, 3},
+	}
+
+	for _, test := range tests {
+		if got := zstdFailures(test.stats); got != test.wanted {
+			t.Errorf("zstdFailures(%v) = %d (wanted \"%d\")", test.stats, got, test.wanted)
+		}
+	}
+}
+
+func TestZstdStatsKnown(t *testing.T) {
+
+	names := []string{"size", "alloc_fallback", "lz4pass_rejected", "decompress_failed"}
+
+	if missing, unknown := strictProblems("zstd", names); len(missing) != 0 || len(unknown) != 0 {
+		t.Errorf("strictProblems(zstd) = %v, %v (wanted none)", missing, unknown)
+	}
+}